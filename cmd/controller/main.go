@@ -37,6 +37,7 @@ func main() {
 		op.EventRecorder,
 		op.GetClient(),
 		op.ImageProvider,
+		op.ConsoleHistoryProvider,
 	)
 	lo.Must0(op.AddHealthzCheck("cloud-provider", ociCloudProvider.LivenessProbe))
 	cloudProvider := metrics.Decorate(ociCloudProvider)
@@ -62,6 +63,12 @@ func main() {
 			op.SubnetProvider,
 			op.SecurityGroupProvider,
 			op.PricingProvider,
+			op.CapacityReservationProvider,
+			op.KmsProvider,
+			op.InstanceTypesProvider,
+			op.CapacityProbeProvider,
+			op.GetAPIReader(),
+			op.UnavailableOfferingsCache,
 		)...).
 		Start(ctx)
 }
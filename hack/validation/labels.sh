@@ -26,7 +26,18 @@ rule=$'self.all(x, x in
         "karpenter.k8s.oracle/instance-gpu",
         "karpenter.k8s.oracle/instance-network-bandwidth",
         "karpenter.k8s.oracle/instance-max-vnics",
-        "karpenter.k8s.oracle/is-flexible"
+        "karpenter.k8s.oracle/is-flexible",
+        "karpenter.k8s.oracle/capacity-reservation-id",
+        "karpenter.k8s.oracle/fault-domain",
+        "karpenter.k8s.oracle/instance-baseline-ocpu-utilization",
+        "karpenter.k8s.oracle/instance-family",
+        "karpenter.k8s.oracle/instance-generation",
+        "karpenter.k8s.oracle/instance-processor-vendor",
+        "karpenter.k8s.oracle/instance-ocpus",
+        "karpenter.k8s.oracle/instance-gpu-manufacturer",
+        "karpenter.k8s.oracle/instance-gpu-model",
+        "karpenter.k8s.oracle/instance-gpu-memory",
+        "karpenter.k8s.oracle/instance-local-nvme"
     ]
     || !x.find("^([^/]+)").endsWith("karpenter.k8s.oracle")
 )
@@ -18,6 +18,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/karpenter/pkg/apis"
 	corev1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 )
 
 func init() {
@@ -31,7 +32,19 @@ func init() {
 		LabelInstanceNetworkBandwidth,
 		LabelInstanceMaxVNICs,
 		LabelIsFlexible,
+		LabelCapacityReservationId,
+		LabelFaultDomain,
+		LabelInstanceBaselineOcpuUtilization,
+		LabelInstanceFamily,
+		LabelInstanceGeneration,
+		LabelInstanceProcessorVendor,
+		LabelInstanceOcpus,
+		LabelInstanceGPUManufacturer,
+		LabelInstanceGPUModel,
+		LabelInstanceGPUMemory,
+		LabelInstanceLocalNVMe,
 	)
+	cloudprovider.ReservationIDLabel = LabelCapacityReservationId
 }
 
 var (
@@ -41,6 +54,8 @@ var (
 
 	LabelNodeClass = Group + "/ocinodeclass"
 	LabelNodeClaim = apis.Group + "/nodeclaim"
+	// LabelConsoleHistory labels the configmaps which keep the console histories of the deleted instances
+	LabelConsoleHistory = Group + "/console-history"
 
 	LabelInstanceShapeName        = Group + "/instance-shape-name"
 	LabelInstanceCPU              = Group + "/instance-cpu"
@@ -50,19 +65,55 @@ var (
 	LabelInstanceNetworkBandwidth = Group + "/instance-network-bandwidth"
 	LabelInstanceMaxVNICs         = Group + "/instance-max-vnics"
 	LabelIsFlexible               = Group + "/is-flexible"
+	LabelCapacityReservationId    = Group + "/capacity-reservation-id"
+	LabelFaultDomain              = Group + "/fault-domain"
+	// LabelInstanceBaselineOcpuUtilization is the baseline of burstable instances, BASELINE_1_1 for the full OCPUs
+	LabelInstanceBaselineOcpuUtilization = Group + "/instance-baseline-ocpu-utilization"
+	// LabelInstanceFamily is the family of the shape, e.g. standard-e4 of VM.Standard.E4.Flex
+	LabelInstanceFamily = Group + "/instance-family"
+	// LabelInstanceGeneration is the codename of the processor of the shape, e.g. milan or altra
+	LabelInstanceGeneration = Group + "/instance-generation"
+	// LabelInstanceProcessorVendor is the vendor of the processor of the shape, ampere, amd or intel
+	LabelInstanceProcessorVendor = Group + "/instance-processor-vendor"
+	// LabelInstanceOcpus is the ocpu count of the instance shape
+	LabelInstanceOcpus = Group + "/instance-ocpus"
+	// LabelInstanceGPUManufacturer is the manufacturer of the gpus of the shape, nvidia or amd
+	LabelInstanceGPUManufacturer = Group + "/instance-gpu-manufacturer"
+	// LabelInstanceGPUModel is the model of the gpus of the shape, e.g. a10 or mi300x
+	LabelInstanceGPUModel = Group + "/instance-gpu-model"
+	// LabelInstanceGPUMemory is the memory of a gpu of the shape, the unit is MiB
+	LabelInstanceGPUMemory = Group + "/instance-gpu-memory"
+	// LabelInstanceLocalNVMe is the size of the local NVMe disks of the shape, the unit is GB
+	LabelInstanceLocalNVMe = Group + "/instance-local-nvme"
 
 	AnnotationOciNodeClassHash        = Group + "/ocinodeclass-hash"
 	AnnotationOciNodeClassHashVersion = Group + "/ocinodeclass-hash-version"
 	AnnotationInstanceTagged          = Group + "/tagged"
+	AnnotationVolumeAutotuned         = Group + "/volume-autotuned"
 
 	ManagedByAnnotationKey = apis.Group + "/managed-by"
 
 	ResourceNVIDIAGPU v1.ResourceName = "nvidia.com/gpu"
+	ResourceAMDGPU    v1.ResourceName = "amd.com/gpu"
+
+	// FaultDomains are the fault domains available in every availability domain
+	FaultDomains = []string{"FAULT-DOMAIN-1", "FAULT-DOMAIN-2", "FAULT-DOMAIN-3"}
 )
 
 const (
 	CapacityTypePreemptible = "preemptible"
 
+	VolumeAttachmentTypeISCSI           = "iscsi"
+	VolumeAttachmentTypeParavirtualized = "paravirtualized"
+
+	FlexShapeSizingSplit   = "Split"
+	FlexShapeSizingDynamic = "Dynamic"
+
+	FileSystemExt4 = "ext4"
+	FileSystemXfs  = "xfs"
+
+	InstanceStorePolicyRAID0 = "RAID0"
+
 	Ubuntu2204ImageFamily     = "Ubuntu2204"
 	OracleOKELinuxImageFamily = "OracleOKELinux"
 	CustomImageFamily         = "Custom"
@@ -25,9 +25,11 @@ import (
 )
 
 const (
-	ConditionTypeSubnetsReady        = "SubnetsReady"
-	ConditionTypeSecurityGroupsReady = "SecurityGroupsReady"
-	ConditionTypeImageReady          = "ImageReady"
+	ConditionTypeSubnetsReady              = "SubnetsReady"
+	ConditionTypeSecurityGroupsReady       = "SecurityGroupsReady"
+	ConditionTypeImageReady                = "ImageReady"
+	ConditionTypeCapacityReservationsReady = "CapacityReservationsReady"
+	ConditionTypeKmsKeyReady               = "KmsKeyReady"
 )
 
 // +kubebuilder:validation:MaxProperties:=64
@@ -40,30 +42,37 @@ type DefinedTagValue map[string]string
 type OciNodeClassSpec struct {
 	VcnId string `json:"vcnId"`
 	// imageSelector is a list of or image selector terms. The terms are ORed.
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['id', 'name']",rule="self.all(x, has(x.id) || has(x.name))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in imageSelector",rule="!self.exists(x, has(x.id) && has(x.name))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['id', 'name', 'tags']",rule="self.all(x, has(x.id) || has(x.name) || has(x.tags))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in imageSelector",rule="!self.exists(x, has(x.id) && (has(x.name) || has(x.tags)))"
 	// +kubebuilder:validation:MinItems:=1
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	ImageSelector []ImageSelectorTerm `json:"imageSelector"`
 	// subnetSelector is a list of or subnet selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="subnetSelector cannot be empty",rule="self.size() != 0"
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['name', 'id']",rule="self.all(x, has(x.name) || has(x.id))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in subnetSelector",rule="!self.all(x, has(x.id) && has(x.name))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['name', 'id', 'tags']",rule="self.all(x, has(x.name) || has(x.id) || has(x.tags))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in subnetSelector",rule="!self.exists(x, has(x.id) && (has(x.name) || has(x.tags)))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	SubnetSelector []SubnetSelectorTerm `json:"subnetSelector"`
 	// securityGroupSelector is a list of or security group selector terms. The terms are ORed.
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['id', 'name']",rule="self.all(x, has(x.id) || has(x.name))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelector",rule="!self.all(x, has(x.id) && has(x.name))"
-	// +kubebuilder:validation:XValidation:message="'name' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelector",rule="!self.all(x, has(x.name) && has(x.id))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['id', 'name', 'tags']",rule="self.all(x, has(x.id) || has(x.name) || has(x.tags))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelector",rule="!self.exists(x, has(x.id) && (has(x.name) || has(x.tags)))"
+	// +kubebuilder:validation:XValidation:message="'name' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelector",rule="!self.exists(x, has(x.name) && has(x.id))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	SecurityGroupSelector []SecurityGroupSelectorTerm `json:"securityGroupSelector,omitempty"`
-	UserData              *string                     `json:"userData,omitempty"`
-	PreInstallScript      *string                     `json:"preInstallScript,omitempty"`
-	MetaData              map[string]string           `json:"metaData,omitempty"`
-	ImageFamily           string                      `json:"imageFamily"`
+	// capacityReservationSelector is a list of capacity reservation selector terms. The terms are ORed.
+	// Instances are launched into the matched reservations before falling back to on-demand capacity.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['id', 'name', 'tags']",rule="self.all(x, has(x.id) || has(x.name) || has(x.tags))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in capacityReservationSelector",rule="!self.exists(x, has(x.id) && (has(x.name) || has(x.tags)))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	CapacityReservationSelector []CapacityReservationSelectorTerm `json:"capacityReservationSelector,omitempty" hash:"ignore"`
+	UserData                    *string                           `json:"userData,omitempty"`
+	PreInstallScript            *string                           `json:"preInstallScript,omitempty"`
+	MetaData                    map[string]string                 `json:"metaData,omitempty"`
+	ImageFamily                 string                            `json:"imageFamily"`
 	// Tags to be applied on instance resources
 	// deprecated, use DefinedTags instead
 	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
@@ -103,10 +112,99 @@ type OciNodeClassSpec struct {
 	Kubelet       *KubeletConfiguration `json:"kubelet,omitempty" hash:"ignore"`
 	BootConfig    *BootConfig           `json:"bootConfig"`
 	LaunchOptions *LaunchOptions        `json:"launchOptions,omitempty"`
-	BlockDevices  []*VolumeAttributes   `json:"blockDevices,omitempty"`
-	AgentList     []string              `json:"agentList,omitempty"`
+	// PlatformConfig enables the shielded instance and confidential computing features of the instance,
+	// shapes that don't support the requested features are not considered for launch.
+	// +kubebuilder:validation:XValidation:message="isMeasuredBootEnabled requires isTrustedPlatformModuleEnabled",rule="has(self.isMeasuredBootEnabled) && self.isMeasuredBootEnabled ? has(self.isTrustedPlatformModuleEnabled) && self.isTrustedPlatformModuleEnabled : true"
+	// +optional
+	PlatformConfig *PlatformConfig `json:"platformConfig,omitempty"`
+	// FlexShapeConfig defines the sizes the flexible shapes are split into for the nodeclass,
+	// the flexCpuMemRatios and flexCpuConstrainList settings are used when not set.
+	// +optional
+	FlexShapeConfig *FlexShapeConfig `json:"flexShapeConfig,omitempty" hash:"ignore"`
+	// +kubebuilder:validation:XValidation:message="device must be unique across blockDevices",rule="self.all(x, !has(x.device) || self.exists_one(y, has(y.device) && y.device == x.device))"
+	// +kubebuilder:validation:XValidation:message="mountPath must be unique across blockDevices",rule="self.all(x, !has(x.mountPath) || self.exists_one(y, has(y.mountPath) && y.mountPath == x.mountPath))"
+	// +kubebuilder:validation:MaxItems:=32
+	BlockDevices []*VolumeAttributes `json:"blockDevices,omitempty"`
+	// InstanceStorePolicy decides how the local NVMe disks of the DenseIO shapes are used.
+	// RAID0 assembles the disks into a RAID0 array which the kubelet and container runtime storage is moved onto,
+	// the size of the array is used as the ephemeral storage of the node. The disks aren't used when not set.
+	// +kubebuilder:validation:Enum=RAID0
+	// +optional
+	InstanceStorePolicy *string  `json:"instanceStorePolicy,omitempty"`
+	AgentList           []string `json:"agentList,omitempty"`
+	// ConsoleHistory captures the serial console history of the instances which are deleted before their nodes
+	// register or while their nodes aren't ready, the history is kept in a configmap to debug the bootstrap failures.
+	// +optional
+	ConsoleHistory *ConsoleHistory `json:"consoleHistory,omitempty" hash:"ignore"`
+}
+
+type ConsoleHistory struct {
+	// Enabled captures the console history before the unregistered or unhealthy instances are deleted.
+	// +required
+	Enabled bool `json:"enabled"`
+	// MaxSizeInKiB is the size of the end of the console history which is kept, defaults to 64.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=512
+	// +optional
+	MaxSizeInKiB *int64 `json:"maxSizeInKiB,omitempty"`
+}
+
+type FlexShapeConfig struct {
+	// Sizing decides how the flexible shapes are sized, defaults to Split.
+	// Split creates an instance type for every ocpu count and memory size the flexible shapes are split into.
+	// Dynamic creates an instance type per flexible shape which is sized to the smallest ocpu count and memory size
+	// fitting the resource requests of the nodeclaim at launch, cpuMemRatios are ignored while ocpus, ocpuRanges
+	// and memoryRanges still limit the sizes.
+	// +kubebuilder:validation:Enum=Split;Dynamic
+	// +optional
+	Sizing *string `json:"sizing,omitempty"`
+	// CpuMemRatios are the memory sizes in GB per vcpu, eg. [2,4], a flex instance with 2 vcpus(1 ocpu) is created with 4GB or 8GB memory.
+	// +kubebuilder:validation:items:Minimum=1
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	CpuMemRatios []int64 `json:"cpuMemRatios,omitempty"`
+	// Ocpus is the list of the ocpu counts flex instances are created with.
+	// +kubebuilder:validation:items:Minimum=1
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	Ocpus []int64 `json:"ocpus,omitempty"`
+	// OcpuRanges are the ranges of the ocpu counts flex instances are created with, merged with ocpus.
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	OcpuRanges []OcpuRange `json:"ocpuRanges,omitempty"`
+	// MemoryRanges restrict the memory sizes of the flex instances, all the memory sizes are allowed when not set.
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	MemoryRanges []MemoryRange `json:"memoryRanges,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:message="max must be greater than or equal to min",rule="self.max >= self.min"
+type OcpuRange struct {
+	// +kubebuilder:validation:Minimum=1
+	// +required
+	Min int64 `json:"min"`
+	// +kubebuilder:validation:Minimum=1
+	// +required
+	Max int64 `json:"max"`
+	// Step is the increment of the ocpu counts inside the range, defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Step *int64 `json:"step,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:message="maxInGBs must be greater than or equal to minInGBs",rule="self.maxInGBs >= self.minInGBs"
+type MemoryRange struct {
+	// +kubebuilder:validation:Minimum=1
+	// +required
+	MinInGBs int64 `json:"minInGBs"`
+	// +kubebuilder:validation:Minimum=1
+	// +required
+	MaxInGBs int64 `json:"maxInGBs"`
 }
 
+// +kubebuilder:validation:XValidation:message="device is required when mountPath is set",rule="has(self.mountPath) ? has(self.device) : true"
+// +kubebuilder:validation:XValidation:message="fileSystem requires mountPath",rule="has(self.fileSystem) ? has(self.mountPath) : true"
+// +kubebuilder:validation:XValidation:message="read only volume can't be formatted and mounted",rule="has(self.mountPath) && has(self.isReadOnly) ? !self.isReadOnly : true"
 type VolumeAttributes struct {
 	// SizeInGBs specifies the size of the block volume in GB.
 	// Must be between 50 and 32768.
@@ -115,9 +213,51 @@ type VolumeAttributes struct {
 	SizeInGBs int64 `json:"sizeInGBs"`
 
 	// VpusPerGB specifies the number of volume performance units per GB.
-	// Allowed values: 0 (low cost), 10 (balanced), 20 (high performance).
-	// +kubebuilder:validation:Enum=0;10;20
+	// Allowed values: 0 (low cost), 10 (balanced), 20 (high performance), 30-120 (ultra high performance).
+	// +kubebuilder:validation:Enum=0;10;20;30;40;50;60;70;80;90;100;110;120
 	VpusPerGB int64 `json:"vpusPerGB"`
+
+	// AutotunePolicies are the auto-tune policies applied to the volume once the instance is launched.
+	// +kubebuilder:validation:XValidation:message="autotuneType must be unique",rule="self.all(x, self.exists_one(y, x.autotuneType == y.autotuneType))"
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	AutotunePolicies []*AutotunePolicy `json:"autotunePolicies,omitempty"`
+
+	// KmsKeyId is the OCID of the Vault service key to assign as the master encryption key for the volume,
+	// the volume is encrypted with Oracle-managed keys when not set.
+	// +optional
+	KmsKeyId *string `json:"kmsKeyId,omitempty"`
+
+	// AttachmentType specifies how the volume is attached to the instance, defaults to iscsi.
+	// +kubebuilder:validation:Enum=iscsi;paravirtualized
+	// +optional
+	AttachmentType *string `json:"attachmentType,omitempty"`
+
+	// Device is the consistent device path of the volume, for example /dev/oracleoci/oraclevdb.
+	// +kubebuilder:validation:Pattern=`^/dev/oracleoci/oraclevd[a-z]{1,2}$`
+	// +kubebuilder:validation:MaxLength=32
+	// +optional
+	Device *string `json:"device,omitempty"`
+
+	// IsReadOnly attaches the volume in read-only mode.
+	// +optional
+	IsReadOnly *bool `json:"isReadOnly,omitempty"`
+
+	// IsShareable attaches the volume in shareable mode.
+	// +optional
+	IsShareable *bool `json:"isShareable,omitempty"`
+
+	// FileSystem is the file system the device is formatted with before mounting, defaults to ext4.
+	// +kubebuilder:validation:Enum=ext4;xfs
+	// +optional
+	FileSystem *string `json:"fileSystem,omitempty"`
+
+	// MountPath is the path the device is mounted at by the bootstrap script, for example /var/lib/containerd.
+	// The capacity of the volume is used as the ephemeral storage of the node when it's mounted to the kubelet root dir.
+	// +kubebuilder:validation:Pattern=`^/.*`
+	// +kubebuilder:validation:MaxLength=255
+	// +optional
+	MountPath *string `json:"mountPath,omitempty"`
 }
 
 type OciNodeClassStatus struct {
@@ -133,6 +273,10 @@ type OciNodeClassStatus struct {
 	// cluster under the security group spec.
 	// +optional
 	SecurityGroups []*SecurityGroup `json:"securityGroups,omitempty"`
+	// CapacityReservations contains the current capacity reservation values that are available to the
+	// cluster under the capacity reservation selectors, one entry per reserved shape configuration.
+	// +optional
+	CapacityReservations []*CapacityReservation `json:"capacityReservations,omitempty"`
 	// Conditions contains signals for health and readiness
 	// +optional
 	Conditions []status.Condition `json:"conditions,omitempty"`
@@ -142,6 +286,10 @@ type Subnet struct {
 	Id              string                   `json:"id,omitempty"`
 	Name            string                   `json:"name,omitempty"`
 	CidrUtilization []CidrUtilizationSummary `json:"cidrUtilization,omitempty"`
+	// AvailabilityDomains are the availability domains without the tenancy prefix where the subnet can launch
+	// instances, a regional subnet spans all the availability domains of the region.
+	// +optional
+	AvailabilityDomains []string `json:"availabilityDomains,omitempty"`
 }
 
 type CidrUtilizationSummary struct {
@@ -167,6 +315,23 @@ type SecurityGroup struct {
 	Name string `json:"name,omitempty"`
 }
 
+type CapacityReservation struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// AvailabilityDomain is the availability domain of the reservation without the tenancy prefix.
+	AvailabilityDomain string `json:"availabilityDomain"`
+	InstanceShape      string `json:"instanceShape"`
+	// Ocpus and MemoryInGBs are only set for the reservations of flexible shapes.
+	// +optional
+	Ocpus int64 `json:"ocpus,omitempty"`
+	// +optional
+	MemoryInGBs int64 `json:"memoryInGBs,omitempty"`
+	// ReservedCount is the number of instances reserved for the shape configuration.
+	ReservedCount int64 `json:"reservedCount"`
+	// UsedCount is the number of instances already launched into the shape configuration.
+	UsedCount int64 `json:"usedCount"`
+}
+
 type ImageSelectorTerm struct {
 	// ID is the ami id in instance
 	// +kubebuilder:validation:Pattern:="ocid1.image.[0-9a-z]+"
@@ -177,21 +342,103 @@ type ImageSelectorTerm struct {
 	// +optional
 	Name          string `json:"name,omitempty"`
 	CompartmentId string `json:"compartmentId,omitempty"`
+	// Tags is a map of key/value tags used to select images.
+	// Keys in the form of "namespace.key" match defined tags, other keys match freeform tags.
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 type SubnetSelectorTerm struct {
 	Id   string `json:"id,omitempty"`
 	Name string `json:"name,omitempty"`
+	// Tags is a map of key/value tags used to select subnets.
+	// Keys in the form of "namespace.key" match defined tags, other keys match freeform tags.
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 type SecurityGroupSelectorTerm struct {
 	Id   string `json:"id,omitempty"`
 	Name string `json:"name,omitempty"`
+	// Tags is a map of key/value tags used to select security groups.
+	// Keys in the form of "namespace.key" match defined tags, other keys match freeform tags.
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+type CapacityReservationSelectorTerm struct {
+	// Id is the ocid of the capacity reservation
+	// +optional
+	Id string `json:"id,omitempty"`
+	// Name is the display name of the capacity reservation
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Tags is a map of key/value tags used to select capacity reservations.
+	// Keys in the form of "namespace.key" match defined tags, other keys match freeform tags.
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 type BootConfig struct {
 	BootVolumeSizeInGBs int64 `json:"bootVolumeSizeInGBs"`
+	// BootVolumeVpusPerGB specifies the number of volume performance units per GB of the boot volume.
+	// Allowed values: 10 (balanced), 20 (high performance), 30-120 (ultra high performance).
+	// +kubebuilder:validation:Enum=10;20;30;40;50;60;70;80;90;100;110;120
 	BootVolumeVpusPerGB int64 `json:"bootVolumeVpusPerGB"`
+	// AutotunePolicies are the auto-tune policies applied to the boot volume once the instance is launched.
+	// +kubebuilder:validation:XValidation:message="autotuneType must be unique",rule="self.all(x, self.exists_one(y, x.autotuneType == y.autotuneType))"
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	AutotunePolicies []*AutotunePolicy `json:"autotunePolicies,omitempty"`
+	// KmsKeyId is the OCID of the Vault service key to assign as the master encryption key for the boot volume,
+	// the boot volume is encrypted with Oracle-managed keys when not set.
+	// +optional
+	KmsKeyId *string `json:"kmsKeyId,omitempty"`
+	// IsPvEncryptionInTransitEnabled enables the in-transit encryption for the data volume's paravirtualized attachment.
+	// +optional
+	IsPvEncryptionInTransitEnabled *bool `json:"isPvEncryptionInTransitEnabled,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:message="maxVpusPerGB is required for PERFORMANCE_BASED and not allowed for DETACHED_VOLUME",rule="self.autotuneType == 'PERFORMANCE_BASED' ? has(self.maxVpusPerGB) : !has(self.maxVpusPerGB)"
+type AutotunePolicy struct {
+	// AutotuneType is the type of the auto-tune policy. DETACHED_VOLUME lowers the performance to 0 VPUs per GB
+	// while the volume is detached, PERFORMANCE_BASED raises the performance up to MaxVpusPerGB while the volume is throttled.
+	// +kubebuilder:validation:Enum=DETACHED_VOLUME;PERFORMANCE_BASED
+	AutotuneType string `json:"autotuneType"`
+	// MaxVpusPerGB is the maximum VPUs per GB the volume is auto-tuned to, only for PERFORMANCE_BASED.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=120
+	// +kubebuilder:validation:MultipleOf=10
+	// +optional
+	MaxVpusPerGB *int64 `json:"maxVpusPerGB,omitempty"`
+}
+
+type PlatformConfig struct {
+
+	// Whether Secure Boot is enabled on the instance.
+	IsSecureBootEnabled *bool `mandatory:"false" json:"isSecureBootEnabled,omitempty"`
+
+	// Whether the Trusted Platform Module (TPM) is enabled on the instance.
+	IsTrustedPlatformModuleEnabled *bool `mandatory:"false" json:"isTrustedPlatformModuleEnabled,omitempty"`
+
+	// Whether the Measured Boot feature is enabled on the instance, requires the Trusted Platform Module.
+	IsMeasuredBootEnabled *bool `mandatory:"false" json:"isMeasuredBootEnabled,omitempty"`
+
+	// Whether the instance is a confidential instance. If this value is `true`, the instance is a confidential instance.
+	// The default value is `false`. Confidential instances use AMD Secure Encrypted Virtualization (SEV).
+	IsMemoryEncryptionEnabled *bool `mandatory:"false" json:"isMemoryEncryptionEnabled,omitempty"`
 }
 
 type LaunchOptions struct {
@@ -319,6 +566,8 @@ func (in *OciNodeClass) StatusConditions() status.ConditionSet {
 		ConditionTypeImageReady,
 		ConditionTypeSubnetsReady,
 		ConditionTypeSecurityGroupsReady,
+		ConditionTypeCapacityReservationsReady,
+		ConditionTypeKmsKeyReady,
 	).For(in)
 }
 
@@ -25,9 +25,50 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutotunePolicy) DeepCopyInto(out *AutotunePolicy) {
+	*out = *in
+	if in.MaxVpusPerGB != nil {
+		in, out := &in.MaxVpusPerGB, &out.MaxVpusPerGB
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutotunePolicy.
+func (in *AutotunePolicy) DeepCopy() *AutotunePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AutotunePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BootConfig) DeepCopyInto(out *BootConfig) {
 	*out = *in
+	if in.AutotunePolicies != nil {
+		in, out := &in.AutotunePolicies, &out.AutotunePolicies
+		*out = make([]*AutotunePolicy, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(AutotunePolicy)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.KmsKeyId != nil {
+		in, out := &in.KmsKeyId, &out.KmsKeyId
+		*out = new(string)
+		**out = **in
+	}
+	if in.IsPvEncryptionInTransitEnabled != nil {
+		in, out := &in.IsPvEncryptionInTransitEnabled, &out.IsPvEncryptionInTransitEnabled
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootConfig.
@@ -40,6 +81,43 @@ func (in *BootConfig) DeepCopy() *BootConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservation.
+func (in *CapacityReservation) DeepCopy() *CapacityReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservationSelectorTerm) DeepCopyInto(out *CapacityReservationSelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservationSelectorTerm.
+func (in *CapacityReservationSelectorTerm) DeepCopy() *CapacityReservationSelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservationSelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CidrUtilizationSummary) DeepCopyInto(out *CidrUtilizationSummary) {
 	*out = *in
@@ -55,6 +133,26 @@ func (in *CidrUtilizationSummary) DeepCopy() *CidrUtilizationSummary {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsoleHistory) DeepCopyInto(out *ConsoleHistory) {
+	*out = *in
+	if in.MaxSizeInKiB != nil {
+		in, out := &in.MaxSizeInKiB, &out.MaxSizeInKiB
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsoleHistory.
+func (in *ConsoleHistory) DeepCopy() *ConsoleHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsoleHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in DefinedTagValue) DeepCopyInto(out *DefinedTagValue) {
 	{
@@ -76,6 +174,48 @@ func (in DefinedTagValue) DeepCopy() DefinedTagValue {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlexShapeConfig) DeepCopyInto(out *FlexShapeConfig) {
+	*out = *in
+	if in.Sizing != nil {
+		in, out := &in.Sizing, &out.Sizing
+		*out = new(string)
+		**out = **in
+	}
+	if in.CpuMemRatios != nil {
+		in, out := &in.CpuMemRatios, &out.CpuMemRatios
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ocpus != nil {
+		in, out := &in.Ocpus, &out.Ocpus
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.OcpuRanges != nil {
+		in, out := &in.OcpuRanges, &out.OcpuRanges
+		*out = make([]OcpuRange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MemoryRanges != nil {
+		in, out := &in.MemoryRanges, &out.MemoryRanges
+		*out = make([]MemoryRange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlexShapeConfig.
+func (in *FlexShapeConfig) DeepCopy() *FlexShapeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(FlexShapeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Image) DeepCopyInto(out *Image) {
 	*out = *in
@@ -101,6 +241,13 @@ func (in *Image) DeepCopy() *Image {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageSelectorTerm) DeepCopyInto(out *ImageSelectorTerm) {
 	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSelectorTerm.
@@ -238,6 +385,21 @@ func (in *LaunchOptions) DeepCopy() *LaunchOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemoryRange) DeepCopyInto(out *MemoryRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemoryRange.
+func (in *MemoryRange) DeepCopy() *MemoryRange {
+	if in == nil {
+		return nil
+	}
+	out := new(MemoryRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OciNodeClass) DeepCopyInto(out *OciNodeClass) {
 	*out = *in
@@ -303,17 +465,30 @@ func (in *OciNodeClassSpec) DeepCopyInto(out *OciNodeClassSpec) {
 	if in.ImageSelector != nil {
 		in, out := &in.ImageSelector, &out.ImageSelector
 		*out = make([]ImageSelectorTerm, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.SubnetSelector != nil {
 		in, out := &in.SubnetSelector, &out.SubnetSelector
 		*out = make([]SubnetSelectorTerm, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.SecurityGroupSelector != nil {
 		in, out := &in.SecurityGroupSelector, &out.SecurityGroupSelector
 		*out = make([]SecurityGroupSelectorTerm, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CapacityReservationSelector != nil {
+		in, out := &in.CapacityReservationSelector, &out.CapacityReservationSelector
+		*out = make([]CapacityReservationSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.UserData != nil {
 		in, out := &in.UserData, &out.UserData
@@ -372,13 +547,23 @@ func (in *OciNodeClassSpec) DeepCopyInto(out *OciNodeClassSpec) {
 	if in.BootConfig != nil {
 		in, out := &in.BootConfig, &out.BootConfig
 		*out = new(BootConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.LaunchOptions != nil {
 		in, out := &in.LaunchOptions, &out.LaunchOptions
 		*out = new(LaunchOptions)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PlatformConfig != nil {
+		in, out := &in.PlatformConfig, &out.PlatformConfig
+		*out = new(PlatformConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FlexShapeConfig != nil {
+		in, out := &in.FlexShapeConfig, &out.FlexShapeConfig
+		*out = new(FlexShapeConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.BlockDevices != nil {
 		in, out := &in.BlockDevices, &out.BlockDevices
 		*out = make([]*VolumeAttributes, len(*in))
@@ -386,15 +571,25 @@ func (in *OciNodeClassSpec) DeepCopyInto(out *OciNodeClassSpec) {
 			if (*in)[i] != nil {
 				in, out := &(*in)[i], &(*out)[i]
 				*out = new(VolumeAttributes)
-				**out = **in
+				(*in).DeepCopyInto(*out)
 			}
 		}
 	}
+	if in.InstanceStorePolicy != nil {
+		in, out := &in.InstanceStorePolicy, &out.InstanceStorePolicy
+		*out = new(string)
+		**out = **in
+	}
 	if in.AgentList != nil {
 		in, out := &in.AgentList, &out.AgentList
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ConsoleHistory != nil {
+		in, out := &in.ConsoleHistory, &out.ConsoleHistory
+		*out = new(ConsoleHistory)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OciNodeClassSpec.
@@ -443,6 +638,17 @@ func (in *OciNodeClassStatus) DeepCopyInto(out *OciNodeClassStatus) {
 			}
 		}
 	}
+	if in.CapacityReservations != nil {
+		in, out := &in.CapacityReservations, &out.CapacityReservations
+		*out = make([]*CapacityReservation, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(CapacityReservation)
+				**out = **in
+			}
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]status.Condition, len(*in))
@@ -462,6 +668,61 @@ func (in *OciNodeClassStatus) DeepCopy() *OciNodeClassStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OcpuRange) DeepCopyInto(out *OcpuRange) {
+	*out = *in
+	if in.Step != nil {
+		in, out := &in.Step, &out.Step
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OcpuRange.
+func (in *OcpuRange) DeepCopy() *OcpuRange {
+	if in == nil {
+		return nil
+	}
+	out := new(OcpuRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformConfig) DeepCopyInto(out *PlatformConfig) {
+	*out = *in
+	if in.IsSecureBootEnabled != nil {
+		in, out := &in.IsSecureBootEnabled, &out.IsSecureBootEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IsTrustedPlatformModuleEnabled != nil {
+		in, out := &in.IsTrustedPlatformModuleEnabled, &out.IsTrustedPlatformModuleEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IsMeasuredBootEnabled != nil {
+		in, out := &in.IsMeasuredBootEnabled, &out.IsMeasuredBootEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IsMemoryEncryptionEnabled != nil {
+		in, out := &in.IsMemoryEncryptionEnabled, &out.IsMemoryEncryptionEnabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformConfig.
+func (in *PlatformConfig) DeepCopy() *PlatformConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroup) DeepCopyInto(out *SecurityGroup) {
 	*out = *in
@@ -480,6 +741,13 @@ func (in *SecurityGroup) DeepCopy() *SecurityGroup {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroupSelectorTerm) DeepCopyInto(out *SecurityGroupSelectorTerm) {
 	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityGroupSelectorTerm.
@@ -500,6 +768,11 @@ func (in *Subnet) DeepCopyInto(out *Subnet) {
 		*out = make([]CidrUtilizationSummary, len(*in))
 		copy(*out, *in)
 	}
+	if in.AvailabilityDomains != nil {
+		in, out := &in.AvailabilityDomains, &out.AvailabilityDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Subnet.
@@ -515,6 +788,13 @@ func (in *Subnet) DeepCopy() *Subnet {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SubnetSelectorTerm) DeepCopyInto(out *SubnetSelectorTerm) {
 	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetSelectorTerm.
@@ -530,6 +810,52 @@ func (in *SubnetSelectorTerm) DeepCopy() *SubnetSelectorTerm {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeAttributes) DeepCopyInto(out *VolumeAttributes) {
 	*out = *in
+	if in.AutotunePolicies != nil {
+		in, out := &in.AutotunePolicies, &out.AutotunePolicies
+		*out = make([]*AutotunePolicy, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(AutotunePolicy)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.KmsKeyId != nil {
+		in, out := &in.KmsKeyId, &out.KmsKeyId
+		*out = new(string)
+		**out = **in
+	}
+	if in.AttachmentType != nil {
+		in, out := &in.AttachmentType, &out.AttachmentType
+		*out = new(string)
+		**out = **in
+	}
+	if in.Device != nil {
+		in, out := &in.Device, &out.Device
+		*out = new(string)
+		**out = **in
+	}
+	if in.IsReadOnly != nil {
+		in, out := &in.IsReadOnly, &out.IsReadOnly
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IsShareable != nil {
+		in, out := &in.IsShareable, &out.IsShareable
+		*out = new(bool)
+		**out = **in
+	}
+	if in.FileSystem != nil {
+		in, out := &in.FileSystem, &out.FileSystem
+		*out = new(string)
+		**out = **in
+	}
+	if in.MountPath != nil {
+		in, out := &in.MountPath, &out.MountPath
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeAttributes.
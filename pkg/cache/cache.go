@@ -24,6 +24,27 @@ const (
 	// UnavailableOfferingsTTL is the time before offerings that were marked as unavailable
 	// are removed from the cache and are available for launch again
 	UnavailableOfferingsTTL = 3 * time.Minute
+	// LimitExceededOfferingsTTL is the time before offerings that reached the service limits are available for
+	// launch again, the limits are raised by a request rather than recovered over minutes
+	LimitExceededOfferingsTTL = 30 * time.Minute
+	// QuotaExceededOfferingsTTL is the longest time before offerings that reached the compartment quotas are available
+	// for launch again, they are available as soon as the quotas can fit the instance again
+	QuotaExceededOfferingsTTL = 24 * time.Hour
+	// DegradedZoneWindow is the window in which the instance types out of capacity in a zone are counted
+	DegradedZoneWindow = 5 * time.Minute
+	// DegradedZoneThreshold is the number of the instance types out of capacity in a zone within the window before
+	// the zone is degraded
+	DegradedZoneThreshold = 5
+	// DegradedZoneTTL is the time before the offerings of a degraded zone are available for launch again
+	DegradedZoneTTL = 10 * time.Minute
+	// LimitsFailureTTL is the time before the limits which failed to resolve are queried again, the limits are assumed
+	// to fit in the meantime
+	LimitsFailureTTL = 5 * time.Minute
+	// CapacityProbeTTL is the time before the capacity of a shape in a zone is probed again
+	CapacityProbeTTL = 2 * time.Minute
+	// ConsoleHistoryTTL is the time before the console history of an instance is captured again, the instance is
+	// deleted repeatedly until it's terminated
+	ConsoleHistoryTTL = 30 * time.Minute
 )
 
 const (
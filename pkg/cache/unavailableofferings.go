@@ -17,53 +17,254 @@ package cache
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/utils"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 )
 
+// UnavailableReason is the reason an offering is unavailable, the offering is unavailable for the TTL of its reason
+type UnavailableReason string
+
+const (
+	// UnavailableReasonOutOfCapacity is a launch out of host capacity
+	UnavailableReasonOutOfCapacity UnavailableReason = "OutOfCapacity"
+	// UnavailableReasonCapacityReport is a capacity report out of host capacity
+	UnavailableReasonCapacityReport UnavailableReason = "CapacityReport"
+	// UnavailableReasonLimitExceeded is a launch over the service limits of the shape
+	UnavailableReasonLimitExceeded UnavailableReason = "LimitExceeded"
+	// UnavailableReasonQuotaExceeded is a launch over the compartment quotas of the shape
+	UnavailableReasonQuotaExceeded UnavailableReason = "QuotaExceeded"
+)
+
+// TTL returns the time the offering is unavailable for the reason, the host capacity recovers in minutes while the
+// limits and the quotas are raised by a request
+func (r UnavailableReason) TTL() time.Duration {
+	switch r {
+	case UnavailableReasonLimitExceeded:
+		return LimitExceededOfferingsTTL
+	case UnavailableReasonQuotaExceeded:
+		return QuotaExceededOfferingsTTL
+	}
+	return UnavailableOfferingsTTL
+}
+
+// degradesZone returns true if the reason counts toward degrading the zone, only the launches out of capacity are
+// counted. The capacity reports are taken for the scarce shapes, which are often out of capacity in a healthy zone.
+func (r UnavailableReason) degradesZone() bool {
+	return r == UnavailableReasonOutOfCapacity
+}
+
+// UnavailableOffering is an offering marked unavailable
+type UnavailableOffering struct {
+	InstanceType string            `json:"instanceType"`
+	Zone         string            `json:"zone"`
+	CapacityType string            `json:"capacityType"`
+	Reason       UnavailableReason `json:"reason"`
+	Expiration   time.Time         `json:"expiration"`
+}
+
+// DegradedZone is a zone where many instance types ran out of capacity, all the on-demand and preemptible offerings
+// of the zone are unavailable
+type DegradedZone struct {
+	Zone       string    `json:"zone"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// Snapshot is the state of the unavailable offerings, it is persisted so a restarted controller doesn't repeat the
+// known failures
+type Snapshot struct {
+	Offerings     []UnavailableOffering `json:"offerings"`
+	DegradedZones []DegradedZone        `json:"degradedZones"`
+}
+
 // UnavailableOfferings stores any offerings that return ICE (insufficient capacity errors) when
 // attempting to launch the capacity. These offerings are ignored as long as they are in the cache on
 // GetInstanceTypes responses
 type UnavailableOfferings struct {
-	// key: <capacityType>:<instanceType>:<zone>, value: struct{}{}
-	cache  *cache.Cache
-	SeqNum uint64
+	// key: <capacityType>:<instanceType>:<zone>, value: UnavailableOffering
+	cache *cache.Cache
+	// key: <zone>, value: DegradedZone
+	degradedZones *cache.Cache
+	mu            sync.Mutex
+	// the last out of capacity time of the instance types in each zone, key: <zone>
+	zoneFailures map[string]map[string]time.Time
+	SeqNum       uint64
 }
 
 func NewUnavailableOfferings() *UnavailableOfferings {
 	uo := &UnavailableOfferings{
-		cache:  cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
-		SeqNum: 0,
+		cache:         cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
+		degradedZones: cache.New(DegradedZoneTTL, UnavailableOfferingsCleanupInterval),
+		zoneFailures:  map[string]map[string]time.Time{},
+		SeqNum:        0,
 	}
 	uo.cache.OnEvicted(func(_ string, _ interface{}) {
 		atomic.AddUint64(&uo.SeqNum, 1)
 	})
+	uo.degradedZones.OnEvicted(func(_ string, _ interface{}) {
+		atomic.AddUint64(&uo.SeqNum, 1)
+	})
 	return uo
 }
 
-// IsUnavailable returns true if the offering appears in the cache
+// IsUnavailable returns true if the offering appears in the cache or its zone is degraded, the reserved offerings
+// aren't affected by the degraded zones as their capacity is held by the reservations
 func (u *UnavailableOfferings) IsUnavailable(instanceType, zone, capacityType string) bool {
-	_, found := u.cache.Get(u.key(instanceType, zone, capacityType))
+	if _, found := u.cache.Get(u.key(instanceType, zone, capacityType)); found {
+		return true
+	}
+	return capacityType != karpv1.CapacityTypeReserved && u.IsDegraded(zone)
+}
+
+// IsDegraded returns true if many instance types recently ran out of capacity in the zone
+func (u *UnavailableOfferings) IsDegraded(zone string) bool {
+	_, found := u.degradedZones.Get(zone)
 	return found
 }
 
+// ZoneUnavailableCount returns the number of the offerings in the zone which recently returned an insufficient capacity error
+func (u *UnavailableOfferings) ZoneUnavailableCount(zone string) int {
+	return len(lo.Filter(lo.Keys(u.cache.Items()), func(key string, _ int) bool {
+		return strings.HasSuffix(key, ":"+zone)
+	}))
+}
+
 // MarkUnavailable communicates recently observed temporary capacity shortages in the provided offerings
-func (u *UnavailableOfferings) MarkUnavailable(ctx context.Context, unavailableReason, instanceType, zone, capacityType string) {
+func (u *UnavailableOfferings) MarkUnavailable(ctx context.Context, reason UnavailableReason, instanceType, zone, capacityType string) {
+	u.markUnavailable(ctx, reason, "", instanceType, zone, capacityType)
+}
+
+// MarkUnavailableForLaunchInstanceErr marks the offering unavailable for the TTL of the class of the launch error
+func (u *UnavailableOfferings) MarkUnavailableForLaunchInstanceErr(ctx context.Context, err error, capacityType string, instanceType string, zone string) {
+	u.MarkUnavailableForLaunchFailure(ctx, utils.ClassifyOciError(err), err.Error(), capacityType, instanceType, zone)
+}
+
+// MarkUnavailableForLaunchFailure marks the offering unavailable for the TTL of the class of the failure, only the
+// out of capacity, limits and quotas are failures of the offering, the others don't make the offering unavailable
+func (u *UnavailableOfferings) MarkUnavailableForLaunchFailure(ctx context.Context, failure utils.OciErrorReason, message, capacityType, instanceType, zone string) {
+	var reason UnavailableReason
+	switch failure {
+	case utils.OciErrorOutOfCapacity:
+		reason = UnavailableReasonOutOfCapacity
+	case utils.OciErrorLimitExceeded:
+		reason = UnavailableReasonLimitExceeded
+	case utils.OciErrorQuotaExceeded:
+		reason = UnavailableReasonQuotaExceeded
+	default:
+		return
+	}
+	u.markUnavailable(ctx, reason, message, instanceType, zone, capacityType)
+}
+
+func (u *UnavailableOfferings) markUnavailable(ctx context.Context, reason UnavailableReason, message, instanceType, zone, capacityType string) {
 	// even if the key is already in the cache, we still need to call Set to extend the cached entry's TTL
 	log.FromContext(ctx).WithValues(
-		"reason", unavailableReason,
+		"reason", reason,
+		"message", message,
 		"instance-type", instanceType,
 		"zone", zone,
 		"capacity-type", capacityType,
-		"ttl", UnavailableOfferingsTTL).V(1).Info("removing offering from offerings")
-	u.cache.SetDefault(u.key(instanceType, zone, capacityType), struct{}{})
+		"ttl", reason.TTL()).V(1).Info("removing offering from offerings")
+	u.cache.Set(u.key(instanceType, zone, capacityType), UnavailableOffering{
+		InstanceType: instanceType,
+		Zone:         zone,
+		CapacityType: capacityType,
+		Reason:       reason,
+	}, reason.TTL())
 	atomic.AddUint64(&u.SeqNum, 1)
+	if reason.degradesZone() {
+		u.recordZoneFailure(ctx, instanceType, zone)
+	}
 }
 
-func (u *UnavailableOfferings) MarkUnavailableForLaunchInstanceErr(ctx context.Context, err error, capacityType string, instanceType string, zone string) {
-	u.MarkUnavailable(ctx, err.Error(), instanceType, zone, capacityType)
+// recordZoneFailure degrades the zone when too many instance types ran out of capacity in the zone within the window
+func (u *UnavailableOfferings) recordZoneFailure(ctx context.Context, instanceType, zone string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := time.Now()
+	failures, ok := u.zoneFailures[zone]
+	if !ok {
+		failures = map[string]time.Time{}
+		u.zoneFailures[zone] = failures
+	}
+	failures[instanceType] = now
+	for name, failed := range failures {
+		if now.Sub(failed) > DegradedZoneWindow {
+			delete(failures, name)
+		}
+	}
+	if len(failures) < DegradedZoneThreshold {
+		return
+	}
+	delete(u.zoneFailures, zone)
+	u.degradedZones.Set(zone, DegradedZone{Zone: zone}, DegradedZoneTTL)
+	atomic.AddUint64(&u.SeqNum, 1)
+	instanceTypes := lo.Keys(failures)
+	sort.Strings(instanceTypes)
+	log.FromContext(ctx).WithValues("zone", zone, "instance-types", instanceTypes, "ttl", DegradedZoneTTL).
+		Info("zone is degraded, removing the offerings of the zone from offerings")
+}
+
+// ResetQuotaExceeded makes the offerings which exceeded the quotas available again once the quotas can fit their
+// instance types in their zones, the unavailable offerings are scanned once for all the instance types
+func (u *UnavailableOfferings) ResetQuotaExceeded(fits func(instanceType, zone string) bool) {
+	for key, item := range u.cache.Items() {
+		if offering := item.Object.(UnavailableOffering); offering.Reason == UnavailableReasonQuotaExceeded &&
+			fits(offering.InstanceType, offering.Zone) {
+			u.cache.Delete(key)
+			atomic.AddUint64(&u.SeqNum, 1)
+		}
+	}
+}
+
+// Snapshot returns the unexpired unavailable offerings and degraded zones sorted by their keys
+func (u *UnavailableOfferings) Snapshot() Snapshot {
+	snapshot := Snapshot{Offerings: []UnavailableOffering{}, DegradedZones: []DegradedZone{}}
+	offerings := u.cache.Items()
+	for _, key := range lo.Keys(offerings) {
+		offering := offerings[key].Object.(UnavailableOffering)
+		offering.Expiration = time.Unix(0, offerings[key].Expiration).UTC()
+		snapshot.Offerings = append(snapshot.Offerings, offering)
+	}
+	sort.Slice(snapshot.Offerings, func(i, j int) bool {
+		a, b := snapshot.Offerings[i], snapshot.Offerings[j]
+		return u.key(a.InstanceType, a.Zone, a.CapacityType) < u.key(b.InstanceType, b.Zone, b.CapacityType)
+	})
+	zones := u.degradedZones.Items()
+	for _, zone := range lo.Keys(zones) {
+		degraded := zones[zone].Object.(DegradedZone)
+		degraded.Expiration = time.Unix(0, zones[zone].Expiration).UTC()
+		snapshot.DegradedZones = append(snapshot.DegradedZones, degraded)
+	}
+	sort.Slice(snapshot.DegradedZones, func(i, j int) bool { return snapshot.DegradedZones[i].Zone < snapshot.DegradedZones[j].Zone })
+	return snapshot
+}
+
+// Restore adds the unexpired offerings and zones of the snapshot until their expirations, the offerings and zones
+// which are already in the cache are kept
+func (u *UnavailableOfferings) Restore(snapshot Snapshot) {
+	for _, offering := range snapshot.Offerings {
+		key := u.key(offering.InstanceType, offering.Zone, offering.CapacityType)
+		if ttl := time.Until(offering.Expiration); ttl > 0 {
+			offering.Expiration = time.Time{}
+			_ = u.cache.Add(key, offering, ttl)
+		}
+	}
+	for _, degraded := range snapshot.DegradedZones {
+		if ttl := time.Until(degraded.Expiration); ttl > 0 {
+			degraded.Expiration = time.Time{}
+			_ = u.degradedZones.Add(degraded.Zone, degraded, ttl)
+		}
+	}
+	atomic.AddUint64(&u.SeqNum, 1)
 }
 
 func (u *UnavailableOfferings) Delete(instanceType string, zone string, capacityType string) {
@@ -72,6 +273,10 @@ func (u *UnavailableOfferings) Delete(instanceType string, zone string, capacity
 
 func (u *UnavailableOfferings) Flush() {
 	u.cache.Flush()
+	u.degradedZones.Flush()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.zoneFailures = map[string]map[string]time.Time{}
 }
 
 // key returns the cache key for all offerings in the cache
@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/fake"
+	"github.com/zoom/karpenter-oci/pkg/utils"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+)
+
+func expiration(t *testing.T, u *UnavailableOfferings, instanceType, zone, capacityType string) time.Duration {
+	t.Helper()
+	for _, offering := range u.Snapshot().Offerings {
+		if offering.InstanceType == instanceType && offering.Zone == zone && offering.CapacityType == capacityType {
+			return time.Until(offering.Expiration)
+		}
+	}
+	t.Fatalf("offering %s/%s/%s isn't unavailable", instanceType, zone, capacityType)
+	return 0
+}
+
+func TestMarkUnavailableForLaunchInstanceErr(t *testing.T) {
+	ctx := context.Background()
+	u := NewUnavailableOfferings()
+	for _, tc := range []struct {
+		instanceType string
+		err          error
+		reason       UnavailableReason
+		ttl          time.Duration
+	}{
+		{instanceType: "shape-1", err: &fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Message: "Out of host capacity."}, reason: UnavailableReasonOutOfCapacity, ttl: UnavailableOfferingsTTL},
+		{instanceType: "shape-2", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "LimitExceeded", Message: "Tenancy has reached the limit"}, reason: UnavailableReasonLimitExceeded, ttl: LimitExceededOfferingsTTL},
+		{instanceType: "shape-3", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "QuotaExceeded", Message: "Quota exceeded"}, reason: UnavailableReasonQuotaExceeded, ttl: QuotaExceededOfferingsTTL},
+	} {
+		u.MarkUnavailableForLaunchInstanceErr(ctx, tc.err, karpv1.CapacityTypeOnDemand, tc.instanceType, "US-ASHBURN-AD-1")
+		if ttl := expiration(t, u, tc.instanceType, "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand); ttl > tc.ttl || ttl < tc.ttl-time.Minute {
+			t.Errorf("expected the %s offering unavailable for %s, got %s", tc.reason, tc.ttl, ttl)
+		}
+		if offering := u.Snapshot().Offerings[len(u.Snapshot().Offerings)-1]; offering.Reason != tc.reason {
+			t.Errorf("expected reason %s, got %s", tc.reason, offering.Reason)
+		}
+	}
+}
+
+func TestMarkUnavailableForLaunchFailure(t *testing.T) {
+	ctx := context.Background()
+	u := NewUnavailableOfferings()
+	for _, failure := range []utils.OciErrorReason{utils.OciErrorUnknown, utils.OciErrorInternal, utils.OciErrorInvalidParameter} {
+		u.MarkUnavailableForLaunchFailure(ctx, failure, "instance is terminated", karpv1.CapacityTypeOnDemand, "shape-1", "US-ASHBURN-AD-1")
+	}
+	if u.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the offering available after the failures which aren't out of capacity")
+	}
+	u.MarkUnavailableForLaunchFailure(ctx, utils.OciErrorOutOfCapacity, "Out of host capacity.", karpv1.CapacityTypeOnDemand, "shape-1", "US-ASHBURN-AD-1")
+	if !u.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the out of capacity offering unavailable")
+	}
+}
+
+func TestResetQuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	u := NewUnavailableOfferings()
+	u.MarkUnavailable(ctx, UnavailableReasonQuotaExceeded, "shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+	u.MarkUnavailable(ctx, UnavailableReasonQuotaExceeded, "shape-1", "US-ASHBURN-AD-1", v1alpha1.CapacityTypePreemptible)
+	u.MarkUnavailable(ctx, UnavailableReasonOutOfCapacity, "shape-2", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+	u.MarkUnavailable(ctx, UnavailableReasonQuotaExceeded, "shape-1", "US-ASHBURN-AD-2", karpv1.CapacityTypeOnDemand)
+	u.ResetQuotaExceeded(func(instanceType, zone string) bool { return zone == "US-ASHBURN-AD-1" })
+	if u.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) || u.IsUnavailable("shape-1", "US-ASHBURN-AD-1", v1alpha1.CapacityTypePreemptible) {
+		t.Errorf("expected the quota exceeded offerings available after the reset")
+	}
+	if !u.IsUnavailable("shape-2", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the out of capacity offering unavailable after the reset")
+	}
+	if !u.IsUnavailable("shape-1", "US-ASHBURN-AD-2", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the quota exceeded offering unavailable in the zone whose quotas can't fit")
+	}
+}
+
+func TestDegradedZone(t *testing.T) {
+	ctx := context.Background()
+	u := NewUnavailableOfferings()
+	// the limits aren't a shortage of the zone
+	for i := 0; i < DegradedZoneThreshold; i++ {
+		u.MarkUnavailable(ctx, UnavailableReasonLimitExceeded, fmt.Sprintf("limited-%d", i), "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+	}
+	// the capacity types of an instance type are counted once
+	for i := 0; i < DegradedZoneThreshold-1; i++ {
+		u.MarkUnavailable(ctx, UnavailableReasonOutOfCapacity, fmt.Sprintf("shape-%d", i), "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+		u.MarkUnavailable(ctx, UnavailableReasonOutOfCapacity, fmt.Sprintf("shape-%d", i), "US-ASHBURN-AD-1", v1alpha1.CapacityTypePreemptible)
+	}
+	if u.IsDegraded("US-ASHBURN-AD-1") || u.IsUnavailable("shape-x", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Fatalf("expected the zone not degraded below the threshold")
+	}
+	u.MarkUnavailable(ctx, UnavailableReasonOutOfCapacity, "shape-last", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+	if !u.IsDegraded("US-ASHBURN-AD-1") {
+		t.Fatalf("expected the zone degraded at the threshold")
+	}
+	if !u.IsUnavailable("shape-x", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) || !u.IsUnavailable("shape-x", "US-ASHBURN-AD-1", v1alpha1.CapacityTypePreemptible) {
+		t.Errorf("expected the offerings of the degraded zone unavailable")
+	}
+	if u.IsUnavailable("shape-x", "US-ASHBURN-AD-1", karpv1.CapacityTypeReserved) || u.IsUnavailable("shape-x", "US-ASHBURN-AD-2", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the reserved offerings and the other zones available")
+	}
+	if zones := u.Snapshot().DegradedZones; len(zones) != 1 || zones[0].Zone != "US-ASHBURN-AD-1" {
+		t.Errorf("expected the degraded zone in the snapshot, got %v", zones)
+	}
+}
+
+func TestCapacityReportDoesNotDegradeZone(t *testing.T) {
+	ctx := context.Background()
+	u := NewUnavailableOfferings()
+	// the scarce shapes are probed, several of them out of capacity in a zone is normal
+	for i := 0; i < DegradedZoneThreshold*2; i++ {
+		u.MarkUnavailable(ctx, UnavailableReasonCapacityReport, fmt.Sprintf("BM.GPU-%d", i), "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+	}
+	if u.IsDegraded("US-ASHBURN-AD-1") || u.IsUnavailable("VM.Standard.E4.Flex", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Fatalf("expected the zone not degraded by the capacity reports")
+	}
+	if !u.IsUnavailable("BM.GPU-0", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the reported offering unavailable")
+	}
+	// the capacity reports aren't counted with the launches either
+	for i := 0; i < DegradedZoneThreshold-1; i++ {
+		u.MarkUnavailable(ctx, UnavailableReasonOutOfCapacity, fmt.Sprintf("shape-%d", i), "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+	}
+	if u.IsDegraded("US-ASHBURN-AD-1") {
+		t.Errorf("expected the zone not degraded below the threshold of the launches")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	u := NewUnavailableOfferings()
+	u.MarkUnavailable(ctx, UnavailableReasonLimitExceeded, "shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+	snapshot := u.Snapshot()
+	snapshot.Offerings = append(snapshot.Offerings, UnavailableOffering{InstanceType: "shape-2", Zone: "US-ASHBURN-AD-1",
+		CapacityType: karpv1.CapacityTypeOnDemand, Reason: UnavailableReasonOutOfCapacity, Expiration: time.Now().Add(-time.Second)})
+	snapshot.DegradedZones = []DegradedZone{{Zone: "US-ASHBURN-AD-2", Expiration: time.Now().Add(time.Minute)}}
+
+	restored := NewUnavailableOfferings()
+	restored.Restore(snapshot)
+	if !restored.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) || !restored.IsDegraded("US-ASHBURN-AD-2") {
+		t.Errorf("expected the unexpired offerings and zones restored")
+	}
+	if restored.IsUnavailable("shape-2", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the expired offering not restored")
+	}
+	if ttl := expiration(t, restored, "shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand); ttl < LimitExceededOfferingsTTL-time.Minute {
+		t.Errorf("expected the restored offering to keep its expiration, got %s", ttl)
+	}
+}
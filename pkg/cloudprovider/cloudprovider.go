@@ -24,6 +24,7 @@ import (
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	cloudproviderevents "github.com/zoom/karpenter-oci/pkg/cloudprovider/events"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/consolehistory"
 	"github.com/zoom/karpenter-oci/pkg/providers/imagefamily"
 	"github.com/zoom/karpenter-oci/pkg/providers/instance"
 	"github.com/zoom/karpenter-oci/pkg/providers/instancetype"
@@ -50,11 +51,12 @@ import (
 var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
 
 type CloudProvider struct {
-	instanceTypeProvider *instancetype.Provider
-	instanceProvider     *instance.Provider
-	kubeClient           client.Client
-	imageProvider        *imagefamily.Provider
-	recorder             events.Recorder
+	instanceTypeProvider   *instancetype.Provider
+	instanceProvider       *instance.Provider
+	kubeClient             client.Client
+	imageProvider          *imagefamily.Provider
+	consoleHistoryProvider *consolehistory.Provider
+	recorder               events.Recorder
 }
 
 func (c *CloudProvider) RepairPolicies() []cloudprovider.RepairPolicy {
@@ -78,13 +80,14 @@ func (c *CloudProvider) GetSupportedNodeClasses() []status.Object {
 }
 
 func New(instanceTypeProvider *instancetype.Provider, instanceProvider *instance.Provider, recorder events.Recorder,
-	kubeClient client.Client, imageProvider *imagefamily.Provider) *CloudProvider {
+	kubeClient client.Client, imageProvider *imagefamily.Provider, consoleHistoryProvider *consolehistory.Provider) *CloudProvider {
 	return &CloudProvider{
-		instanceTypeProvider: instanceTypeProvider,
-		instanceProvider:     instanceProvider,
-		kubeClient:           kubeClient,
-		imageProvider:        imageProvider,
-		recorder:             recorder,
+		instanceTypeProvider:   instanceTypeProvider,
+		instanceProvider:       instanceProvider,
+		kubeClient:             kubeClient,
+		imageProvider:          imageProvider,
+		consoleHistoryProvider: consoleHistoryProvider,
+		recorder:               recorder,
 	}
 }
 
@@ -115,9 +118,12 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *corev1.NodeClaim)
 	if err != nil {
 		return nil, err
 	}
-	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
-		return i.Name == *newInstance.Shape
-	})
+	instanceType := findInstanceType(instanceTypes, newInstance)
+	if instanceType != nil && instancetype.IsDynamicFlex(instanceType) {
+		if instanceType, err = c.instanceTypeProvider.ResolveFlexInstanceType(ctx, nodeClass, instanceType, newInstance.ShapeConfig); err != nil {
+			return nil, fmt.Errorf("resolving flex instance type, %w", err)
+		}
+	}
 	nc := c.instanceToNodeClaim(ctx, newInstance, instanceType)
 	nc.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
 		v1alpha1.AnnotationOciNodeClassHash:        nodeClass.Hash(),
@@ -184,9 +190,52 @@ func (c *CloudProvider) GetInstanceTypes(ctx context.Context, nodePool *corev1.N
 
 func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *corev1.NodeClaim) error {
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("id", nodeClaim.Status.ProviderID))
+	c.captureConsoleHistory(ctx, nodeClaim)
 	return c.instanceProvider.Delete(ctx, nodeClaim.Status.ProviderID)
 }
 
+// captureConsoleHistory captures the console history of the instance before it's deleted when the console history is
+// enabled in the nodeclass and the node didn't register or isn't ready, the history is requested before the deletion
+// and kept in the background, the deletion isn't blocked by the capture or a failed capture
+func (c *CloudProvider) captureConsoleHistory(ctx context.Context, nodeClaim *corev1.NodeClaim) {
+	if nodeClaim.Status.ProviderID == "" || nodeClaim.Spec.NodeClassRef == nil || !nodeClaim.StatusConditions().Get(corev1.ConditionTypeLaunched).IsTrue() {
+		return
+	}
+	nodeClass, err := c.resolveNodeClassFromNodeClaim(ctx, nodeClaim)
+	if err != nil || nodeClass.Spec.ConsoleHistory == nil || !nodeClass.Spec.ConsoleHistory.Enabled {
+		return
+	}
+	if ready, err := c.nodeReady(ctx, nodeClaim); err != nil || ready {
+		return
+	}
+	// the history is kept after the nodeclaim is deleted
+	nodeClaim = nodeClaim.DeepCopy()
+	if err := c.consoleHistoryProvider.Capture(ctx, nodeClass, nodeClaim, func(configMap *v1.ConfigMap) {
+		log.FromContext(ctx).WithValues("configmap", configMap.Name).Info("captured the console history of the unhealthy instance")
+		c.recorder.Publish(cloudproviderevents.NodeClaimConsoleHistoryCaptured(nodeClaim, configMap))
+	}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to capture the console history")
+	}
+}
+
+// nodeReady returns true if the node of the nodeclaim registered and is ready
+func (c *CloudProvider) nodeReady(ctx context.Context, nodeClaim *corev1.NodeClaim) (bool, error) {
+	if !nodeClaim.StatusConditions().Get(corev1.ConditionTypeRegistered).IsTrue() || nodeClaim.Status.NodeName == "" {
+		return false, nil
+	}
+	node := &v1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	condition, ok := lo.Find(node.Status.Conditions, func(condition v1.NodeCondition) bool {
+		return condition.Type == v1.NodeReady
+	})
+	return ok && condition.Status == v1.ConditionTrue, nil
+}
+
 func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *corev1.NodeClaim) (cloudprovider.DriftReason, error) {
 
 	// get node pool using pool name parsed from node claim label
@@ -273,10 +322,41 @@ func (c *CloudProvider) resolveInstanceTypeFromInstance(ctx context.Context, ins
 		// If we can't resolve the NodePool, we fall back to not getting instance type info
 		return nil, client.IgnoreNotFound(fmt.Errorf("resolving nodeclass, %w", err))
 	}
-	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
-		return i.Name == *instance.Shape
+	instanceType := findInstanceType(instanceTypes, instance)
+	if instanceType == nil || !instancetype.IsDynamicFlex(instanceType) {
+		return instanceType, nil
+	}
+	nodeClass, err := c.resolveNodeClassFromNodePool(ctx, nodePool)
+	if err != nil {
+		return nil, client.IgnoreNotFound(fmt.Errorf("resolving nodeclass, %w", err))
+	}
+	return c.instanceTypeProvider.ResolveFlexInstanceType(ctx, nodeClass, instanceType, instance.ShapeConfig)
+}
+
+// findInstanceType resolves the instance type of the instance, the flexible instance types share the same shape name,
+// so the shape config of the instance is used to pick the matching one, fall back to the first one with the shape name
+func findInstanceType(instanceTypes []*cloudprovider.InstanceType, instance *core.Instance) *cloudprovider.InstanceType {
+	candidates := lo.Filter(instanceTypes, func(i *cloudprovider.InstanceType, _ int) bool {
+		return i.Name == lo.FromPtr(instance.Shape)
 	})
-	return instanceType, nil
+	if len(candidates) == 0 {
+		return nil
+	}
+	if instance.ShapeConfig == nil || len(candidates) == 1 {
+		return candidates[0]
+	}
+	ocpus := fmt.Sprint(int64(lo.FromPtr(instance.ShapeConfig.Ocpus)))
+	memory := fmt.Sprint(int64(lo.FromPtr(instance.ShapeConfig.MemoryInGBs) * 1024))
+	baseline := lo.Ternary(instance.ShapeConfig.BaselineOcpuUtilization == "",
+		string(core.ShapeBaselineOcpuUtilizations1), string(instance.ShapeConfig.BaselineOcpuUtilization))
+	if instanceType, ok := lo.Find(candidates, func(i *cloudprovider.InstanceType) bool {
+		return i.Requirements.Get(v1alpha1.LabelInstanceOcpus).Has(ocpus) &&
+			i.Requirements.Get(v1alpha1.LabelInstanceMemory).Has(memory) &&
+			i.Requirements.Get(v1alpha1.LabelInstanceBaselineOcpuUtilization).Has(baseline)
+	}); ok {
+		return instanceType
+	}
+	return candidates[0]
 }
 
 func (c *CloudProvider) resolveNodePoolFromInstance(ctx context.Context, instance *core.Instance) (*corev1.NodePool, error) {
@@ -314,13 +394,21 @@ func (c *CloudProvider) instanceToNodeClaim(ctx context.Context, i *core.Instanc
 	} else {
 		labels[v1.LabelTopologyZone] = splitAd[0]
 	}
-	// can't use this deprecated label, conflict with zone, refer: NewRequirementWithFlexibility
-	// labels[v1.LabelFailureDomainBetaZone] = *i.FaultDomain
+	// can't use the deprecated failure-domain label, conflict with zone, refer: NewRequirementWithFlexibility
+	if i.FaultDomain != nil {
+		labels[v1alpha1.LabelFaultDomain] = *i.FaultDomain
+	}
 
 	labels[corev1.CapacityTypeLabelKey] = corev1.CapacityTypeOnDemand
+	// the reservation id requirement of the instance type is only meaningful for instances launched into the reservation
+	delete(labels, cloudprovider.ReservationIDLabel)
 	if i.PreemptibleInstanceConfig != nil {
 		labels[corev1.CapacityTypeLabelKey] = v1alpha1.CapacityTypePreemptible
 	}
+	if i.CapacityReservationId != nil {
+		labels[corev1.CapacityTypeLabelKey] = corev1.CapacityTypeReserved
+		labels[cloudprovider.ReservationIDLabel] = *i.CapacityReservationId
+	}
 	if v, ok := i.DefinedTags[options.FromContext(ctx).TagNamespace][utils.SafeTagKey(corev1.NodePoolLabelKey)]; ok {
 		labels[corev1.NodePoolLabelKey] = v.(string)
 	}
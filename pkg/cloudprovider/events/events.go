@@ -15,6 +15,8 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+
 	v1 "k8s.io/api/core/v1"
 	corev1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -37,3 +39,13 @@ func NodeClaimFailedToResolveNodeClass(nodeClaim *corev1.NodeClaim) events.Event
 		DedupeValues:   []string{string(nodeClaim.UID)},
 	}
 }
+
+func NodeClaimConsoleHistoryCaptured(nodeClaim *corev1.NodeClaim, configMap *v1.ConfigMap) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "ConsoleHistoryCaptured",
+		Message:        fmt.Sprintf("Captured the console history of the instance in configmap %s/%s", configMap.Namespace, configMap.Name),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/fake"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/consolehistory"
 	"github.com/zoom/karpenter-oci/pkg/test"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -40,6 +41,7 @@ import (
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 	coretestv1alpha1 "sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+	"strings"
 	"testing"
 	"time"
 
@@ -74,7 +76,7 @@ var _ = BeforeSuite(func() {
 	fakeClock = clock.NewFakeClock(time.Now())
 	recorder = events.NewRecorder(&record.FakeRecorder{})
 	cloudProvider = New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, recorder,
-		env.Client, ociEnv.AMIProvider)
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, recorder, cloudProvider, cluster, fakeClock)
 })
@@ -184,6 +186,20 @@ var _ = Describe("CloudProvider", func() {
 		Expect(cloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
 		Expect(cloudProviderNodeClaim).To(BeNil())
 	})
+	It("should set the fault domain label from the instance", func() {
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: v1.NodeSelectorRequirement{
+				Key:      v1alpha1.LabelFaultDomain,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{"FAULT-DOMAIN-3"},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).To(BeNil())
+		Expect(cloudProviderNodeClaim).ToNot(BeNil())
+		Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue(v1alpha1.LabelFaultDomain, "FAULT-DOMAIN-3"))
+	})
 	It("should set ImageID in the status field of the nodeClaim", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
 		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
@@ -396,6 +412,96 @@ var _ = Describe("CloudProvider", func() {
 
 		})
 	})
+	Context("Console History", func() {
+		var nodeName string
+		BeforeEach(func() {
+			nodeClass.Spec.ConsoleHistory = &v1alpha1.ConsoleHistory{Enabled: true}
+			nodeName = ""
+		})
+		launch := func() {
+			GinkgoHelper()
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(BeNil())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			nodeClaim.Status.NodeName = nodeName
+			nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeLaunched)
+			if nodeName != "" {
+				nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+			}
+			ExpectApplied(ctx, env.Client, nodeClaim)
+		}
+		expectCaptured := func() *v1.ConfigMap {
+			GinkgoHelper()
+			ociEnv.ConsoleHistoryProvider.Wait()
+			return ExpectExists(ctx, env.Client, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: consolehistory.ConfigMapPrefix + nodeClaim.Name, Namespace: "default"}})
+		}
+		expectNotCaptured := func() {
+			GinkgoHelper()
+			ociEnv.ConsoleHistoryProvider.Wait()
+			ExpectNotFound(ctx, env.Client, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: consolehistory.ConfigMapPrefix + nodeClaim.Name, Namespace: "default"}})
+		}
+
+		It("should capture the console history of an unregistered instance before deleting it", func() {
+			launch()
+			ociEnv.CmpCli.ConsoleOutputs.Store(nodeClaim.Status.ProviderID, "cloud-init: bootstrap failed")
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			configMap := expectCaptured()
+			Expect(configMap.Data).To(HaveKeyWithValue(consolehistory.ConsoleHistoryKey, "cloud-init: bootstrap failed"))
+			Expect(configMap.Data).To(HaveKeyWithValue(consolehistory.InstanceIdKey, nodeClaim.Status.ProviderID))
+			Expect(configMap.Data).To(HaveKeyWithValue(consolehistory.TruncatedKey, "false"))
+			Expect(configMap.Labels).To(HaveKeyWithValue(v1alpha1.LabelNodeClaim, nodeClaim.Name))
+			_, ok := ociEnv.CmpCli.Instances.Load(nodeClaim.Status.ProviderID)
+			Expect(ok).To(BeFalse())
+			// the captured console history is deleted after it's read
+			histories := 0
+			ociEnv.CmpCli.ConsoleHistories.Range(func(_, _ any) bool {
+				histories++
+				return true
+			})
+			Expect(histories).To(BeZero())
+		})
+		It("should keep the end of a console history longer than the max size", func() {
+			nodeClass.Spec.ConsoleHistory.MaxSizeInKiB = lo.ToPtr[int64](1)
+			launch()
+			ociEnv.CmpCli.ConsoleOutputs.Store(nodeClaim.Status.ProviderID, strings.Repeat("a", 3000)+"kubelet failed to start")
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			configMap := expectCaptured()
+			Expect(configMap.Data[consolehistory.ConsoleHistoryKey]).To(HaveLen(1024))
+			Expect(configMap.Data[consolehistory.ConsoleHistoryKey]).To(HaveSuffix("kubelet failed to start"))
+			Expect(configMap.Data).To(HaveKeyWithValue(consolehistory.TruncatedKey, "true"))
+		})
+		It("should capture the console history of an instance whose node isn't ready", func() {
+			node := coretest.Node(coretest.NodeOptions{ReadyStatus: v1.ConditionFalse})
+			nodeName = node.Name
+			ExpectApplied(ctx, env.Client, node)
+			launch()
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			expectCaptured()
+		})
+		It("should not capture the console history of an instance whose node is ready", func() {
+			node := coretest.Node(coretest.NodeOptions{ReadyStatus: v1.ConditionTrue})
+			nodeName = node.Name
+			ExpectApplied(ctx, env.Client, node)
+			launch()
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			expectNotCaptured()
+		})
+		It("should not capture the console history when it isn't enabled", func() {
+			nodeClass.Spec.ConsoleHistory = nil
+			launch()
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			expectNotCaptured()
+		})
+		It("should delete the instance when the console history can't be captured", func() {
+			launch()
+			ociEnv.CmpCli.CaptureConsoleHistoryBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: 500, Message: "internal error"})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			expectNotCaptured()
+			_, ok := ociEnv.CmpCli.Instances.Load(nodeClaim.Status.ProviderID)
+			Expect(ok).To(BeFalse())
+		})
+	})
 })
 
 func CreateOciTestResource(nodePool *karpv1.NodePool, nodeClass *v1alpha1.OciNodeClass, nodeClaim *karpv1.NodeClaim) {
@@ -17,17 +17,28 @@ package controllers
 import (
 	"context"
 	"github.com/awslabs/operatorpkg/controller"
+	"github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclaim/autotune"
 	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclaim/garbagecollection"
+	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclaim/launchfailure"
 	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclaim/tagging"
 	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclass/hash"
 	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclass/status"
 	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclass/termination"
+	controllerCapacityProbe "github.com/zoom/karpenter-oci/pkg/controllers/providers/capacityprobe"
 	controllerPricing "github.com/zoom/karpenter-oci/pkg/controllers/providers/pricing"
+	controllerUnavailableOfferings "github.com/zoom/karpenter-oci/pkg/controllers/providers/unavailableofferings"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityprobe"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityreservation"
 	"github.com/zoom/karpenter-oci/pkg/providers/imagefamily"
 	"github.com/zoom/karpenter-oci/pkg/providers/instance"
+	"github.com/zoom/karpenter-oci/pkg/providers/instancetype"
+	"github.com/zoom/karpenter-oci/pkg/providers/kms"
 	"github.com/zoom/karpenter-oci/pkg/providers/pricing"
 	"github.com/zoom/karpenter-oci/pkg/providers/securitygroup"
 	"github.com/zoom/karpenter-oci/pkg/providers/subnet"
+	"knative.dev/pkg/system"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -35,14 +46,23 @@ import (
 
 func NewControllers(ctx context.Context, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider,
 	instanceProvider *instance.Provider, recorder events.Recorder, imageProvider *imagefamily.Provider,
-	subnetProvider *subnet.Provider, securityProvider *securitygroup.Provider, pricingProvider pricing.Provider) []controller.Controller {
+	subnetProvider *subnet.Provider, securityProvider *securitygroup.Provider, pricingProvider pricing.Provider,
+	capacityReservationProvider *capacityreservation.Provider, kmsProvider *kms.Provider, instanceTypeProvider *instancetype.Provider,
+	capacityProbeProvider *capacityprobe.Provider, apiReader client.Reader, unavailableOfferings *cache.UnavailableOfferings) []controller.Controller {
 	controllers := []controller.Controller{
 		hash.NewController(kubeClient),
-		status.NewController(kubeClient, subnetProvider, securityProvider, imageProvider),
+		status.NewController(kubeClient, subnetProvider, securityProvider, imageProvider, capacityReservationProvider, kmsProvider),
 		termination.NewController(kubeClient, recorder),
 		garbagecollection.NewController(kubeClient, cloudProvider),
 		controllerPricing.NewController(pricingProvider),
 		tagging.NewController(kubeClient, cloudProvider, instanceProvider),
+		autotune.NewController(kubeClient, cloudProvider, instanceProvider),
+		launchfailure.NewController(kubeClient, cloudProvider, instanceProvider, unavailableOfferings, recorder),
+		controllerUnavailableOfferings.NewController(kubeClient, apiReader, unavailableOfferings, system.Namespace()),
+	}
+	// the capacity is probed only for the shapes in capacity-probe-shapes
+	if options.FromContext(ctx).CapacityProbeShapes != "" {
+		controllers = append(controllers, controllerCapacityProbe.NewController(kubeClient, instanceTypeProvider, capacityProbeProvider))
 	}
 	return controllers
 }
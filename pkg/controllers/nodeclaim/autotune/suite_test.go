@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autotune_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/zoom/karpenter-oci/pkg/apis"
+	oci_v1alpha1 "github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/cloudprovider"
+	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclaim/autotune"
+	"github.com/zoom/karpenter-oci/pkg/fake"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/test"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var ociEnv *test.Environment
+var env *coretest.Environment
+var autotuneController *autotune.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AutotuneController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{ReservedCapacity: lo.ToPtr(true)}}))
+	ctx = options.ToContext(ctx, test.Options())
+	ociEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
+	autotuneController = autotune.NewController(env.Client, cloudProvider, ociEnv.InstanceProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ociEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("AutotuneController", func() {
+	var ociInstance *core.Instance
+	var nodeClass *oci_v1alpha1.OciNodeClass
+	var nodeClaim *karpv1.NodeClaim
+
+	BeforeEach(func() {
+		ociInstance = &core.Instance{
+			LifecycleState:     core.InstanceLifecycleStateRunning,
+			Id:                 lo.ToPtr(fake.InstanceID()),
+			AvailabilityDomain: lo.ToPtr("JPqd:US-ASHBURN-AD-1"),
+		}
+		ociEnv.CmpCli.Instances.Store(lo.FromPtr(ociInstance.Id), ociInstance)
+		ociEnv.CmpCli.BootVolumeAttachments.Store(lo.FromPtr(ociInstance.Id), []core.BootVolumeAttachment{
+			{InstanceId: ociInstance.Id, BootVolumeId: lo.ToPtr("ocid1.bootvolume.oc1.iad.aaaaaaaa")},
+		})
+		ociEnv.CmpCli.VolumeAttachments.Store(lo.FromPtr(ociInstance.Id), []core.VolumeAttachment{
+			core.IScsiVolumeAttachment{InstanceId: ociInstance.Id, DisplayName: lo.ToPtr("block-device-0"), VolumeId: lo.ToPtr("ocid1.volume.oc1.iad.aaaaaaaa")},
+		})
+		nodeClass = test.OciNodeClass()
+		nodeClass.Spec.BootConfig.AutotunePolicies = []*oci_v1alpha1.AutotunePolicy{
+			{AutotuneType: string(core.AutotunePolicyAutotuneTypeDetachedVolume)},
+		}
+		nodeClass.Spec.BlockDevices = []*oci_v1alpha1.VolumeAttributes{{SizeInGBs: 100, VpusPerGB: 30, AutotunePolicies: []*oci_v1alpha1.AutotunePolicy{
+			{AutotuneType: string(core.AutotunePolicyAutotuneTypePerformanceBased), MaxVpusPerGB: lo.ToPtr(int64(120))},
+		}}}
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: lo.FromPtr(ociInstance.Id),
+				NodeName:   "default",
+			},
+		})
+	})
+
+	It("shouldn't auto-tune volumes without a Node", func() {
+		nodeClaim.Status.NodeName = ""
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, autotuneController, nodeClaim)
+		Expect(nodeClaim.Annotations).To(Not(HaveKey(oci_v1alpha1.AnnotationVolumeAutotuned)))
+		Expect(ociEnv.BlockStorageCli.UpdateBootVolumeBehavior.Calls()).To(Equal(0))
+		Expect(ociEnv.BlockStorageCli.UpdateVolumeBehavior.Calls()).To(Equal(0))
+	})
+
+	It("should skip the volumes when the nodeclass has no auto-tune policies", func() {
+		nodeClass.Spec.BootConfig.AutotunePolicies = nil
+		nodeClass.Spec.BlockDevices = nil
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, autotuneController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(oci_v1alpha1.AnnotationVolumeAutotuned, "true"))
+		Expect(ociEnv.BlockStorageCli.UpdateBootVolumeBehavior.Calls()).To(Equal(0))
+		Expect(ociEnv.BlockStorageCli.UpdateVolumeBehavior.Calls()).To(Equal(0))
+	})
+
+	It("should gracefully handle missing instance", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ociEnv.CmpCli.Instances.Delete(lo.FromPtr(ociInstance.Id))
+		ExpectObjectReconciled(ctx, env.Client, autotuneController, nodeClaim)
+		Expect(nodeClaim.Annotations).To(Not(HaveKey(oci_v1alpha1.AnnotationVolumeAutotuned)))
+	})
+
+	It("should update the auto-tune policies of the boot volume and block volumes", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, autotuneController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(oci_v1alpha1.AnnotationVolumeAutotuned, "true"))
+
+		Expect(ociEnv.BlockStorageCli.UpdateBootVolumeBehavior.CalledWithInput.Len()).To(Equal(1))
+		bootVolumeInput := ociEnv.BlockStorageCli.UpdateBootVolumeBehavior.CalledWithInput.Pop()
+		Expect(lo.FromPtr(bootVolumeInput.BootVolumeId)).To(Equal("ocid1.bootvolume.oc1.iad.aaaaaaaa"))
+		Expect(bootVolumeInput.AutotunePolicies).To(ConsistOf(core.DetachedVolumeAutotunePolicy{}))
+
+		Expect(ociEnv.BlockStorageCli.UpdateVolumeBehavior.CalledWithInput.Len()).To(Equal(1))
+		volumeInput := ociEnv.BlockStorageCli.UpdateVolumeBehavior.CalledWithInput.Pop()
+		Expect(lo.FromPtr(volumeInput.VolumeId)).To(Equal("ocid1.volume.oc1.iad.aaaaaaaa"))
+		Expect(volumeInput.AutotunePolicies).To(ConsistOf(core.PerformanceBasedAutotunePolicy{MaxVpusPerGB: lo.ToPtr(int64(120))}))
+	})
+
+	It("should retry when the block volume isn't attached yet", func() {
+		ociEnv.CmpCli.VolumeAttachments.Delete(lo.FromPtr(ociInstance.Id))
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, autotuneController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(Not(HaveKey(oci_v1alpha1.AnnotationVolumeAutotuned)))
+		Expect(ociEnv.BlockStorageCli.UpdateVolumeBehavior.Calls()).To(Equal(0))
+	})
+})
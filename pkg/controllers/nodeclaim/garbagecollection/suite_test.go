@@ -62,7 +62,7 @@ var _ = BeforeSuite(func() {
 	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(coretestv1alpha1.CRDs...))
 	ociEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, ociEnv.AMIProvider)
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
 	garbageCollectionController = garbagecollection.NewController(env.Client, cloudProvider)
 })
 
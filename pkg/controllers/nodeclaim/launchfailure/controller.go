@@ -0,0 +1,125 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchfailure
+
+import (
+	"context"
+	"time"
+
+	"github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/providers/instance"
+	corev1 "k8s.io/api/core/v1"
+
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// pollPeriod is the period the launching instances are checked until their nodes register
+const pollPeriod = 15 * time.Second
+
+// Controller fails the nodeclaims whose launch failed after LaunchInstance returned, instead of waiting for the
+// registration timeout. The instance is terminated or its work request failed while provisioning, the nodeclaim is
+// deleted, so its pods are scheduled again. The offering is marked unavailable when it's out of capacity, limits or quotas.
+type Controller struct {
+	kubeClient           client.Client
+	cloudProvider        cloudprovider.CloudProvider
+	instanceProvider     *instance.Provider
+	unavailableOfferings *cache.UnavailableOfferings
+	recorder             events.Recorder
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, instanceProvider *instance.Provider,
+	unavailableOfferings *cache.UnavailableOfferings, recorder events.Recorder) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		cloudProvider:        cloudProvider,
+		instanceProvider:     instanceProvider,
+		unavailableOfferings: unavailableOfferings,
+		recorder:             recorder,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.launchfailure")
+
+	if !isLaunching(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	failure, err := c.instanceProvider.GetLaunchFailure(ctx, nodeClaim.Status.ProviderID)
+	if err != nil {
+		// the nodeclaim of the missing instance is garbage collected
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	}
+	if failure == nil {
+		return reconcile.Result{RequeueAfter: pollPeriod}, nil
+	}
+	instanceType, zone, capacityType := nodeClaim.Labels[corev1.LabelInstanceTypeStable], nodeClaim.Labels[corev1.LabelTopologyZone], nodeClaim.Labels[karpv1.CapacityTypeLabelKey]
+	log.FromContext(ctx).Info("instance launch failed, deleting nodeclaim", "reason", failure.Reason, "message", failure.Message,
+		"instance-type", instanceType, "zone", zone, "capacity-type", capacityType)
+	// only the offering is at fault when it's out of capacity, limits or quotas, the image, the user data or a terminated
+	// instance would fail any offering
+	if failure.Reason.IsInsufficientCapacity() && instanceType != "" && zone != "" && capacityType != "" {
+		c.unavailableOfferings.MarkUnavailableForLaunchFailure(ctx, failure.Reason, failure.Message, capacityType, instanceType, zone)
+	}
+	c.recorder.Publish(NodeClaimLaunchFailedEvent(nodeClaim, failure))
+	if err := c.kubeClient.Delete(ctx, nodeClaim); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.launchfailure").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isLaunching(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isLaunching(nc *karpv1.NodeClaim) bool {
+	// Instance isn't launched yet
+	if nc.Status.ProviderID == "" || !nc.StatusConditions().Get(karpv1.ConditionTypeLaunched).IsTrue() {
+		return false
+	}
+	// Node has registered, the failures of the instance are handled as the failures of the node
+	if nc.StatusConditions().Get(karpv1.ConditionTypeRegistered).IsTrue() {
+		return false
+	}
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchfailure
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/providers/instance"
+	v1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func NodeClaimLaunchFailedEvent(nodeClaim *karpv1.NodeClaim, failure *instance.LaunchFailure) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "LaunchFailed",
+		Message:        fmt.Sprintf("Instance launch failed after it was accepted, %s: %s", lo.Ternary(failure.Reason == "", "Unknown", string(failure.Reason)), failure.Message),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
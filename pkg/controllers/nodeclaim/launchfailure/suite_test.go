@@ -0,0 +1,209 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchfailure_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/oracle/oci-go-sdk/v65/workrequests"
+	"github.com/zoom/karpenter-oci/pkg/apis"
+	oci_v1alpha1 "github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/cloudprovider"
+	"github.com/zoom/karpenter-oci/pkg/controllers/nodeclaim/launchfailure"
+	"github.com/zoom/karpenter-oci/pkg/fake"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/test"
+
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var ociEnv *test.Environment
+var env *coretest.Environment
+var recorder *coretest.EventRecorder
+var launchFailureController *launchfailure.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LaunchFailureController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ociEnv = test.NewEnvironment(ctx, env)
+	recorder = coretest.NewEventRecorder()
+	cloudProvider := cloudprovider.New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, recorder,
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
+	launchFailureController = launchfailure.NewController(env.Client, cloudProvider, ociEnv.InstanceProvider, ociEnv.UnavailableOfferingsCache, recorder)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ociEnv.Reset()
+	recorder.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("LaunchFailureController", func() {
+	var ociInstance *core.Instance
+	var nodeClass *oci_v1alpha1.OciNodeClass
+	var nodeClaim *karpv1.NodeClaim
+
+	BeforeEach(func() {
+		ociInstance = &core.Instance{
+			LifecycleState:     core.InstanceLifecycleStateProvisioning,
+			Id:                 lo.ToPtr(fake.InstanceID()),
+			AvailabilityDomain: lo.ToPtr("JPqd:US-ASHBURN-AD-1"),
+		}
+		ociEnv.CmpCli.Instances.Store(lo.FromPtr(ociInstance.Id), ociInstance)
+		nodeClass = test.OciNodeClass()
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				corev1.LabelInstanceTypeStable: "shape-1",
+				corev1.LabelTopologyZone:       "US-ASHBURN-AD-1",
+				karpv1.CapacityTypeLabelKey:    karpv1.CapacityTypeOnDemand,
+			}},
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: lo.FromPtr(ociInstance.Id),
+			},
+		})
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeLaunched)
+	})
+
+	failWorkRequest := func(code, message string) {
+		ociEnv.WorkRequestCli.WorkRequests.Store(lo.FromPtr(ociInstance.Id), []workrequests.WorkRequestSummary{
+			{Id: lo.ToPtr("ocid1.workrequest.oc1.iad.aaaaaaaa"), OperationType: lo.ToPtr("LaunchInstance"), Status: workrequests.WorkRequestSummaryStatusFailed},
+		})
+		ociEnv.WorkRequestCli.WorkRequestErrors.Store("ocid1.workrequest.oc1.iad.aaaaaaaa", []workrequests.WorkRequestError{
+			{Code: lo.ToPtr(code), Message: lo.ToPtr(message)},
+		})
+	}
+
+	It("should ignore the nodeclaim which isn't launched", func() {
+		nodeClaim.StatusConditions().SetUnknown(karpv1.ConditionTypeLaunched)
+		ociInstance.LifecycleState = core.InstanceLifecycleStateTerminated
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.CmpCli.GetInstanceBehavior.Calls()).To(Equal(0))
+	})
+	It("should ignore the nodeclaim whose node registered", func() {
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		ociInstance.LifecycleState = core.InstanceLifecycleStateTerminated
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.CmpCli.GetInstanceBehavior.Calls()).To(Equal(0))
+	})
+	It("should requeue while the instance is provisioning", func() {
+		ociEnv.WorkRequestCli.WorkRequests.Store(lo.FromPtr(ociInstance.Id), []workrequests.WorkRequestSummary{
+			{Id: lo.ToPtr("ocid1.workrequest.oc1.iad.aaaaaaaa"), OperationType: lo.ToPtr("LaunchInstance"), Status: workrequests.WorkRequestSummaryStatusInProgress},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+	})
+	It("should fail the nodeclaim when the launch work request is out of capacity", func() {
+		failWorkRequest("InternalError", "Out of host capacity.")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeTrue())
+		Expect(ociEnv.UnavailableOfferingsCache.Snapshot().Offerings[0].Reason).To(Equal(ocicache.UnavailableReasonOutOfCapacity))
+		Expect(recorder.Calls("LaunchFailed")).To(Equal(1))
+		Expect(recorder.DetectedEvent("Instance launch failed after it was accepted, OutOfHostCapacity: Out of host capacity.")).To(BeTrue())
+	})
+	It("should mark the offering unavailable for the TTL of the quota", func() {
+		failWorkRequest("QuotaExceeded", "Quota exceeded for standard-e4-core-count")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.UnavailableOfferingsCache.Snapshot().Offerings[0].Reason).To(Equal(ocicache.UnavailableReasonQuotaExceeded))
+	})
+	It("should fail the nodeclaim without marking the offering unavailable when the instance is terminated while provisioning", func() {
+		ociInstance.LifecycleState = core.InstanceLifecycleStateTerminated
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+		Expect(recorder.DetectedEvent("Instance launch failed after it was accepted, Unknown: instance is terminated")).To(BeTrue())
+	})
+	It("shouldn't mark the offering unavailable when the launch work request failed internally", func() {
+		failWorkRequest("InternalError", "Failed to run the instance")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+		Expect(ociEnv.UnavailableOfferingsCache.ZoneUnavailableCount("US-ASHBURN-AD-1")).To(Equal(0))
+		Expect(recorder.Calls("LaunchFailed")).To(Equal(1))
+	})
+	It("shouldn't mark the offering unavailable when the image is rejected", func() {
+		failWorkRequest("InvalidParameter", "Image is not compatible with the shape")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+		Expect(recorder.Calls("LaunchFailed")).To(Equal(1))
+	})
+	It("should ignore the failed work requests of other operations", func() {
+		ociEnv.WorkRequestCli.WorkRequests.Store(lo.FromPtr(ociInstance.Id), []workrequests.WorkRequestSummary{
+			{Id: lo.ToPtr("ocid1.workrequest.oc1.iad.aaaaaaaa"), OperationType: lo.ToPtr("UpdateInstance"), Status: workrequests.WorkRequestSummaryStatusFailed},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectExists(ctx, env.Client, nodeClaim)
+	})
+	It("should gracefully handle missing instance", func() {
+		ociEnv.CmpCli.Instances.Delete(lo.FromPtr(ociInstance.Id))
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, launchFailureController, nodeClaim)
+		ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(recorder.Calls("LaunchFailed")).To(Equal(0))
+	})
+})
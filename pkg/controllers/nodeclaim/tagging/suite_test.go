@@ -61,7 +61,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	ociEnv = test.NewEnvironment(ctx, env)
 	cloudProvider := cloudprovider.New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, ociEnv.AMIProvider)
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
 	taggingController = tagging.NewController(env.Client, cloudProvider, ociEnv.InstanceProvider)
 })
 var _ = AfterSuite(func() {
@@ -18,6 +18,7 @@ import (
 	"context"
 	"github.com/awslabs/operatorpkg/object"
 	"github.com/imdario/mergo"
+	"github.com/samber/lo"
 	"github.com/zoom/karpenter-oci/pkg/apis"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
@@ -117,6 +118,7 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		Entry("UserData Drift", &v1alpha1.OciNodeClass{Spec: v1alpha1.OciNodeClassSpec{UserData: utils.String("userdata-test-2")}}),
 		Entry("Tags Drift", &v1alpha1.OciNodeClass{Spec: v1alpha1.OciNodeClassSpec{DefinedTags: map[string]v1alpha1.DefinedTagValue{test.Options().TagNamespace: {"keyTag-test-3": "valueTag-test-3"}}}}),
 		Entry("BlockDeviceMappings Drift", &v1alpha1.OciNodeClass{Spec: v1alpha1.OciNodeClassSpec{BlockDevices: []*v1alpha1.VolumeAttributes{{SizeInGBs: 200, VpusPerGB: 20}}}}),
+		Entry("PlatformConfig Drift", &v1alpha1.OciNodeClass{Spec: v1alpha1.OciNodeClassSpec{PlatformConfig: &v1alpha1.PlatformConfig{IsSecureBootEnabled: lo.ToPtr(true)}}}),
 	)
 	It("should not update ocinodeclass-hash on all NodeClaims when the ocinodeclass-hash-version matches the controller hash version", func() {
 		nodeClass.Annotations = map[string]string{
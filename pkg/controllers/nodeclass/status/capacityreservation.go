@@ -0,0 +1,80 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityreservation"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type CapacityReservation struct {
+	capacityReservationProvider *capacityreservation.Provider
+}
+
+func (c *CapacityReservation) Reconcile(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) (reconcile.Result, error) {
+	if len(nodeClass.Spec.CapacityReservationSelector) == 0 {
+		nodeClass.Status.CapacityReservations = nil
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeCapacityReservationsReady)
+		return reconcile.Result{}, nil
+	}
+	reservations, err := c.capacityReservationProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting capacity reservations, %w", err)
+	}
+	// reservations are an optional source of capacity, an empty result doesn't block launching on-demand instances
+	statuses := make(map[string]*v1alpha1.CapacityReservation)
+	for _, reservation := range reservations {
+		for _, config := range reservation.InstanceReservationConfigs {
+			var ocpus, memoryInGBs int64
+			if config.InstanceShapeConfig != nil {
+				ocpus = int64(lo.FromPtr(config.InstanceShapeConfig.Ocpus))
+				memoryInGBs = int64(lo.FromPtr(config.InstanceShapeConfig.MemoryInGBs))
+			}
+			// configs of different fault domains are merged into a single entry
+			key := fmt.Sprintf("%s:%s:%d:%d", lo.FromPtr(reservation.Id), lo.FromPtr(config.InstanceShape), ocpus, memoryInGBs)
+			if existing, ok := statuses[key]; ok {
+				existing.ReservedCount += lo.FromPtr(config.ReservedCount)
+				existing.UsedCount += lo.FromPtr(config.UsedCount)
+				continue
+			}
+			statuses[key] = &v1alpha1.CapacityReservation{
+				Id:                 lo.FromPtr(reservation.Id),
+				Name:               lo.FromPtr(reservation.DisplayName),
+				AvailabilityDomain: lo.LastOrEmpty(strings.Split(lo.FromPtr(reservation.AvailabilityDomain), ":")),
+				InstanceShape:      lo.FromPtr(config.InstanceShape),
+				Ocpus:              ocpus,
+				MemoryInGBs:        memoryInGBs,
+				ReservedCount:      lo.FromPtr(config.ReservedCount),
+				UsedCount:          lo.FromPtr(config.UsedCount),
+			}
+		}
+	}
+	keys := lo.Keys(statuses)
+	sort.Strings(keys)
+	nodeClass.Status.CapacityReservations = lo.Map(keys, func(key string, _ int) *v1alpha1.CapacityReservation {
+		return statuses[key]
+	})
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeCapacityReservationsReady)
+	// usage of the reservations changes with every launch, refresh it more often than the other resources
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
@@ -0,0 +1,109 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"github.com/oracle/oci-go-sdk/v65/common"
+	oci_core "github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	test "github.com/zoom/karpenter-oci/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Capacity Reservation Status Controller", func() {
+	BeforeEach(func() {
+		nodeClass = test.OciNodeClass()
+		ociEnv.CmpCli.CapacityReservations.Add(&oci_core.ComputeCapacityReservation{
+			Id:                 common.String("ocid1.capacityreservation.oc1.iad.aaaaaaaa"),
+			DisplayName:        common.String("reservation-1"),
+			AvailabilityDomain: common.String("JPqd:US-ASHBURN-AD-1"),
+			LifecycleState:     oci_core.ComputeCapacityReservationLifecycleStateActive,
+			InstanceReservationConfigs: []oci_core.InstanceReservationConfig{
+				{
+					InstanceShape: common.String("VM.Standard.E4.Flex"),
+					FaultDomain:   common.String("FAULT-DOMAIN-1"),
+					InstanceShapeConfig: &oci_core.InstanceReservationShapeConfigDetails{
+						Ocpus: common.Float32(2), MemoryInGBs: common.Float32(16),
+					},
+					ReservedCount: common.Int64(2),
+					UsedCount:     common.Int64(1),
+				},
+				{
+					InstanceShape: common.String("VM.Standard.E4.Flex"),
+					FaultDomain:   common.String("FAULT-DOMAIN-2"),
+					InstanceShapeConfig: &oci_core.InstanceReservationShapeConfigDetails{
+						Ocpus: common.Float32(2), MemoryInGBs: common.Float32(16),
+					},
+					ReservedCount: common.Int64(3),
+					UsedCount:     common.Int64(0),
+				},
+			},
+		})
+		ociEnv.CmpCli.CapacityReservations.Add(&oci_core.ComputeCapacityReservation{
+			Id:                 common.String("ocid1.capacityreservation.oc1.iad.aaaaaaab"),
+			DisplayName:        common.String("reservation-2"),
+			AvailabilityDomain: common.String("JPqd:US-ASHBURN-AD-2"),
+			LifecycleState:     oci_core.ComputeCapacityReservationLifecycleStateActive,
+			FreeformTags:       map[string]string{"team": "ml"},
+			InstanceReservationConfigs: []oci_core.InstanceReservationConfig{
+				{
+					InstanceShape: common.String("BM.GPU.A100-v2.8"),
+					ReservedCount: common.Int64(1),
+					UsedCount:     common.Int64(1),
+				},
+			},
+		})
+	})
+	It("Should not resolve capacity reservations when the selector is empty", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(BeEmpty())
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeCapacityReservationsReady).IsTrue()).To(BeTrue())
+	})
+	It("Should update OciNodeClass status for Capacity Reservations", func() {
+		nodeClass.Spec.CapacityReservationSelector = []v1alpha1.CapacityReservationSelectorTerm{
+			{Id: "ocid1.capacityreservation.oc1.iad.aaaaaaaa"},
+			{Tags: map[string]string{"team": "*"}},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityReservations).To(Equal([]*v1alpha1.CapacityReservation{
+			{
+				Id:                 "ocid1.capacityreservation.oc1.iad.aaaaaaaa",
+				Name:               "reservation-1",
+				AvailabilityDomain: "US-ASHBURN-AD-1",
+				InstanceShape:      "VM.Standard.E4.Flex",
+				Ocpus:              2,
+				MemoryInGBs:        16,
+				ReservedCount:      5,
+				UsedCount:          1,
+			},
+			{
+				Id:                 "ocid1.capacityreservation.oc1.iad.aaaaaaab",
+				Name:               "reservation-2",
+				AvailabilityDomain: "US-ASHBURN-AD-2",
+				InstanceShape:      "BM.GPU.A100-v2.8",
+				ReservedCount:      1,
+				UsedCount:          1,
+			},
+		}))
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeCapacityReservationsReady).IsTrue()).To(BeTrue())
+	})
+})
@@ -18,7 +18,9 @@ import (
 	"context"
 	"github.com/awslabs/operatorpkg/reasonable"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityreservation"
 	"github.com/zoom/karpenter-oci/pkg/providers/imagefamily"
+	"github.com/zoom/karpenter-oci/pkg/providers/kms"
 	"github.com/zoom/karpenter-oci/pkg/providers/securitygroup"
 	"github.com/zoom/karpenter-oci/pkg/providers/subnet"
 	"go.uber.org/multierr"
@@ -42,19 +44,23 @@ type nodeClassStatusReconciler interface {
 type Controller struct {
 	kubeClient client.Client
 
-	ami           *Image
-	subnet        *Subnet
-	securitygroup *SecurityGroup
+	ami                 *Image
+	subnet              *Subnet
+	securitygroup       *SecurityGroup
+	capacityreservation *CapacityReservation
+	kmskey              *KmsKey
 }
 
 func NewController(kubeClient client.Client, subnetProvider *subnet.Provider, securityGroupProvider *securitygroup.Provider,
-	imageProvider *imagefamily.Provider) *Controller {
+	imageProvider *imagefamily.Provider, capacityReservationProvider *capacityreservation.Provider, kmsProvider *kms.Provider) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
 
-		ami:           &Image{imageProvider: imageProvider},
-		subnet:        &Subnet{subnetProvider: subnetProvider},
-		securitygroup: &SecurityGroup{securityGroupProvider: securityGroupProvider},
+		ami:                 &Image{imageProvider: imageProvider},
+		subnet:              &Subnet{subnetProvider: subnetProvider},
+		securitygroup:       &SecurityGroup{securityGroupProvider: securityGroupProvider},
+		capacityreservation: &CapacityReservation{capacityReservationProvider: capacityReservationProvider},
+		kmskey:              &KmsKey{kmsProvider: kmsProvider},
 	}
 }
 
@@ -83,6 +89,8 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1alpha1.OciNodeC
 		c.ami,
 		c.subnet,
 		c.securitygroup,
+		c.capacityreservation,
+		c.kmskey,
 	} {
 		res, err := reconciler.Reconcile(ctx, nodeClass)
 		errs = multierr.Append(errs, err)
@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/keymanagement"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/providers/kms"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type KmsKey struct {
+	kmsProvider *kms.Provider
+}
+
+func (k *KmsKey) Reconcile(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) (reconcile.Result, error) {
+	keyIds := kmsKeyIds(nodeClass)
+	if len(keyIds) == 0 {
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeKmsKeyReady)
+		return reconcile.Result{}, nil
+	}
+	for _, keyId := range keyIds {
+		// the vault of the key is resolved from the key id, a malformed id is never found
+		if _, err := kms.ManagementEndpoint(keyId); err != nil {
+			nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeKmsKeyReady, "InvalidKmsKeyId", fmt.Sprintf("KmsKey %s is not a valid kms key id", keyId))
+			return reconcile.Result{}, nil
+		}
+		key, err := k.kmsProvider.GetKey(ctx, keyId)
+		if err != nil {
+			if serviceErr, ok := common.IsServiceError(err); ok && serviceErr.GetHTTPStatusCode() == http.StatusNotFound {
+				nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeKmsKeyReady, "KmsKeyNotFound", fmt.Sprintf("KmsKey %s not found", keyId))
+				return reconcile.Result{RequeueAfter: time.Minute}, nil
+			}
+			return reconcile.Result{}, fmt.Errorf("getting kms key %s, %w", keyId, err)
+		}
+		if key.LifecycleState != keymanagement.KeyLifecycleStateEnabled {
+			nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeKmsKeyReady, "KmsKeyNotEnabled", fmt.Sprintf("KmsKey %s is in %s state", keyId, key.LifecycleState))
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeKmsKeyReady)
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+// kmsKeyIds returns the distinct kms keys referenced by the boot volume and block devices
+func kmsKeyIds(nodeClass *v1alpha1.OciNodeClass) []string {
+	var keyIds []string
+	if nodeClass.Spec.BootConfig != nil && lo.FromPtr(nodeClass.Spec.BootConfig.KmsKeyId) != "" {
+		keyIds = append(keyIds, *nodeClass.Spec.BootConfig.KmsKeyId)
+	}
+	for _, blockDevice := range nodeClass.Spec.BlockDevices {
+		if lo.FromPtr(blockDevice.KmsKeyId) != "" {
+			keyIds = append(keyIds, *blockDevice.KmsKeyId)
+		}
+	}
+	return lo.Uniq(keyIds)
+}
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/keymanagement"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	test "github.com/zoom/karpenter-oci/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass KmsKey Status Controller", func() {
+	BeforeEach(func() {
+		nodeClass = test.OciNodeClass()
+		ociEnv.KmsCli.Keys.Add(&keymanagement.Key{
+			Id:             common.String("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaaa"),
+			LifecycleState: keymanagement.KeyLifecycleStateEnabled,
+		})
+		ociEnv.KmsCli.Keys.Add(&keymanagement.Key{
+			Id:             common.String("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaab"),
+			LifecycleState: keymanagement.KeyLifecycleStateDisabled,
+		})
+	})
+	It("Should be ready when no kms key is specified", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).IsTrue()).To(BeTrue())
+		Expect(ociEnv.KmsCli.CalledWithGetKeys.Len()).To(Equal(0))
+	})
+	It("Should be ready when the kms keys exist", func() {
+		nodeClass.Spec.BootConfig.KmsKeyId = common.String("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaaa")
+		nodeClass.Spec.BlockDevices = []*v1alpha1.VolumeAttributes{{SizeInGBs: 100, VpusPerGB: 10, KmsKeyId: common.String("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaaa")}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).IsTrue()).To(BeTrue())
+		Expect(ociEnv.KmsCli.CalledWithGetKeys.Len()).To(Equal(1))
+	})
+	It("Should not be ready when the kms key is not found", func() {
+		nodeClass.Spec.BlockDevices = []*v1alpha1.VolumeAttributes{{SizeInGBs: 100, VpusPerGB: 10, KmsKeyId: common.String("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaac")}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).IsFalse()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).Reason).To(Equal("KmsKeyNotFound"))
+	})
+	It("Should not be ready when the kms key is not enabled", func() {
+		nodeClass.Spec.BootConfig.KmsKeyId = common.String("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaab")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).IsFalse()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).Reason).To(Equal("KmsKeyNotEnabled"))
+	})
+	It("Should not be ready when the kms key id is malformed", func() {
+		nodeClass.Spec.BootConfig.KmsKeyId = common.String("ocid1.vault.oc1.iad.bbpnr2ukaaeuk")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).IsFalse()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeKmsKeyReady).Reason).To(Equal("InvalidKmsKeyId"))
+		Expect(ociEnv.KmsCli.CalledWithGetKeys.Len()).To(Equal(0))
+	})
+})
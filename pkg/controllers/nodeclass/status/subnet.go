@@ -49,8 +49,9 @@ func (s *Subnet) Reconcile(ctx context.Context, nodeClass *v1alpha1.OciNodeClass
 	})
 	nodeClass.Status.Subnets = lo.Map(subnets, func(ociSubnet core.Subnet, _ int) *v1alpha1.Subnet {
 		subnetStatus := &v1alpha1.Subnet{
-			Id:   utils.ToString(ociSubnet.Id),
-			Name: utils.ToString(ociSubnet.DisplayName),
+			Id:                  utils.ToString(ociSubnet.Id),
+			Name:                utils.ToString(ociSubnet.DisplayName),
+			AvailabilityDomains: subnet.Zones(ctx, &ociSubnet),
 		}
 		summarys, err1 := s.subnetProvider.GetSubnetUtilization(ctx, &ociSubnet)
 		if err1 != nil {
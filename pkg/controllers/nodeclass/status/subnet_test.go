@@ -18,6 +18,7 @@ import (
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
 	test2 "github.com/zoom/karpenter-oci/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -74,6 +75,18 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1alpha1.ConditionTypeSubnetsReady)).To(BeTrue())
 	})
+	It("Should resolve the availability domains of the Subnets", func() {
+		ctx = options.ToContext(ctx, test2.Options(test2.OptionsFields{AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2"}}))
+		subnets := ociEnv.VcnCli.ListSubnetsOutput.Clone().Items
+		subnets[1].AvailabilityDomain = common.String("JPqd:US-ASHBURN-AD-2")
+		ociEnv.VcnCli.ListSubnetsOutput.Set(&core.ListSubnetsResponse{Items: subnets})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.Subnets).To(HaveLen(2))
+		Expect(nodeClass.Status.Subnets[0].AvailabilityDomains).To(Equal([]string{"US-ASHBURN-AD-1", "US-ASHBURN-AD-2"}))
+		Expect(nodeClass.Status.Subnets[1].AvailabilityDomains).To(Equal([]string{"US-ASHBURN-AD-2"}))
+	})
 	It("Should not resolve a invalid selectors for Subnet", func() {
 		nodeClass.Spec.SubnetSelector = []v1alpha1.SubnetSelectorTerm{{
 			Name: "fake_subnet_name",
@@ -54,6 +54,8 @@ var _ = BeforeSuite(func() {
 		ociEnv.SubnetProvider,
 		ociEnv.SecurityGroupProvider,
 		ociEnv.AMIProvider,
+		ociEnv.CapacityReservationProvider,
+		ociEnv.KmsProvider,
 	)
 })
 
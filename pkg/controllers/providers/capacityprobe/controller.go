@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityprobe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityprobe"
+	"github.com/zoom/karpenter-oci/pkg/providers/instancetype"
+	"go.uber.org/multierr"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+)
+
+// Controller probes the capacity of the instance types of the nodeclasses in the background, so the offerings out of
+// capacity are unavailable before the nodeclaims are scheduled
+type Controller struct {
+	kubeClient            client.Client
+	instanceTypeProvider  *instancetype.Provider
+	capacityProbeProvider *capacityprobe.Provider
+}
+
+func NewController(kubeClient client.Client, instanceTypeProvider *instancetype.Provider, capacityProbeProvider *capacityprobe.Provider) *Controller {
+	return &Controller{
+		kubeClient:            kubeClient,
+		instanceTypeProvider:  instanceTypeProvider,
+		capacityProbeProvider: capacityProbeProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.capacityprobe")
+
+	nodeClasses := &v1alpha1.OciNodeClassList{}
+	if err := c.kubeClient.List(ctx, nodeClasses); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclasses, %w", err)
+	}
+	var errs []error
+	for i := range nodeClasses.Items {
+		if !nodeClasses.Items[i].DeletionTimestamp.IsZero() {
+			continue
+		}
+		instanceTypes, err := c.instanceTypeProvider.List(ctx, &nodeClasses.Items[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing instance types of nodeclass %s, %w", nodeClasses.Items[i].Name, err))
+			continue
+		}
+		if err := c.capacityProbeProvider.ProbeAll(ctx, instanceTypes); err != nil {
+			errs = append(errs, fmt.Errorf("probing the capacity of nodeclass %s, %w", nodeClasses.Items[i].Name, err))
+		}
+	}
+	if err := multierr.Combine(errs...); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: time.Duration(options.FromContext(ctx).CapacityProbePeriod) * time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.capacityprobe").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
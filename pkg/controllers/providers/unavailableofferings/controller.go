@@ -0,0 +1,160 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unavailableofferings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zoom/karpenter-oci/pkg/cache"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+)
+
+const (
+	// ConfigMapName is the name of the configmap which persists the unavailable offerings
+	ConfigMapName = "karpenter-oci-unavailable-offerings"
+	// SnapshotKey is the key of the snapshot of the unavailable offerings in the configmap
+	SnapshotKey = "snapshot.json"
+	// DebugPath is the path of the unavailable offerings on the metrics server
+	DebugPath  = "/debug/unavailable-offerings"
+	syncPeriod = 30 * time.Second
+)
+
+// Controller persists the unavailable offerings to a configmap and restores them when the controller becomes the
+// leader, so a restarted or newly elected leader doesn't repeat the known launch failures. The unavailable offerings
+// are exported as metrics on each sync.
+type Controller struct {
+	kubeClient           client.Client
+	apiReader            client.Reader
+	unavailableOfferings *cache.UnavailableOfferings
+	namespace            string
+	restored             bool
+	// persistedSeqNum is the sequence number of the unavailable offerings when they were last persisted
+	persistedSeqNum uint64
+}
+
+// NewController creates the controller, the configmap is read with the api reader, so the configmaps aren't watched
+func NewController(kubeClient client.Client, apiReader client.Reader, unavailableOfferings *cache.UnavailableOfferings, namespace string) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		apiReader:            apiReader,
+		unavailableOfferings: unavailableOfferings,
+		namespace:            namespace,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.unavailableofferings")
+
+	configMap := &corev1.ConfigMap{}
+	err := c.apiReader.Get(ctx, types.NamespacedName{Namespace: c.namespace, Name: ConfigMapName}, configMap)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, fmt.Errorf("getting configmap %s, %w", ConfigMapName, err)
+	}
+	found := err == nil
+	if !c.restored {
+		if data, ok := configMap.Data[SnapshotKey]; ok && found {
+			snapshot := cache.Snapshot{}
+			if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+				log.FromContext(ctx).Error(err, "failed to parse the persisted unavailable offerings, ignoring them")
+			} else {
+				c.unavailableOfferings.Restore(snapshot)
+				log.FromContext(ctx).WithValues("offerings", len(snapshot.Offerings), "degraded-zones", len(snapshot.DegradedZones)).
+					V(1).Info("restored the unavailable offerings")
+			}
+		}
+		c.restored = true
+	}
+	snapshot := c.unavailableOfferings.Snapshot()
+	exportMetrics(snapshot)
+
+	seqNum := atomic.LoadUint64(&c.unavailableOfferings.SeqNum)
+	if found && seqNum == c.persistedSeqNum {
+		return reconcile.Result{RequeueAfter: syncPeriod}, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("marshaling the unavailable offerings, %w", err)
+	}
+	if !found {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: c.namespace},
+			Data:       map[string]string{SnapshotKey: string(data)},
+		}
+		if err := c.kubeClient.Create(ctx, configMap); err != nil {
+			return reconcile.Result{}, fmt.Errorf("creating configmap %s, %w", ConfigMapName, err)
+		}
+	} else {
+		stored := configMap.DeepCopy()
+		configMap.Data = map[string]string{SnapshotKey: string(data)}
+		if err := c.kubeClient.Patch(ctx, configMap, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, fmt.Errorf("patching configmap %s, %w", ConfigMapName, err)
+		}
+	}
+	c.persistedSeqNum = seqNum
+	return reconcile.Result{RequeueAfter: syncPeriod}, nil
+}
+
+// exportMetrics replaces the metrics of the unavailable offerings and the degraded zones with the snapshot
+func exportMetrics(snapshot cache.Snapshot) {
+	offeringUnavailable.Reset()
+	for _, offering := range snapshot.Offerings {
+		offeringUnavailable.With(prometheus.Labels{
+			instanceTypeLabel: offering.InstanceType,
+			capacityTypeLabel: offering.CapacityType,
+			zoneLabel:         offering.Zone,
+			reasonLabel:       string(offering.Reason),
+		}).Set(1)
+	}
+	zoneDegraded.Reset()
+	for _, zone := range snapshot.DegradedZones {
+		zoneDegraded.With(prometheus.Labels{zoneLabel: zone.Zone}).Set(1)
+	}
+}
+
+// ServeHTTP writes the unavailable offerings and the degraded zones as json
+func (c *Controller) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(c.unavailableOfferings.Snapshot()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	if err := m.AddMetricsServerExtraHandler(DebugPath, c); err != nil {
+		return fmt.Errorf("adding handler %s, %w", DebugPath, err)
+	}
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.unavailableofferings").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unavailableofferings_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zoom/karpenter-oci/pkg/apis"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/controllers/providers/unavailableofferings"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	coretestv1alpha1 "sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var ociEnv *test.Environment
+var controller *unavailableofferings.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "UnavailableOfferings")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(coretestv1alpha1.CRDs...), coretest.WithFieldIndexers(test.OciNodeClassFieldIndexer(ctx)))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ociEnv = test.NewEnvironment(ctx, env)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ociEnv.Reset()
+	controller = unavailableofferings.NewController(env.Client, env.Client, ociEnv.UnavailableOfferingsCache, "default")
+})
+
+var _ = AfterEach(func() {
+	ExpectDeleted(ctx, env.Client, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: unavailableofferings.ConfigMapName, Namespace: "default"}})
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func expectPersisted() ocicache.Snapshot {
+	GinkgoHelper()
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: unavailableofferings.ConfigMapName, Namespace: "default"}}
+	configMap = ExpectExists(ctx, env.Client, configMap)
+	snapshot := ocicache.Snapshot{}
+	Expect(json.Unmarshal([]byte(configMap.Data[unavailableofferings.SnapshotKey]), &snapshot)).To(Succeed())
+	return snapshot
+}
+
+var _ = Describe("UnavailableOfferings", func() {
+	It("should persist the unavailable offerings to the configmap", func() {
+		ociEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, ocicache.UnavailableReasonOutOfCapacity, "shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)
+		ExpectSingletonReconciled(ctx, controller)
+		snapshot := expectPersisted()
+		Expect(snapshot.Offerings).To(HaveLen(1))
+		Expect(snapshot.Offerings[0].InstanceType).To(Equal("shape-1"))
+		Expect(snapshot.Offerings[0].Reason).To(Equal(ocicache.UnavailableReasonOutOfCapacity))
+		Expect(snapshot.Offerings[0].Expiration).To(BeTemporally("~", time.Now().Add(ocicache.UnavailableOfferingsTTL), time.Minute))
+
+		// the changes are persisted on the next sync
+		ociEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, ocicache.UnavailableReasonQuotaExceeded, "shape-2", "US-ASHBURN-AD-2", v1alpha1.CapacityTypePreemptible)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectPersisted().Offerings).To(HaveLen(2))
+	})
+	It("should restore the unexpired offerings from the configmap", func() {
+		snapshot := ocicache.Snapshot{
+			Offerings: []ocicache.UnavailableOffering{
+				{InstanceType: "shape-1", Zone: "US-ASHBURN-AD-1", CapacityType: karpv1.CapacityTypeOnDemand, Reason: ocicache.UnavailableReasonLimitExceeded, Expiration: time.Now().Add(10 * time.Minute)},
+				{InstanceType: "shape-2", Zone: "US-ASHBURN-AD-1", CapacityType: karpv1.CapacityTypeOnDemand, Reason: ocicache.UnavailableReasonOutOfCapacity, Expiration: time.Now().Add(-time.Minute)},
+			},
+			DegradedZones: []ocicache.DegradedZone{{Zone: "US-ASHBURN-AD-2", Expiration: time.Now().Add(5 * time.Minute)}},
+		}
+		data, err := json.Marshal(snapshot)
+		Expect(err).ToNot(HaveOccurred())
+		ExpectApplied(ctx, env.Client, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: unavailableofferings.ConfigMapName, Namespace: "default"},
+			Data:       map[string]string{unavailableofferings.SnapshotKey: string(data)},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeTrue())
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-2", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+		Expect(ociEnv.UnavailableOfferingsCache.IsDegraded("US-ASHBURN-AD-2")).To(BeTrue())
+		// the expired offering isn't persisted again
+		Expect(expectPersisted().Offerings).To(HaveLen(1))
+	})
+	It("should serve the unavailable offerings as json", func() {
+		ociEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, ocicache.UnavailableReasonCapacityReport, "shape-1", "US-ASHBURN-AD-3", karpv1.CapacityTypeOnDemand)
+		recorder := httptest.NewRecorder()
+		controller.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, unavailableofferings.DebugPath, nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		snapshot := ocicache.Snapshot{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &snapshot)).To(Succeed())
+		Expect(snapshot.Offerings).To(HaveLen(1))
+		Expect(snapshot.Offerings[0].Zone).To(Equal("US-ASHBURN-AD-3"))
+		Expect(snapshot.Offerings[0].Reason).To(Equal(ocicache.UnavailableReasonCapacityReport))
+	})
+})
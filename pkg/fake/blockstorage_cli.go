@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+)
+
+type BlockStorageCli struct {
+	BlockStorageBehavior
+}
+
+// BlockStorageBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type BlockStorageBehavior struct {
+	UpdateBootVolumeBehavior MockedFunction[core.UpdateBootVolumeRequest, core.UpdateBootVolumeResponse]
+	UpdateVolumeBehavior     MockedFunction[core.UpdateVolumeRequest, core.UpdateVolumeResponse]
+}
+
+var _ api.BlockStorageClient = &BlockStorageCli{}
+
+func NewBlockStorageCli() *BlockStorageCli {
+	return &BlockStorageCli{}
+}
+
+func (c *BlockStorageCli) UpdateBootVolume(ctx context.Context, request core.UpdateBootVolumeRequest) (response core.UpdateBootVolumeResponse, err error) {
+	ptr, err := c.UpdateBootVolumeBehavior.Invoke(&request, func(input *core.UpdateBootVolumeRequest) (*core.UpdateBootVolumeResponse, error) {
+		return &core.UpdateBootVolumeResponse{
+			RawResponse: &http.Response{StatusCode: http.StatusOK},
+			BootVolume: core.BootVolume{
+				Id:               input.BootVolumeId,
+				AutotunePolicies: input.AutotunePolicies,
+			},
+		}, nil
+	})
+	if err != nil {
+		return core.UpdateBootVolumeResponse{}, err
+	}
+	return *ptr, nil
+}
+
+func (c *BlockStorageCli) UpdateVolume(ctx context.Context, request core.UpdateVolumeRequest) (response core.UpdateVolumeResponse, err error) {
+	ptr, err := c.UpdateVolumeBehavior.Invoke(&request, func(input *core.UpdateVolumeRequest) (*core.UpdateVolumeResponse, error) {
+		return &core.UpdateVolumeResponse{
+			RawResponse: &http.Response{StatusCode: http.StatusOK},
+			Volume: core.Volume{
+				Id:               input.VolumeId,
+				AutotunePolicies: input.AutotunePolicies,
+			},
+		}, nil
+	})
+	if err != nil {
+		return core.UpdateVolumeResponse{}, err
+	}
+	return *ptr, nil
+}
+
+func (c *BlockStorageCli) Reset() {
+	c.UpdateBootVolumeBehavior.Reset()
+	c.UpdateVolumeBehavior.Reset()
+}
@@ -22,11 +22,14 @@ import (
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
 	"github.com/zoom/karpenter-oci/pkg/providers/internalmodel"
 	"net/http"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/utils/atomic"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -40,24 +43,40 @@ type CapacityPool struct {
 	CapacityType string
 	InstanceType string
 	Zone         string
+	// Ocpus restricts the capacity reports out of capacity to a size of the flex shape, all the sizes when it's zero
+	Ocpus float32
 }
 
 // CmpBehavior must be reset between tests otherwise tests will
 // pollute each other.
 type CmpBehavior struct {
-	GetImagesOutput             AtomicPtr[core.GetImageResponse]
-	ListImagesOutput            AtomicPtr[core.ListImagesResponse]
-	DescribeInstanceTypesOutput AtomicPtrSlice[internalmodel.WrapShape]
-	LaunchInstanceBehavior      MockedFunction[core.LaunchInstanceRequest, core.LaunchInstanceResponse]
-	TerminateInstancesBehavior  MockedFunction[core.TerminateInstanceRequest, core.TerminateInstanceResponse]
-	GetInstanceBehavior         MockedFunction[core.GetInstanceRequest, core.GetInstanceResponse]
-	GetVnicAttachmentBehavior   MockedFunction[core.ListVnicAttachmentsRequest, core.ListVnicAttachmentsResponse]
-	ListInstanceBehavior        MockedFunction[core.ListInstancesRequest, core.ListInstancesResponse]
-	CalledWithListImagesInput   AtomicPtrSlice[core.ListImagesRequest]
-	UpdateInstanceBehavior      MockedFunction[core.UpdateInstanceRequest, core.UpdateInstanceResponse]
-	Instances                   sync.Map
-	Vnics                       sync.Map
-	InsufficientCapacityPools   atomic.Slice[CapacityPool]
+	GetImagesOutput               AtomicPtr[core.GetImageResponse]
+	ListImagesOutput              AtomicPtr[core.ListImagesResponse]
+	DescribeInstanceTypesOutput   AtomicPtrSlice[internalmodel.WrapShape]
+	LaunchInstanceBehavior        MockedFunction[core.LaunchInstanceRequest, core.LaunchInstanceResponse]
+	TerminateInstancesBehavior    MockedFunction[core.TerminateInstanceRequest, core.TerminateInstanceResponse]
+	GetInstanceBehavior           MockedFunction[core.GetInstanceRequest, core.GetInstanceResponse]
+	GetVnicAttachmentBehavior     MockedFunction[core.ListVnicAttachmentsRequest, core.ListVnicAttachmentsResponse]
+	ListInstanceBehavior          MockedFunction[core.ListInstancesRequest, core.ListInstancesResponse]
+	CalledWithListImagesInput     AtomicPtrSlice[core.ListImagesRequest]
+	UpdateInstanceBehavior        MockedFunction[core.UpdateInstanceRequest, core.UpdateInstanceResponse]
+	Instances                     sync.Map
+	Vnics                         sync.Map
+	BootVolumeAttachments         sync.Map
+	VolumeAttachments             sync.Map
+	InsufficientCapacityPools     atomic.Slice[CapacityPool]
+	CapacityReservations          AtomicPtrSlice[core.ComputeCapacityReservation]
+	CapacityReportBehavior        MockedFunction[core.CreateComputeCapacityReportRequest, core.CreateComputeCapacityReportResponse]
+	CaptureConsoleHistoryBehavior MockedFunction[core.CaptureConsoleHistoryRequest, core.CaptureConsoleHistoryResponse]
+	// RetryTokens are the retry tokens of the launched instances, key: retry token, value: instance id
+	RetryTokens sync.Map
+	// ConsoleOutputs are the serial console outputs of the instances, key: instance id, value: string
+	ConsoleOutputs sync.Map
+	// ConsoleHistories are the captured console histories, key: console history id, value: *core.ConsoleHistory
+	ConsoleHistories sync.Map
+	// PageSize is the number of the images, instances and capacity reservations of a page of the list calls, a single
+	// page when nil
+	PageSize AtomicPtr[int]
 }
 
 type FakeServicefailure struct {
@@ -92,7 +111,14 @@ func (se FakeServicefailure) Error() string {
 var defaultDescribeInstanceTypesOutput = core.ListShapesResponse{
 	Items: []core.Shape{
 		{Shape: common.String("shape-1"), IsFlexible: common.Bool(false), Ocpus: common.Float32(1), MemoryInGBs: common.Float32(4),
-			NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)},
+			NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2),
+			PlatformConfigOptions: &core.ShapePlatformConfigOptions{
+				Type:                         core.ShapePlatformConfigOptionsTypeAmdVm,
+				SecureBootOptions:            &core.ShapeSecureBootOptions{AllowedValues: []bool{true, false}},
+				MeasuredBootOptions:          &core.ShapeMeasuredBootOptions{AllowedValues: []bool{true, false}},
+				TrustedPlatformModuleOptions: &core.ShapeTrustedPlatformModuleOptions{AllowedValues: []bool{true, false}},
+				MemoryEncryptionOptions:      &core.ShapeMemoryEncryptionOptions{AllowedValues: []bool{true, false}},
+			}},
 		{Shape: common.String("shape-2"), IsFlexible: common.Bool(false), Ocpus: common.Float32(2), MemoryInGBs: common.Float32(8),
 			NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)},
 		{Shape: common.String("shape-3"), IsFlexible: common.Bool(false), Ocpus: common.Float32(4), MemoryInGBs: common.Float32(16),
@@ -100,7 +126,8 @@ var defaultDescribeInstanceTypesOutput = core.ListShapesResponse{
 		{Shape: common.String("shape-4"), IsFlexible: common.Bool(false), Ocpus: common.Float32(8), MemoryInGBs: common.Float32(32),
 			NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)},
 		{Shape: common.String("shape-gpu"), IsFlexible: common.Bool(false), Ocpus: common.Float32(2), MemoryInGBs: common.Float32(8),
-			NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2), Gpus: common.Int(1), GpuDescription: common.String("A100")},
+			NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2), Gpus: common.Int(1), GpuDescription: common.String("A100"),
+			ProcessorDescription: common.String("2.6 GHz Intel® Xeon® Platinum 8358 (Ice Lake)")},
 	},
 }
 
@@ -114,10 +141,11 @@ func (c *CmpCli) ListImages(ctx context.Context, request core.ListImagesRequest)
 	c.CalledWithListImagesInput.Add(&request)
 	if !c.ListImagesOutput.IsNil() {
 		describeImagesOutput := c.ListImagesOutput.Clone()
-		describeImagesOutput.Items = FilterDescribeImages(describeImagesOutput.Items, *request.DisplayName)
+		describeImagesOutput.Items, describeImagesOutput.OpcNextPage = paginate(FilterDescribeImages(describeImagesOutput.Items, request.DisplayName),
+			request.Page, c.PageSize.Clone())
 		return *describeImagesOutput, nil
 	}
-	if *request.DisplayName == "invalid" {
+	if lo.FromPtr(request.DisplayName) == "invalid" {
 		return core.ListImagesResponse{}, nil
 	}
 	return core.ListImagesResponse{
@@ -144,17 +172,23 @@ func (c *CmpCli) GetImage(ctx context.Context, request core.GetImageRequest) (re
 		},
 	}, nil
 }
-func FilterDescribeImages(images []core.Image, name string) []core.Image {
+func FilterDescribeImages(images []core.Image, name *string) []core.Image {
 	return lo.Filter(images, func(image core.Image, _ int) bool {
-		return *image.DisplayName == name
+		return name == nil || *image.DisplayName == *name
 	})
 }
 
 func (c *CmpCli) LaunchInstance(ctx context.Context, request core.LaunchInstanceRequest) (response core.LaunchInstanceResponse, err error) {
 	ptr, err := c.LaunchInstanceBehavior.Invoke(&request, func(input *core.LaunchInstanceRequest) (*core.LaunchInstanceResponse, error) {
 		var insufficientErr error
+		capacityType := karpv1.CapacityTypeOnDemand
+		if request.PreemptibleInstanceConfig != nil {
+			capacityType = v1alpha1.CapacityTypePreemptible
+		} else if request.CapacityReservationId != nil {
+			capacityType = karpv1.CapacityTypeReserved
+		}
 		c.InsufficientCapacityPools.Range(func(pool CapacityPool) bool {
-			if pool.InstanceType == lo.FromPtr(request.Shape) && pool.Zone == strings.Split(lo.FromPtr(request.AvailabilityDomain), ":")[1] {
+			if pool.CapacityType == capacityType && pool.InstanceType == lo.FromPtr(request.Shape) && pool.Zone == strings.Split(lo.FromPtr(request.AvailabilityDomain), ":")[1] {
 				insufficientErr = &FakeServicefailure{StatusCode: 500, Message: "Out of host capacity"}
 				return false
 			}
@@ -163,19 +197,46 @@ func (c *CmpCli) LaunchInstance(ctx context.Context, request core.LaunchInstance
 		if insufficientErr != nil {
 			return nil, insufficientErr
 		}
+		// the launch with the retry token of a launched instance returns the launched instance
+		if request.OpcRetryToken != nil {
+			if id, ok := c.RetryTokens.Load(*request.OpcRetryToken); ok {
+				if instance, ok := c.Instances.Load(id); ok {
+					launched := instance.(*core.Instance)
+					// the retry token of another request is rejected
+					if lo.FromPtr(launched.Shape) != lo.FromPtr(request.Shape) || lo.FromPtr(launched.AvailabilityDomain) != lo.FromPtr(request.AvailabilityDomain) {
+						return nil, &FakeServicefailure{StatusCode: http.StatusConflict, Code: "IncorrectState", Message: "The retry token was used with a different request"}
+					}
+					return &core.LaunchInstanceResponse{Instance: *launched}, nil
+				}
+			}
+		}
 		imageId := common.String("ocid1.image.oc1.iad.aaaaaaaa")
 		instance := &core.Instance{
-			Id:                 common.String(uuid.New().String()),
-			Shape:              request.Shape,
-			AvailabilityDomain: request.AvailabilityDomain,
-			FaultDomain:        common.String("FAULT-DOMAIN-1"),
-			TimeCreated:        &common.SDKTime{Time: time.Now()},
+			Id:                        common.String(uuid.New().String()),
+			Shape:                     request.Shape,
+			DisplayName:               request.DisplayName,
+			DefinedTags:               request.DefinedTags,
+			AvailabilityDomain:        request.AvailabilityDomain,
+			FaultDomain:               lo.Ternary(request.FaultDomain != nil, request.FaultDomain, common.String("FAULT-DOMAIN-1")),
+			TimeCreated:               &common.SDKTime{Time: time.Now()},
+			CapacityReservationId:     request.CapacityReservationId,
+			PreemptibleInstanceConfig: request.PreemptibleInstanceConfig,
 			SourceDetails: core.InstanceSourceViaImageDetails{
 				ImageId: imageId,
 			},
 			ImageId: imageId,
 		}
+		if request.ShapeConfig != nil {
+			instance.ShapeConfig = &core.InstanceShapeConfig{
+				Ocpus:                   request.ShapeConfig.Ocpus,
+				MemoryInGBs:             request.ShapeConfig.MemoryInGBs,
+				BaselineOcpuUtilization: core.InstanceShapeConfigBaselineOcpuUtilizationEnum(request.ShapeConfig.BaselineOcpuUtilization),
+			}
+		}
 		c.Instances.Store(*instance.Id, instance)
+		if request.OpcRetryToken != nil {
+			c.RetryTokens.Store(*request.OpcRetryToken, *instance.Id)
+		}
 
 		vnics := []core.VnicAttachment{
 			{
@@ -191,6 +252,27 @@ func (c *CmpCli) LaunchInstance(ctx context.Context, request core.LaunchInstance
 		}
 		c.Vnics.Store(*instance.Id, vnics)
 
+		c.BootVolumeAttachments.Store(*instance.Id, []core.BootVolumeAttachment{
+			{
+				AvailabilityDomain: request.AvailabilityDomain,
+				BootVolumeId:       common.String(uuid.New().String()),
+				Id:                 common.String(uuid.New().String()),
+				InstanceId:         instance.Id,
+				LifecycleState:     core.BootVolumeAttachmentLifecycleStateAttached,
+			},
+		})
+		c.VolumeAttachments.Store(*instance.Id, lo.Map(request.LaunchVolumeAttachments, func(item core.LaunchAttachVolumeDetails, _ int) core.VolumeAttachment {
+			return core.IScsiVolumeAttachment{
+				AvailabilityDomain: request.AvailabilityDomain,
+				Id:                 common.String(uuid.New().String()),
+				InstanceId:         instance.Id,
+				VolumeId:           common.String(uuid.New().String()),
+				DisplayName:        item.GetDisplayName(),
+				Device:             item.GetDevice(),
+				LifecycleState:     core.VolumeAttachmentLifecycleStateAttached,
+			}
+		}))
+
 		result := &core.LaunchInstanceResponse{
 			Instance: *instance,
 		}
@@ -235,16 +317,21 @@ func (c *CmpCli) ListInstances(ctx context.Context, request core.ListInstancesRe
 		var instances []*core.Instance
 		c.Instances.Range(func(k interface{}, v interface{}) bool {
 			ins := v.(*core.Instance)
-			instances = append(instances, ins)
+			if input.DisplayName == nil || lo.FromPtr(ins.DisplayName) == *input.DisplayName {
+				instances = append(instances, ins)
+			}
 			return true
 		})
 
+		// the pages are listed in a stable order
+		sort.Slice(instances, func(i, j int) bool { return lo.FromPtr(instances[i].Id) < lo.FromPtr(instances[j].Id) })
+		items, next := paginate(lo.FlatMap[*core.Instance, core.Instance](instances, func(item *core.Instance, index int) []core.Instance {
+			return []core.Instance{*item}
+		}), input.Page, c.PageSize.Clone())
 		return &core.ListInstancesResponse{
-			RawResponse: nil,
-			Items: lo.FlatMap[*core.Instance, core.Instance](instances, func(item *core.Instance, index int) []core.Instance {
-				return []core.Instance{*item}
-			}),
-			OpcNextPage:  nil,
+			RawResponse:  nil,
+			Items:        items,
+			OpcNextPage:  next,
 			OpcRequestId: nil,
 		}, nil
 	})
@@ -313,6 +400,148 @@ func (c *CmpCli) ListVnicAttachments(ctx context.Context, request core.ListVnicA
 	return *ptr, err
 }
 
+func (c *CmpCli) ListBootVolumeAttachments(ctx context.Context, request core.ListBootVolumeAttachmentsRequest) (response core.ListBootVolumeAttachmentsResponse, err error) {
+	attachments, ok := c.BootVolumeAttachments.Load(lo.FromPtr(request.InstanceId))
+	if !ok {
+		return core.ListBootVolumeAttachmentsResponse{RawResponse: &http.Response{StatusCode: http.StatusOK}}, nil
+	}
+	return core.ListBootVolumeAttachmentsResponse{
+		RawResponse: &http.Response{StatusCode: http.StatusOK},
+		Items:       attachments.([]core.BootVolumeAttachment),
+	}, nil
+}
+
+func (c *CmpCli) ListVolumeAttachments(ctx context.Context, request core.ListVolumeAttachmentsRequest) (response core.ListVolumeAttachmentsResponse, err error) {
+	attachments, ok := c.VolumeAttachments.Load(lo.FromPtr(request.InstanceId))
+	if !ok {
+		return core.ListVolumeAttachmentsResponse{RawResponse: &http.Response{StatusCode: http.StatusOK}}, nil
+	}
+	return core.ListVolumeAttachmentsResponse{
+		RawResponse: &http.Response{StatusCode: http.StatusOK},
+		Items:       attachments.([]core.VolumeAttachment),
+	}, nil
+}
+
+func (c *CmpCli) ListComputeCapacityReservations(ctx context.Context, request core.ListComputeCapacityReservationsRequest) (response core.ListComputeCapacityReservationsResponse, err error) {
+	items := make([]core.ComputeCapacityReservationSummary, 0)
+	c.CapacityReservations.ForEach(func(reservation *core.ComputeCapacityReservation) {
+		if request.DisplayName != nil && lo.FromPtr(reservation.DisplayName) != *request.DisplayName {
+			return
+		}
+		items = append(items, core.ComputeCapacityReservationSummary{
+			Id:                    reservation.Id,
+			CompartmentId:         reservation.CompartmentId,
+			AvailabilityDomain:    reservation.AvailabilityDomain,
+			DisplayName:           reservation.DisplayName,
+			LifecycleState:        reservation.LifecycleState,
+			FreeformTags:          reservation.FreeformTags,
+			DefinedTags:           reservation.DefinedTags,
+			ReservedInstanceCount: reservation.ReservedInstanceCount,
+			UsedInstanceCount:     reservation.UsedInstanceCount,
+		})
+	})
+	items, next := paginate(items, request.Page, c.PageSize.Clone())
+	return core.ListComputeCapacityReservationsResponse{Items: items, OpcNextPage: next}, nil
+}
+
+func (c *CmpCli) GetComputeCapacityReservation(ctx context.Context, request core.GetComputeCapacityReservationRequest) (response core.GetComputeCapacityReservationResponse, err error) {
+	var found *core.ComputeCapacityReservation
+	c.CapacityReservations.ForEach(func(reservation *core.ComputeCapacityReservation) {
+		if lo.FromPtr(reservation.Id) == lo.FromPtr(request.CapacityReservationId) {
+			found = reservation
+		}
+	})
+	if found == nil {
+		return core.GetComputeCapacityReservationResponse{RawResponse: &http.Response{StatusCode: http.StatusNotFound}},
+			&FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "capacity reservation not found"}
+	}
+	return core.GetComputeCapacityReservationResponse{ComputeCapacityReservation: *found}, nil
+}
+
+// CreateComputeCapacityReport reports the on-demand insufficient capacity pools out of host capacity
+func (c *CmpCli) CreateComputeCapacityReport(ctx context.Context, request core.CreateComputeCapacityReportRequest) (response core.CreateComputeCapacityReportResponse, err error) {
+	ptr, err := c.CapacityReportBehavior.Invoke(&request, func(input *core.CreateComputeCapacityReportRequest) (*core.CreateComputeCapacityReportResponse, error) {
+		zone := strings.Split(lo.FromPtr(input.AvailabilityDomain), ":")[1]
+		return &core.CreateComputeCapacityReportResponse{ComputeCapacityReport: core.ComputeCapacityReport{
+			CompartmentId:      input.CompartmentId,
+			AvailabilityDomain: input.AvailabilityDomain,
+			TimeCreated:        &common.SDKTime{Time: time.Now()},
+			ShapeAvailabilities: lo.Map(input.ShapeAvailabilities, func(item core.CreateCapacityReportShapeAvailabilityDetails, _ int) core.CapacityReportShapeAvailability {
+				status := core.CapacityReportShapeAvailabilityAvailabilityStatusAvailable
+				c.InsufficientCapacityPools.Range(func(pool CapacityPool) bool {
+					if pool.CapacityType == karpv1.CapacityTypeOnDemand && pool.InstanceType == lo.FromPtr(item.InstanceShape) && pool.Zone == zone &&
+						(pool.Ocpus == 0 || item.InstanceShapeConfig != nil && pool.Ocpus == lo.FromPtr(item.InstanceShapeConfig.Ocpus)) {
+						status = core.CapacityReportShapeAvailabilityAvailabilityStatusOutOfHostCapacity
+						return false
+					}
+					return true
+				})
+				return core.CapacityReportShapeAvailability{
+					InstanceShape:       item.InstanceShape,
+					InstanceShapeConfig: item.InstanceShapeConfig,
+					AvailableCount:      common.Int64(lo.Ternary[int64](status == core.CapacityReportShapeAvailabilityAvailabilityStatusAvailable, 1, 0)),
+					AvailabilityStatus:  status,
+				}
+			}),
+		}}, nil
+	})
+	if err != nil {
+		return core.CreateComputeCapacityReportResponse{}, err
+	}
+	return *ptr, nil
+}
+
+// CaptureConsoleHistory captures the console output of the instance, the console history succeeds immediately
+func (c *CmpCli) CaptureConsoleHistory(ctx context.Context, request core.CaptureConsoleHistoryRequest) (response core.CaptureConsoleHistoryResponse, err error) {
+	ptr, err := c.CaptureConsoleHistoryBehavior.Invoke(&request, func(input *core.CaptureConsoleHistoryRequest) (*core.CaptureConsoleHistoryResponse, error) {
+		if _, ok := c.Instances.Load(lo.FromPtr(input.InstanceId)); !ok {
+			return nil, &FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "instance not found"}
+		}
+		history := &core.ConsoleHistory{
+			Id:             common.String(uuid.New().String()),
+			InstanceId:     input.InstanceId,
+			LifecycleState: core.ConsoleHistoryLifecycleStateSucceeded,
+			TimeCreated:    &common.SDKTime{Time: time.Now()},
+		}
+		c.ConsoleHistories.Store(*history.Id, history)
+		return &core.CaptureConsoleHistoryResponse{ConsoleHistory: *history}, nil
+	})
+	if err != nil {
+		return core.CaptureConsoleHistoryResponse{}, err
+	}
+	return *ptr, nil
+}
+
+func (c *CmpCli) GetConsoleHistory(ctx context.Context, request core.GetConsoleHistoryRequest) (response core.GetConsoleHistoryResponse, err error) {
+	history, ok := c.ConsoleHistories.Load(lo.FromPtr(request.InstanceConsoleHistoryId))
+	if !ok {
+		return core.GetConsoleHistoryResponse{}, &FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "console history not found"}
+	}
+	return core.GetConsoleHistoryResponse{ConsoleHistory: *history.(*core.ConsoleHistory)}, nil
+}
+
+// GetConsoleHistoryContent returns the range of the console output of the instance, the bytes after the range are
+// returned in opc-bytes-remaining
+func (c *CmpCli) GetConsoleHistoryContent(ctx context.Context, request core.GetConsoleHistoryContentRequest) (response core.GetConsoleHistoryContentResponse, err error) {
+	history, ok := c.ConsoleHistories.Load(lo.FromPtr(request.InstanceConsoleHistoryId))
+	if !ok {
+		return core.GetConsoleHistoryContentResponse{}, &FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "console history not found"}
+	}
+	output, _ := c.ConsoleOutputs.Load(lo.FromPtr(history.(*core.ConsoleHistory).InstanceId))
+	content, _ := output.(string)
+	start := lo.Clamp(lo.FromPtr(request.Offset), 0, len(content))
+	end := lo.Clamp(start+lo.FromPtr(request.Length), start, len(content))
+	return core.GetConsoleHistoryContentResponse{
+		Value:             common.String(content[start:end]),
+		OpcBytesRemaining: common.Int(len(content) - end),
+	}, nil
+}
+
+func (c *CmpCli) DeleteConsoleHistory(ctx context.Context, request core.DeleteConsoleHistoryRequest) (response core.DeleteConsoleHistoryResponse, err error) {
+	c.ConsoleHistories.Delete(lo.FromPtr(request.InstanceConsoleHistoryId))
+	return core.DeleteConsoleHistoryResponse{}, nil
+}
+
 func (c *CmpCli) Reset() {
 	c.ListImagesOutput.Reset()
 	c.DescribeInstanceTypesOutput.Reset()
@@ -321,9 +550,33 @@ func (c *CmpCli) Reset() {
 	c.GetInstanceBehavior.Reset()
 	c.ListInstanceBehavior.Reset()
 	c.CalledWithListImagesInput.Reset()
+	c.PageSize.Reset()
 	c.Instances.Range(func(k, v any) bool {
 		c.Instances.Delete(k)
 		return true
 	})
+	c.RetryTokens.Range(func(k, v any) bool {
+		c.RetryTokens.Delete(k)
+		return true
+	})
+	c.BootVolumeAttachments.Range(func(k, v any) bool {
+		c.BootVolumeAttachments.Delete(k)
+		return true
+	})
+	c.VolumeAttachments.Range(func(k, v any) bool {
+		c.VolumeAttachments.Delete(k)
+		return true
+	})
 	c.InsufficientCapacityPools.Reset()
+	c.CapacityReservations.Reset()
+	c.CapacityReportBehavior.Reset()
+	c.CaptureConsoleHistoryBehavior.Reset()
+	c.ConsoleOutputs.Range(func(k, v any) bool {
+		c.ConsoleOutputs.Delete(k)
+		return true
+	})
+	c.ConsoleHistories.Range(func(k, v any) bool {
+		c.ConsoleHistories.Delete(k)
+		return true
+	})
 }
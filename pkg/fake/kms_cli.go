@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/oracle/oci-go-sdk/v65/keymanagement"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+)
+
+type KmsCli struct {
+	KmsBehavior
+}
+
+// KmsBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type KmsBehavior struct {
+	Keys              AtomicPtrSlice[keymanagement.Key]
+	CalledWithGetKeys AtomicPtrSlice[keymanagement.GetKeyRequest]
+}
+
+var _ api.KmsManagementClient = &KmsCli{}
+
+func NewKmsCli() *KmsCli {
+	return &KmsCli{}
+}
+
+func (c *KmsCli) GetKey(ctx context.Context, request keymanagement.GetKeyRequest) (response keymanagement.GetKeyResponse, err error) {
+	c.CalledWithGetKeys.Add(&request)
+	var found *keymanagement.Key
+	c.Keys.ForEach(func(key *keymanagement.Key) {
+		if lo.FromPtr(key.Id) == lo.FromPtr(request.KeyId) {
+			found = key
+		}
+	})
+	if found == nil {
+		return keymanagement.GetKeyResponse{RawResponse: &http.Response{StatusCode: http.StatusNotFound}},
+			&FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "key not found"}
+	}
+	return keymanagement.GetKeyResponse{Key: *found}, nil
+}
+
+func (c *KmsCli) Reset() {
+	c.Keys.Reset()
+	c.CalledWithGetKeys.Reset()
+}
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/oracle/oci-go-sdk/v65/limits"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+)
+
+type LimitsCli struct {
+	LimitsBehavior
+}
+
+// LimitsBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type LimitsBehavior struct {
+	LimitValues AtomicPtrSlice[limits.LimitValueSummary]
+	// ResourceAvailabilities key: <limit name>/<availability domain>, the availability domain is empty for the
+	// limits which aren't scoped to an availability domain
+	ResourceAvailabilities            AtomicPtr[map[string]limits.ResourceAvailability]
+	ListLimitValuesError              AtomicError
+	CalledWithListLimitValues         AtomicPtrSlice[limits.ListLimitValuesRequest]
+	CalledWithGetResourceAvailability AtomicPtrSlice[limits.GetResourceAvailabilityRequest]
+}
+
+var _ api.LimitsClient = &LimitsCli{}
+
+func NewLimitsCli() *LimitsCli {
+	return &LimitsCli{}
+}
+
+func (c *LimitsCli) ListLimitValues(ctx context.Context, request limits.ListLimitValuesRequest) (response limits.ListLimitValuesResponse, err error) {
+	c.CalledWithListLimitValues.Add(&request)
+	if err := c.ListLimitValuesError.Get(); err != nil {
+		return limits.ListLimitValuesResponse{RawResponse: &http.Response{StatusCode: http.StatusNotFound}}, err
+	}
+	var items []limits.LimitValueSummary
+	c.LimitValues.ForEach(func(value *limits.LimitValueSummary) {
+		if request.Name == nil || lo.FromPtr(value.Name) == *request.Name {
+			items = append(items, *value)
+		}
+	})
+	return limits.ListLimitValuesResponse{Items: items}, nil
+}
+
+func (c *LimitsCli) GetResourceAvailability(ctx context.Context, request limits.GetResourceAvailabilityRequest) (response limits.GetResourceAvailabilityResponse, err error) {
+	c.CalledWithGetResourceAvailability.Add(&request)
+	if !c.ResourceAvailabilities.IsNil() {
+		key := fmt.Sprintf("%s/%s", lo.FromPtr(request.LimitName), lo.FromPtr(request.AvailabilityDomain))
+		if availability, ok := (*c.ResourceAvailabilities.Clone())[key]; ok {
+			return limits.GetResourceAvailabilityResponse{ResourceAvailability: availability}, nil
+		}
+	}
+	return limits.GetResourceAvailabilityResponse{RawResponse: &http.Response{StatusCode: http.StatusNotFound}},
+		&FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "limit not found"}
+}
+
+func (c *LimitsCli) Reset() {
+	c.LimitValues.Reset()
+	c.ResourceAvailabilities.Reset()
+	c.ListLimitValuesError.Reset()
+	c.CalledWithListLimitValues.Reset()
+	c.CalledWithGetResourceAvailability.Reset()
+}
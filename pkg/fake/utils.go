@@ -16,12 +16,32 @@ package fake
 
 import (
 	"fmt"
+	"strconv"
+
 	"github.com/Pallinder/go-randomdata"
+	"github.com/samber/lo"
 	"github.com/zoom/karpenter-oci/pkg/providers/internalmodel"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 )
 
+// paginate returns the page of the items and the token of the next page, the page token is the offset of the page.
+// All the items are in a single page when the page size isn't set.
+func paginate[T any](items []T, page *string, pageSize *int) ([]T, *string) {
+	if pageSize == nil || *pageSize <= 0 {
+		return items, nil
+	}
+	offset, _ := strconv.Atoi(lo.FromPtr(page))
+	end := min(offset+*pageSize, len(items))
+	if offset >= len(items) {
+		return nil, nil
+	}
+	if end == len(items) {
+		return items[offset:end], nil
+	}
+	return items[offset:end], lo.ToPtr(strconv.Itoa(end))
+}
+
 func InstanceID() string {
 	return fmt.Sprintf("ocid1.instance.oc1.iad.%s", randomdata.Alphanumeric(60))
 }
@@ -46,6 +46,8 @@ type VcnBehavior struct {
 	CreateSubnetResponse           AtomicPtr[core.CreateSubnetResponse]
 	DeleteSubnetResponse           AtomicPtr[core.DeleteSubnetResponse]
 	GetSubnetCidrUtilizationOutput AtomicPtr[map[string]core.GetSubnetCidrUtilizationResponse]
+	// PageSize is the number of the subnets and security groups of a page of the list calls, a single page when nil
+	PageSize AtomicPtr[int]
 }
 
 var DefaultVnics = []core.Vnic{
@@ -119,14 +121,11 @@ func NewVcnCli() *VcnCli {
 func (v *VcnCli) ListSubnets(ctx context.Context, request core.ListSubnetsRequest) (response core.ListSubnetsResponse, err error) {
 	if !v.ListSubnetsOutput.IsNil() {
 		describeSubnetsOutput := v.ListSubnetsOutput.Clone()
-		describeSubnetsOutput.Items = FilterDescribeSubnets(describeSubnetsOutput.Items, *request.DisplayName)
+		describeSubnetsOutput.Items, describeSubnetsOutput.OpcNextPage = paginate(FilterDescribeSubnets(describeSubnetsOutput.Items, request.DisplayName),
+			request.Page, v.PageSize.Clone())
 		return *describeSubnetsOutput, nil
 	}
 
-	if request.DisplayName == nil {
-		return core.ListSubnetsResponse{}, fmt.Errorf("InvalidParameterValue: The filter 'null' is invalid")
-	}
-
 	// Combine default subnets with created subnets
 	allSubnets := make([]core.Subnet, 0)
 	allSubnets = append(allSubnets, DefaultSubnets...)
@@ -134,20 +133,18 @@ func (v *VcnCli) ListSubnets(ctx context.Context, request core.ListSubnetsReques
 		allSubnets = append(allSubnets, subnet)
 	}
 
-	return core.ListSubnetsResponse{Items: FilterDescribeSubnets(allSubnets, *request.DisplayName)}, nil
+	return core.ListSubnetsResponse{Items: FilterDescribeSubnets(allSubnets, request.DisplayName)}, nil
 }
 
 func (v *VcnCli) ListNetworkSecurityGroups(ctx context.Context, request core.ListNetworkSecurityGroupsRequest) (response core.ListNetworkSecurityGroupsResponse, err error) {
 	if !v.ListSecurityGroupOutput.IsNil() {
 		describeSgsOutput := v.ListSecurityGroupOutput.Clone()
-		describeSgsOutput.Items = FilterDescribeSecurityGroups(describeSgsOutput.Items, *request.DisplayName)
+		describeSgsOutput.Items, describeSgsOutput.OpcNextPage = paginate(FilterDescribeSecurityGroups(describeSgsOutput.Items, request.DisplayName),
+			request.Page, v.PageSize.Clone())
 		return *describeSgsOutput, nil
 	}
 
-	if request.DisplayName == nil {
-		return core.ListNetworkSecurityGroupsResponse{}, fmt.Errorf("InvalidParameterValue: The filter 'null' is invalid")
-	}
-	return core.ListNetworkSecurityGroupsResponse{Items: FilterDescribeSecurityGroups(DefaultSecurityGroup, *request.DisplayName)}, nil
+	return core.ListNetworkSecurityGroupsResponse{Items: FilterDescribeSecurityGroups(DefaultSecurityGroup, request.DisplayName)}, nil
 }
 
 func (v *VcnCli) GetSubnet(ctx context.Context, request core.GetSubnetRequest) (response core.GetSubnetResponse, err error) {
@@ -243,15 +240,15 @@ func (v *VcnCli) GetSubnetCidrUtilization(ctx context.Context, request core.GetS
 	}}, nil
 }
 
-func FilterDescribeSecurityGroups(sgs []core.NetworkSecurityGroup, displayName string) []core.NetworkSecurityGroup {
+func FilterDescribeSecurityGroups(sgs []core.NetworkSecurityGroup, displayName *string) []core.NetworkSecurityGroup {
 	return lo.Filter(sgs, func(sg core.NetworkSecurityGroup, _ int) bool {
-		return *sg.DisplayName == displayName
+		return displayName == nil || *sg.DisplayName == *displayName
 	})
 }
 
-func FilterDescribeSubnets(subnets []core.Subnet, name string) []core.Subnet {
+func FilterDescribeSubnets(subnets []core.Subnet, name *string) []core.Subnet {
 	return lo.Filter(subnets, func(subnet core.Subnet, _ int) bool {
-		return *subnet.DisplayName == name
+		return name == nil || *subnet.DisplayName == *name
 	})
 }
 
@@ -353,6 +350,8 @@ func (v *VcnCli) DeleteSubnet(ctx context.Context, request core.DeleteSubnetRequ
 
 func (v *VcnCli) Reset() {
 	v.ListSubnetsOutput.Reset()
+	v.ListSecurityGroupOutput.Reset()
+	v.PageSize.Reset()
 	v.CreateSecurityGroupResponse.Reset()
 	v.DeleteSecurityGroupResponse.Reset()
 	v.CreateSubnetResponse.Reset()
@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/oracle/oci-go-sdk/v65/workrequests"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+)
+
+type WorkRequestCli struct {
+	WorkRequestBehavior
+}
+
+// WorkRequestBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type WorkRequestBehavior struct {
+	ListWorkRequestsBehavior      MockedFunction[workrequests.ListWorkRequestsRequest, workrequests.ListWorkRequestsResponse]
+	ListWorkRequestErrorsBehavior MockedFunction[workrequests.ListWorkRequestErrorsRequest, workrequests.ListWorkRequestErrorsResponse]
+	// WorkRequests are the work requests of the resources, key: resource id, value: []workrequests.WorkRequestSummary
+	WorkRequests sync.Map
+	// WorkRequestErrors are the errors of the work requests, key: work request id, value: []workrequests.WorkRequestError
+	WorkRequestErrors sync.Map
+}
+
+var _ api.WorkRequestClient = &WorkRequestCli{}
+
+func NewWorkRequestCli() *WorkRequestCli {
+	return &WorkRequestCli{}
+}
+
+func (c *WorkRequestCli) ListWorkRequests(ctx context.Context, request workrequests.ListWorkRequestsRequest) (response workrequests.ListWorkRequestsResponse, err error) {
+	ptr, err := c.ListWorkRequestsBehavior.Invoke(&request, func(input *workrequests.ListWorkRequestsRequest) (*workrequests.ListWorkRequestsResponse, error) {
+		items, ok := c.WorkRequests.Load(lo.FromPtr(input.ResourceId))
+		if !ok {
+			return &workrequests.ListWorkRequestsResponse{}, nil
+		}
+		return &workrequests.ListWorkRequestsResponse{Items: items.([]workrequests.WorkRequestSummary)}, nil
+	})
+	if err != nil {
+		return workrequests.ListWorkRequestsResponse{}, err
+	}
+	return *ptr, nil
+}
+
+func (c *WorkRequestCli) ListWorkRequestErrors(ctx context.Context, request workrequests.ListWorkRequestErrorsRequest) (response workrequests.ListWorkRequestErrorsResponse, err error) {
+	ptr, err := c.ListWorkRequestErrorsBehavior.Invoke(&request, func(input *workrequests.ListWorkRequestErrorsRequest) (*workrequests.ListWorkRequestErrorsResponse, error) {
+		items, ok := c.WorkRequestErrors.Load(lo.FromPtr(input.WorkRequestId))
+		if !ok {
+			return &workrequests.ListWorkRequestErrorsResponse{}, nil
+		}
+		return &workrequests.ListWorkRequestErrorsResponse{Items: items.([]workrequests.WorkRequestError)}, nil
+	})
+	if err != nil {
+		return workrequests.ListWorkRequestErrorsResponse{}, err
+	}
+	return *ptr, nil
+}
+
+func (c *WorkRequestCli) Reset() {
+	c.ListWorkRequestsBehavior.Reset()
+	c.ListWorkRequestErrorsBehavior.Reset()
+	c.WorkRequests.Range(func(k, v any) bool {
+		c.WorkRequests.Delete(k)
+		return true
+	})
+	c.WorkRequestErrors.Range(func(k, v any) bool {
+		c.WorkRequestErrors.Delete(k)
+		return true
+	})
+}
@@ -28,5 +28,14 @@ type ComputeClient interface {
 	ListInstances(ctx context.Context, request core.ListInstancesRequest) (response core.ListInstancesResponse, err error)
 	ListShapes(ctx context.Context, request core.ListShapesRequest) (response core.ListShapesResponse, err error)
 	ListVnicAttachments(ctx context.Context, request core.ListVnicAttachmentsRequest) (response core.ListVnicAttachmentsResponse, err error)
+	ListBootVolumeAttachments(ctx context.Context, request core.ListBootVolumeAttachmentsRequest) (response core.ListBootVolumeAttachmentsResponse, err error)
+	ListVolumeAttachments(ctx context.Context, request core.ListVolumeAttachmentsRequest) (response core.ListVolumeAttachmentsResponse, err error)
 	UpdateInstance(ctx context.Context, request core.UpdateInstanceRequest) (response core.UpdateInstanceResponse, err error)
+	ListComputeCapacityReservations(ctx context.Context, request core.ListComputeCapacityReservationsRequest) (response core.ListComputeCapacityReservationsResponse, err error)
+	GetComputeCapacityReservation(ctx context.Context, request core.GetComputeCapacityReservationRequest) (response core.GetComputeCapacityReservationResponse, err error)
+	CreateComputeCapacityReport(ctx context.Context, request core.CreateComputeCapacityReportRequest) (response core.CreateComputeCapacityReportResponse, err error)
+	CaptureConsoleHistory(ctx context.Context, request core.CaptureConsoleHistoryRequest) (response core.CaptureConsoleHistoryResponse, err error)
+	GetConsoleHistory(ctx context.Context, request core.GetConsoleHistoryRequest) (response core.GetConsoleHistoryResponse, err error)
+	GetConsoleHistoryContent(ctx context.Context, request core.GetConsoleHistoryContentRequest) (response core.GetConsoleHistoryContentResponse, err error)
+	DeleteConsoleHistory(ctx context.Context, request core.DeleteConsoleHistoryRequest) (response core.DeleteConsoleHistoryResponse, err error)
 }
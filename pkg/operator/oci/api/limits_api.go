@@ -0,0 +1,26 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+
+	"github.com/oracle/oci-go-sdk/v65/limits"
+)
+
+type LimitsClient interface {
+	ListLimitValues(ctx context.Context, request limits.ListLimitValuesRequest) (response limits.ListLimitValuesResponse, err error)
+	GetResourceAvailability(ctx context.Context, request limits.GetResourceAvailabilityRequest) (response limits.GetResourceAvailabilityResponse, err error)
+}
@@ -22,17 +22,26 @@ import (
 	"github.com/oracle/oci-go-sdk/v65/common/auth"
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/keymanagement"
+	ocilimits "github.com/oracle/oci-go-sdk/v65/limits"
+	"github.com/oracle/oci-go-sdk/v65/workrequests"
 	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
 	"github.com/zoom/karpenter-oci/pkg/operator/oci/config"
 	metadata "github.com/zoom/karpenter-oci/pkg/operator/oci/instance"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityprobe"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityreservation"
+	"github.com/zoom/karpenter-oci/pkg/providers/consolehistory"
 	"github.com/zoom/karpenter-oci/pkg/providers/imagefamily"
 	"github.com/zoom/karpenter-oci/pkg/providers/instance"
 	"github.com/zoom/karpenter-oci/pkg/providers/instancetype"
+	"github.com/zoom/karpenter-oci/pkg/providers/kms"
 	"github.com/zoom/karpenter-oci/pkg/providers/launchtemplate"
+	"github.com/zoom/karpenter-oci/pkg/providers/limits"
 	"github.com/zoom/karpenter-oci/pkg/providers/pricing"
 	"github.com/zoom/karpenter-oci/pkg/providers/securitygroup"
 	"github.com/zoom/karpenter-oci/pkg/providers/subnet"
@@ -41,6 +50,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/transport"
 	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/system"
 	"os"
 	"os/user"
 	oreoperator "sigs.k8s.io/karpenter/pkg/operator"
@@ -61,12 +71,18 @@ const (
 type Operator struct {
 	*oreoperator.Operator
 
-	ImageProvider         *imagefamily.Provider
-	InstanceTypesProvider *instancetype.Provider
-	InstanceProvider      *instance.Provider
-	SubnetProvider        *subnet.Provider
-	SecurityGroupProvider *securitygroup.Provider
-	PricingProvider       pricing.Provider
+	ImageProvider               *imagefamily.Provider
+	InstanceTypesProvider       *instancetype.Provider
+	InstanceProvider            *instance.Provider
+	SubnetProvider              *subnet.Provider
+	SecurityGroupProvider       *securitygroup.Provider
+	PricingProvider             pricing.Provider
+	CapacityReservationProvider *capacityreservation.Provider
+	KmsProvider                 *kms.Provider
+	LimitsProvider              *limits.Provider
+	CapacityProbeProvider       *capacityprobe.Provider
+	ConsoleHistoryProvider      *consolehistory.Provider
+	UnavailableOfferingsCache   *ocicache.UnavailableOfferings
 }
 
 func NewOperator(ctx context.Context, operator *oreoperator.Operator) (context.Context, *Operator) {
@@ -99,23 +115,41 @@ func NewOperator(ctx context.Context, operator *oreoperator.Operator) (context.C
 	region := lo.Must(configProvider.Region())
 	cmpClient := lo.Must(core.NewComputeClientWithConfigurationProvider(configProvider))
 	netClient := lo.Must(core.NewVirtualNetworkClientWithConfigurationProvider(configProvider))
+	blockStorageClient := lo.Must(core.NewBlockstorageClientWithConfigurationProvider(configProvider))
+	workRequestClient := lo.Must(workrequests.NewWorkRequestClientWithConfigurationProvider(configProvider))
 	subnetProvider := subnet.NewProvider(netClient, cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
 	sgProvider := securitygroup.NewProvider(netClient, cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
 	imageProvider := imagefamily.NewProvider(cmpClient, cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
 	imageResolver := imagefamily.NewResolver(imageProvider)
+	capacityReservationProvider := capacityreservation.NewProvider(cmpClient, cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
+	kmsProvider := kms.NewProvider(func(endpoint string) (api.KmsManagementClient, error) {
+		return keymanagement.NewKmsManagementClientWithConfigurationProvider(configProvider, endpoint)
+	}, cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
 	launchProvider := launchtemplate.NewDefaultProvider(imageResolver, lo.Must(GetCABundle(ctx, operator.GetConfig())), options.FromContext(ctx).ClusterEndpoint, options.FromContext(ctx).BootStrapToken)
+	tenancyId := lo.Must(configProvider.TenancyOCID())
+	limitsProvider := limits.NewProvider(lo.Must(ocilimits.NewLimitsClientWithConfigurationProvider(configProvider)),
+		tenancyId, cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
 	unavailableOfferCache := ocicache.NewUnavailableOfferings()
+	capacityProbeProvider := capacityprobe.NewProvider(cmpClient, tenancyId, unavailableOfferCache, cache.New(ocicache.CapacityProbeTTL, ocicache.DefaultCleanupInterval))
 	pricingProvider := pricing.NewDefaultProvider(ctx, options.FromContext(ctx).PriceEndpoint)
-	instanceProvider := instance.NewProvider(cmpClient, subnetProvider, sgProvider, launchProvider, unavailableOfferCache)
-	instancetypeProvider := instancetype.NewProvider(region, cmpClient, cache.New(ocicache.InstanceTypesAndZonesTTL, ocicache.DefaultCleanupInterval), unavailableOfferCache, pricingProvider)
+	instancetypeProvider := instancetype.NewProvider(region, cmpClient, cache.New(ocicache.InstanceTypesAndZonesTTL, ocicache.DefaultCleanupInterval), unavailableOfferCache, pricingProvider, subnetProvider, limitsProvider)
+	consoleHistoryProvider := consolehistory.NewProvider(cmpClient, operator.GetClient(), operator.GetAPIReader(), system.Namespace(),
+		cache.New(ocicache.ConsoleHistoryTTL, ocicache.DefaultCleanupInterval))
+	instanceProvider := instance.NewProvider(cmpClient, blockStorageClient, workRequestClient, subnetProvider, sgProvider, launchProvider, instancetypeProvider, unavailableOfferCache, capacityProbeProvider, operator.GetClient())
 	return ctx, &Operator{
-		Operator:              operator,
-		ImageProvider:         imageProvider,
-		InstanceTypesProvider: instancetypeProvider,
-		InstanceProvider:      instanceProvider,
-		SubnetProvider:        subnetProvider,
-		SecurityGroupProvider: sgProvider,
-		PricingProvider:       pricingProvider,
+		Operator:                    operator,
+		ImageProvider:               imageProvider,
+		InstanceTypesProvider:       instancetypeProvider,
+		InstanceProvider:            instanceProvider,
+		SubnetProvider:              subnetProvider,
+		SecurityGroupProvider:       sgProvider,
+		PricingProvider:             pricingProvider,
+		CapacityReservationProvider: capacityReservationProvider,
+		KmsProvider:                 kmsProvider,
+		LimitsProvider:              limitsProvider,
+		CapacityProbeProvider:       capacityProbeProvider,
+		ConsoleHistoryProvider:      consoleHistoryProvider,
+		UnavailableOfferingsCache:   unavailableOfferCache,
 	}
 }
 
@@ -49,6 +49,10 @@ type Options struct {
 	VMMemoryOverheadPercent  float64
 	FlexCpuMemRatios         string
 	FlexCpuConstrainList     string
+	FlexBurstableBaselines   string
+	MaxLaunchAttempts        int
+	CapacityProbeShapes      string
+	CapacityProbePeriod      int
 	AvailableDomains         []string
 	OciAuthMethods           string
 	PriceEndpoint            string
@@ -79,6 +83,11 @@ func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
 	defaultFlexCpuConstrainList := env.WithDefaultString("FLEX_CPU_CONSTRAIN_LIST", generateDefaultFlexCpuConstrainList())
 	fs.StringVar(&o.FlexCpuConstrainList, "flex-cpu-constrain-list", defaultFlexCpuConstrainList, "to constrain the ocpu cores of flex instance, instance create in this cpu size list, ocpu is twice of vcpu")
 
+	fs.StringVar(&o.FlexBurstableBaselines, "flex-burstable-baselines", env.WithDefaultString("FLEX_BURSTABLE_BASELINES", ""), "the baseline ocpu utilizations of the burstable flex instances, eg FLEX_BURSTABLE_BASELINES=BASELINE_1_8,BASELINE_1_2, burstable instances aren't created if not set")
+	fs.IntVar(&o.MaxLaunchAttempts, "max-launch-attempts", env.WithDefaultInt("MAX_LAUNCH_ATTEMPTS", 3), "the max offerings tried in a single instance launch, the next cheapest offering is launched when the previous one has no capacity")
+	fs.StringVar(&o.CapacityProbeShapes, "capacity-probe-shapes", env.WithDefaultString("CAPACITY_PROBE_SHAPES", ""), "the shape prefixes probed with compute capacity reports before launching, eg CAPACITY_PROBE_SHAPES=BM.,VM.GPU,VM.DenseIO, the capacity isn't probed if not set")
+	fs.IntVar(&o.CapacityProbePeriod, "capacity-probe-period", env.WithDefaultInt("CAPACITY_PROBE_PERIOD", 5), "the minutes between the background capacity probes of the shapes in capacity-probe-shapes")
+
 	fs.StringVar(&o.CompartmentId, "compartment-id", env.WithDefaultString("COMPARTMENT_ID", ""), "[REQUIRED] The compartment id to create and list instances")
 	fs.StringVar(&o.TagNamespace, "tag-namespace", env.WithDefaultString("TAG_NAMESPACE", "oke-karpenter-ns"), "[REQUIRED] The tag namespace used to create and list instances")
 	fs.StringVar(&o.OciAuthMethods, "oci-auth-methods", env.WithDefaultString("OCI_AUTH_METHODS", "OKE"), "[REQUIRED] the auth method to access oracle cloud resource, support OKE,API_KEY,SESSION,INSTANCE_PRINCIPAL")
@@ -16,8 +16,10 @@ package options
 
 import (
 	"fmt"
+	"github.com/oracle/oci-go-sdk/v65/core"
 	"go.uber.org/multierr"
 	"net/url"
+	"strings"
 )
 
 func (o Options) Validate() error {
@@ -25,9 +27,38 @@ func (o Options) Validate() error {
 		o.validateEndpoint(),
 		o.validateVMMemoryOverheadPercent(),
 		o.validateRequiredFields(),
+		o.validateFlexBurstableBaselines(),
+		o.validateMaxLaunchAttempts(),
+		o.validateCapacityProbePeriod(),
 	)
 }
 
+func (o Options) validateFlexBurstableBaselines() error {
+	if o.FlexBurstableBaselines == "" {
+		return nil
+	}
+	for _, baseline := range strings.Split(o.FlexBurstableBaselines, ",") {
+		if baseline != string(core.ShapeBaselineOcpuUtilizations8) && baseline != string(core.ShapeBaselineOcpuUtilizations2) {
+			return fmt.Errorf("%q is not a valid flex-burstable-baselines value, support %s and %s", baseline, core.ShapeBaselineOcpuUtilizations8, core.ShapeBaselineOcpuUtilizations2)
+		}
+	}
+	return nil
+}
+
+func (o Options) validateMaxLaunchAttempts() error {
+	if o.MaxLaunchAttempts < 1 {
+		return fmt.Errorf("max-launch-attempts must be at least 1")
+	}
+	return nil
+}
+
+func (o Options) validateCapacityProbePeriod() error {
+	if o.CapacityProbePeriod < 1 {
+		return fmt.Errorf("capacity-probe-period must be at least 1")
+	}
+	return nil
+}
+
 func (o Options) validateEndpoint() error {
 	if o.ClusterEndpoint == "" {
 		return nil
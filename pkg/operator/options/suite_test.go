@@ -61,7 +61,11 @@ var _ = Describe("Options", func() {
 			"--compartment-id", "ocid1.compartment.oc1..aaaaaaaa",
 			"--vm-memory-overhead-percent", "0.075",
 			"--flex-cpu-mem-ratios", "2,4",
-			"--flex-cpu-constrain-list", "2,4,8")
+			"--flex-cpu-constrain-list", "2,4,8",
+			"--flex-burstable-baselines", "BASELINE_1_8,BASELINE_1_2",
+			"--max-launch-attempts", "5",
+			"--capacity-probe-shapes", "BM.,VM.GPU",
+			"--capacity-probe-period", "10")
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
 			ClusterName:             lo.ToPtr("env-cluster"),
@@ -72,6 +76,10 @@ var _ = Describe("Options", func() {
 			VMMemoryOverheadPercent: lo.ToPtr[float64](0.075),
 			FlexCpuMemRatios:        lo.ToPtr("2,4"),
 			FlexCpuConstrainList:    lo.ToPtr("2,4,8"),
+			FlexBurstableBaselines:  lo.ToPtr("BASELINE_1_8,BASELINE_1_2"),
+			MaxLaunchAttempts:       lo.ToPtr(5),
+			CapacityProbeShapes:     lo.ToPtr("BM.,VM.GPU"),
+			CapacityProbePeriod:     lo.ToPtr(10),
 		}))
 	})
 	It("should correctly fallback to env vars when CLI flags aren't set", func() {
@@ -83,6 +91,10 @@ var _ = Describe("Options", func() {
 		_ = os.Setenv("VM_MEMORY_OVERHEAD_PERCENT", "0.075")
 		_ = os.Setenv("FLEX_CPU_MEM_RATIOS", "2,4")
 		_ = os.Setenv("FLEX_CPU_CONSTRAIN_LIST", "2,4,8")
+		_ = os.Setenv("FLEX_BURSTABLE_BASELINES", "BASELINE_1_2")
+		_ = os.Setenv("MAX_LAUNCH_ATTEMPTS", "2")
+		_ = os.Setenv("CAPACITY_PROBE_SHAPES", "VM.DenseIO")
+		_ = os.Setenv("CAPACITY_PROBE_PERIOD", "15")
 		_ = os.Setenv("AVAILABLE_DOMAIN_PREFIX", "env-prefix")
 
 		// Add flags after we set the environment variables so that the parsing logic correctly refers
@@ -99,6 +111,10 @@ var _ = Describe("Options", func() {
 			VMMemoryOverheadPercent: lo.ToPtr[float64](0.075),
 			FlexCpuMemRatios:        lo.ToPtr("2,4"),
 			FlexCpuConstrainList:    lo.ToPtr("2,4,8"),
+			FlexBurstableBaselines:  lo.ToPtr("BASELINE_1_2"),
+			MaxLaunchAttempts:       lo.ToPtr(2),
+			CapacityProbeShapes:     lo.ToPtr("VM.DenseIO"),
+			CapacityProbePeriod:     lo.ToPtr(15),
 		}))
 	})
 
@@ -118,6 +134,18 @@ var _ = Describe("Options", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vm-memory-overhead-percent", "-0.01")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when flexBurstableBaselines is invalid", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--flex-burstable-baselines", "BASELINE_1_1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when maxLaunchAttempts is less than 1", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-launch-attempts", "0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when capacityProbePeriod is less than 1", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--capacity-probe-period", "0")
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
 
@@ -131,5 +159,9 @@ func expectOptionsEqual(optsA *options.Options, optsB *options.Options) {
 	Expect(optsA.VMMemoryOverheadPercent).To(Equal(optsB.VMMemoryOverheadPercent))
 	Expect(optsA.FlexCpuMemRatios).To(Equal(optsB.FlexCpuMemRatios))
 	Expect(optsA.FlexCpuConstrainList).To(Equal(optsB.FlexCpuConstrainList))
+	Expect(optsA.FlexBurstableBaselines).To(Equal(optsB.FlexBurstableBaselines))
+	Expect(optsA.MaxLaunchAttempts).To(Equal(optsB.MaxLaunchAttempts))
+	Expect(optsA.CapacityProbeShapes).To(Equal(optsB.CapacityProbeShapes))
+	Expect(optsA.CapacityProbePeriod).To(Equal(optsB.CapacityProbePeriod))
 	Expect(optsA.AvailableDomains).To(Equal(optsB.AvailableDomains))
 }
@@ -0,0 +1,251 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityprobe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"go.uber.org/multierr"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+const (
+	// the capacity reports count against the request limits of the compute service, the probes are limited to one per
+	// second with a burst for the launches
+	probesPerSecond = 1
+	probeBurst      = 5
+)
+
+// probe is a shape config probed in a capacity report, the ocpus and memory are zero for the fixed shapes and the
+// dynamic flex shapes which are sized at launch
+type probe struct {
+	shape       string
+	ocpus       float32
+	memoryInGBs float32
+}
+
+func (pr probe) key(zone string) string {
+	return fmt.Sprintf("%s:%s:%g:%g", pr.shape, zone, pr.ocpus, pr.memoryInGBs)
+}
+
+// smaller returns true if the probe is a smaller size of the flex shape than the other probe
+func (pr probe) smaller(other probe) bool {
+	if pr.ocpus != other.ocpus {
+		return pr.ocpus < other.ocpus
+	}
+	return pr.memoryInGBs < other.memoryInGBs
+}
+
+func (pr probe) details() core.CreateCapacityReportShapeAvailabilityDetails {
+	details := core.CreateCapacityReportShapeAvailabilityDetails{InstanceShape: common.String(pr.shape)}
+	if pr.ocpus != 0 {
+		details.InstanceShapeConfig = &core.CapacityReportInstanceShapeConfig{
+			Ocpus:       common.Float32(pr.ocpus),
+			MemoryInGBs: common.Float32(pr.memoryInGBs),
+		}
+	}
+	return details
+}
+
+// newProbe returns the probe of the instance type, the flex instance types are probed with their ocpus and memory
+func newProbe(instanceType *cloudprovider.InstanceType) probe {
+	pr := probe{shape: instanceType.Name}
+	ocpus, memory := instanceType.Requirements.Get(v1alpha1.LabelInstanceOcpus), instanceType.Requirements.Get(v1alpha1.LabelInstanceMemory)
+	if !instanceType.Requirements.Get(v1alpha1.LabelIsFlexible).Has("true") || ocpus.Len() != 1 || memory.Len() != 1 {
+		return pr
+	}
+	ocpuCount, err := strconv.Atoi(ocpus.Any())
+	if err != nil {
+		return pr
+	}
+	memoryInMi, err := strconv.Atoi(memory.Any())
+	if err != nil {
+		return pr
+	}
+	pr.ocpus = float32(ocpuCount)
+	pr.memoryInGBs = float32(memoryInMi / 1024)
+	return pr
+}
+
+type Provider struct {
+	compClient           api.ComputeClient
+	tenancyId            string
+	unavailableOfferings *ocicache.UnavailableOfferings
+	cache                *cache.Cache
+	limiter              *rate.Limiter
+}
+
+func NewProvider(compClient api.ComputeClient, tenancyId string, unavailableOfferings *ocicache.UnavailableOfferings, cache *cache.Cache) *Provider {
+	return &Provider{
+		compClient:           compClient,
+		tenancyId:            tenancyId,
+		unavailableOfferings: unavailableOfferings,
+		cache:                cache,
+		limiter:              rate.NewLimiter(probesPerSecond, probeBurst),
+	}
+}
+
+// Probed returns true if the capacity of the shape is probed before launching, the shapes are matched by the prefixes
+// in capacity-probe-shapes
+func Probed(ctx context.Context, shape string) bool {
+	if options.FromContext(ctx).CapacityProbeShapes == "" {
+		return false
+	}
+	return lo.ContainsBy(strings.Split(options.FromContext(ctx).CapacityProbeShapes, ","), func(prefix string) bool {
+		return prefix != "" && strings.HasPrefix(shape, prefix)
+	})
+}
+
+// Available returns false if the latest capacity report of the instance type in the zone is out of capacity. The
+// capacity is probed when it isn't cached, the launches aren't delayed by the rate limit or a failed probe, so the
+// instance type is available when it can't be probed
+func (p *Provider) Available(ctx context.Context, instanceType *cloudprovider.InstanceType, zone string) bool {
+	if !Probed(ctx, instanceType.Name) {
+		return true
+	}
+	pr := newProbe(instanceType)
+	if available, ok := p.cache.Get(pr.key(zone)); ok {
+		return available.(bool)
+	}
+	if !p.limiter.Allow() {
+		log.FromContext(ctx).V(1).Info("capacity probe is rate limited, skip the probe", "instance-type", instanceType.Name, "zone", zone)
+		return true
+	}
+	reported, err := p.report(ctx, zone, []probe{pr})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to probe the capacity", "instance-type", instanceType.Name, "zone", zone)
+		return true
+	}
+	// a size of a flex shape out of capacity doesn't tell whether its smaller sizes have capacity, the launch moves on
+	// to the next instance type without marking the shape unavailable
+	if pr.ocpus == 0 {
+		p.markUnavailable(ctx, zone, []probe{pr}, reported)
+	}
+	available, ok := reported[pr]
+	return !ok || available
+}
+
+// ProbeAll probes the capacity of the probed instance types in the zones of their on-demand and preemptible offerings,
+// the instance types of a zone are probed in a single capacity report
+func (p *Provider) ProbeAll(ctx context.Context, instanceTypes []*cloudprovider.InstanceType) error {
+	probes := map[string][]probe{}
+	for _, instanceType := range instanceTypes {
+		if !Probed(ctx, instanceType.Name) {
+			continue
+		}
+		pr := newProbe(instanceType)
+		for _, offering := range instanceType.Offerings {
+			zone := offering.Zone()
+			if offering.CapacityType() == corev1.CapacityTypeReserved || lo.Contains(probes[zone], pr) {
+				continue
+			}
+			if _, ok := p.cache.Get(pr.key(zone)); ok {
+				continue
+			}
+			probes[zone] = append(probes[zone], pr)
+		}
+	}
+	zones := lo.Keys(probes)
+	sort.Strings(zones)
+	var errs []error
+	for _, zone := range zones {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		reported, err := p.report(ctx, zone, probes[zone])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.markUnavailable(ctx, zone, probes[zone], reported)
+	}
+	return multierr.Combine(errs...)
+}
+
+// report creates the capacity report of the probes in the zone and caches the reported availabilities
+func (p *Provider) report(ctx context.Context, zone string, probes []probe) (map[probe]bool, error) {
+	ad, ok := lo.Find(options.FromContext(ctx).AvailableDomains, func(item string) bool {
+		return strings.Contains(item, zone)
+	})
+	if !ok {
+		return nil, fmt.Errorf("failed to find a zone for %s, available az: %s", zone, options.FromContext(ctx).AvailableDomains)
+	}
+	// the capacity reports are created in the root compartment
+	resp, err := p.compClient.CreateComputeCapacityReport(ctx, core.CreateComputeCapacityReportRequest{
+		CreateComputeCapacityReportDetails: core.CreateComputeCapacityReportDetails{
+			CompartmentId:       common.String(p.tenancyId),
+			AvailabilityDomain:  common.String(ad),
+			ShapeAvailabilities: lo.Map(probes, func(pr probe, _ int) core.CreateCapacityReportShapeAvailabilityDetails { return pr.details() }),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating the capacity report of %s, %w", ad, err)
+	}
+	reported := map[probe]bool{}
+	for _, item := range resp.ShapeAvailabilities {
+		pr := probe{shape: lo.FromPtr(item.InstanceShape)}
+		if item.InstanceShapeConfig != nil {
+			pr.ocpus, pr.memoryInGBs = lo.FromPtr(item.InstanceShapeConfig.Ocpus), lo.FromPtr(item.InstanceShapeConfig.MemoryInGBs)
+		}
+		// the shape is reported per fault domain, the shape is available when any fault domain has capacity
+		reported[pr] = reported[pr] || item.AvailabilityStatus == core.CapacityReportShapeAvailabilityAvailabilityStatusAvailable
+	}
+	for _, pr := range probes {
+		if available, ok := reported[pr]; ok {
+			p.cache.SetDefault(pr.key(zone), available)
+		}
+	}
+	return reported, nil
+}
+
+// markUnavailable marks the on-demand and preemptible offerings of the shapes unavailable when the smallest reported
+// size of the shape is out of capacity. The offerings are marked by the shape, so the larger sizes of a flex shape
+// which are out of capacity don't hide its smaller sizes which have capacity.
+func (p *Provider) markUnavailable(ctx context.Context, zone string, probes []probe, reported map[probe]bool) {
+	smallest := map[string]probe{}
+	for _, pr := range probes {
+		if _, ok := reported[pr]; !ok {
+			continue
+		}
+		if other, ok := smallest[pr.shape]; !ok || pr.smaller(other) {
+			smallest[pr.shape] = pr
+		}
+	}
+	for _, pr := range smallest {
+		if reported[pr] {
+			continue
+		}
+		log.FromContext(ctx).V(1).Info("capacity report is out of capacity, mark the offerings unavailable", "instance-type", pr.shape,
+			"zone", zone, "ocpus", pr.ocpus, "memory-in-gbs", pr.memoryInGBs)
+		for _, capacityType := range []string{corev1.CapacityTypeOnDemand, v1alpha1.CapacityTypePreemptible} {
+			p.unavailableOfferings.MarkUnavailable(ctx, ocicache.UnavailableReasonCapacityReport, pr.shape, zone, capacityType)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityprobe_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/fake"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityprobe"
+	"github.com/zoom/karpenter-oci/pkg/test"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+)
+
+func instanceType(name string, flexible bool, ocpus, memoryInMi string, zones ...string) *cloudprovider.InstanceType {
+	requirements := scheduling.NewRequirements(
+		scheduling.NewRequirement(v1alpha1.LabelIsFlexible, corev1.NodeSelectorOpIn, lo.Ternary(flexible, "true", "false")),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceOcpus, corev1.NodeSelectorOpIn, ocpus),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceMemory, corev1.NodeSelectorOpIn, memoryInMi),
+	)
+	var offerings cloudprovider.Offerings
+	for _, zone := range zones {
+		for _, capacityType := range []string{karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeReserved} {
+			offerings = append(offerings, &cloudprovider.Offering{
+				Requirements: scheduling.NewRequirements(
+					scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType),
+					scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, zone),
+				),
+				Available: true,
+			})
+		}
+	}
+	return &cloudprovider.InstanceType{Name: name, Requirements: requirements, Offerings: offerings}
+}
+
+func setup(shapes string) (context.Context, *fake.CmpCli, *ocicache.UnavailableOfferings, *capacityprobe.Provider) {
+	ctx := options.ToContext(context.Background(), test.Options(test.OptionsFields{
+		AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2"}, CapacityProbeShapes: lo.ToPtr(shapes)}))
+	cli := fake.NewCmpCli()
+	unavailableOfferings := ocicache.NewUnavailableOfferings()
+	provider := capacityprobe.NewProvider(cli, "ocid1.tenancy.oc1..aaaaaaaa", unavailableOfferings, cache.New(ocicache.CapacityProbeTTL, ocicache.DefaultCleanupInterval))
+	return ctx, cli, unavailableOfferings, provider
+}
+
+func TestProbed(t *testing.T) {
+	ctx, _, _, _ := setup("BM.,VM.GPU")
+	for shape, probed := range map[string]bool{
+		"BM.Standard.E4.128":  true,
+		"VM.GPU.A10.1":        true,
+		"VM.Standard.E4.Flex": false,
+	} {
+		if got := capacityprobe.Probed(ctx, shape); got != probed {
+			t.Errorf("expected probed %t of %s, got %t", probed, shape, got)
+		}
+	}
+	ctx, _, _, _ = setup("")
+	if capacityprobe.Probed(ctx, "BM.Standard.E4.128") {
+		t.Errorf("expected no probed shapes when capacity-probe-shapes isn't set")
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	ctx, cli, unavailableOfferings, provider := setup("BM.,VM.DenseIO")
+	cli.InsufficientCapacityPools.Set([]fake.CapacityPool{{CapacityType: karpv1.CapacityTypeOnDemand, InstanceType: "BM.Standard.E4.128", Zone: "US-ASHBURN-AD-1"}})
+	bm := instanceType("BM.Standard.E4.128", false, "128", "2097152", "US-ASHBURN-AD-1", "US-ASHBURN-AD-2")
+
+	if provider.Available(ctx, bm, "US-ASHBURN-AD-1") {
+		t.Errorf("expected BM.Standard.E4.128 out of capacity in AD-1")
+	}
+	for _, capacityType := range []string{karpv1.CapacityTypeOnDemand, v1alpha1.CapacityTypePreemptible} {
+		if !unavailableOfferings.IsUnavailable("BM.Standard.E4.128", "US-ASHBURN-AD-1", capacityType) {
+			t.Errorf("expected the %s offering of BM.Standard.E4.128 in AD-1 marked unavailable", capacityType)
+		}
+	}
+	if !provider.Available(ctx, bm, "US-ASHBURN-AD-2") {
+		t.Errorf("expected BM.Standard.E4.128 available in AD-2")
+	}
+	// the probes are cached
+	provider.Available(ctx, bm, "US-ASHBURN-AD-1")
+	if cli.CapacityReportBehavior.Calls() != 2 {
+		t.Errorf("expected cached probes, got %d capacity reports", cli.CapacityReportBehavior.Calls())
+	}
+	req := cli.CapacityReportBehavior.CalledWithInput.Pop()
+	if lo.FromPtr(req.CompartmentId) != "ocid1.tenancy.oc1..aaaaaaaa" || lo.FromPtr(req.AvailabilityDomain) != "JPqd:US-ASHBURN-AD-2" {
+		t.Errorf("unexpected capacity report of %s in %s", lo.FromPtr(req.CompartmentId), lo.FromPtr(req.AvailabilityDomain))
+	}
+	// the shapes which aren't probed are available
+	if !provider.Available(ctx, instanceType("VM.Standard.E4.Flex", true, "2", "16384", "US-ASHBURN-AD-1"), "US-ASHBURN-AD-1") {
+		t.Errorf("expected VM.Standard.E4.Flex available")
+	}
+	// the instance type is available when the probe fails
+	cli.CapacityReportBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: "Internal error occurred"})
+	if !provider.Available(ctx, instanceType("VM.DenseIO.E4.Flex", true, "8", "131072", "US-ASHBURN-AD-1"), "US-ASHBURN-AD-1") {
+		t.Errorf("expected VM.DenseIO.E4.Flex available when the probe fails")
+	}
+}
+
+func TestProbeAll(t *testing.T) {
+	ctx, cli, unavailableOfferings, provider := setup("VM.DenseIO")
+	cli.InsufficientCapacityPools.Set([]fake.CapacityPool{{CapacityType: karpv1.CapacityTypeOnDemand, InstanceType: "VM.DenseIO.E4.Flex", Zone: "US-ASHBURN-AD-2"}})
+	instanceTypes := []*cloudprovider.InstanceType{
+		instanceType("VM.DenseIO.E4.Flex", true, "8", "131072", "US-ASHBURN-AD-1", "US-ASHBURN-AD-2"),
+		instanceType("VM.DenseIO.E4.Flex", true, "16", "262144", "US-ASHBURN-AD-1", "US-ASHBURN-AD-2"),
+		instanceType("VM.Standard.E4.Flex", true, "2", "16384", "US-ASHBURN-AD-1", "US-ASHBURN-AD-2"),
+	}
+	if err := provider.ProbeAll(ctx, instanceTypes); err != nil {
+		t.Fatal(err)
+	}
+	// a capacity report per zone with the flex configs of the probed shapes
+	if cli.CapacityReportBehavior.Calls() != 2 {
+		t.Fatalf("expected a capacity report per zone, got %d", cli.CapacityReportBehavior.Calls())
+	}
+	cli.CapacityReportBehavior.CalledWithInput.ForEach(func(req *core.CreateComputeCapacityReportRequest) {
+		if len(req.ShapeAvailabilities) != 2 {
+			t.Errorf("expected 2 shape configs in the capacity report of %s, got %d", lo.FromPtr(req.AvailabilityDomain), len(req.ShapeAvailabilities))
+		}
+		for _, item := range req.ShapeAvailabilities {
+			config := lo.FromPtr(item.InstanceShapeConfig)
+			if lo.FromPtr(item.InstanceShape) != "VM.DenseIO.E4.Flex" || !lo.Contains([]float32{8, 16}, lo.FromPtr(config.Ocpus)) ||
+				lo.FromPtr(config.MemoryInGBs) != lo.FromPtr(config.Ocpus)*16 {
+				t.Errorf("unexpected shape config %s %v %v", lo.FromPtr(item.InstanceShape), lo.FromPtr(config.Ocpus), lo.FromPtr(config.MemoryInGBs))
+			}
+		}
+	})
+	if unavailableOfferings.IsUnavailable("VM.DenseIO.E4.Flex", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) ||
+		!unavailableOfferings.IsUnavailable("VM.DenseIO.E4.Flex", "US-ASHBURN-AD-2", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected VM.DenseIO.E4.Flex unavailable only in AD-2")
+	}
+	// the cached probes aren't reported again
+	if err := provider.ProbeAll(ctx, instanceTypes); err != nil {
+		t.Fatal(err)
+	}
+	if cli.CapacityReportBehavior.Calls() != 2 {
+		t.Errorf("expected cached probes, got %d capacity reports", cli.CapacityReportBehavior.Calls())
+	}
+}
+
+func TestProbeAllFlexSizes(t *testing.T) {
+	ctx, cli, unavailableOfferings, provider := setup("VM.DenseIO")
+	// only the larger size is out of capacity in AD-1, the smaller size is out of capacity in AD-2
+	cli.InsufficientCapacityPools.Set([]fake.CapacityPool{
+		{CapacityType: karpv1.CapacityTypeOnDemand, InstanceType: "VM.DenseIO.E4.Flex", Zone: "US-ASHBURN-AD-1", Ocpus: 16},
+		{CapacityType: karpv1.CapacityTypeOnDemand, InstanceType: "VM.DenseIO.E4.Flex", Zone: "US-ASHBURN-AD-2", Ocpus: 8},
+	})
+	large := instanceType("VM.DenseIO.E4.Flex", true, "16", "262144", "US-ASHBURN-AD-1", "US-ASHBURN-AD-2")
+	if err := provider.ProbeAll(ctx, []*cloudprovider.InstanceType{
+		instanceType("VM.DenseIO.E4.Flex", true, "8", "131072", "US-ASHBURN-AD-1", "US-ASHBURN-AD-2"), large,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if unavailableOfferings.IsUnavailable("VM.DenseIO.E4.Flex", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the smaller size of VM.DenseIO.E4.Flex available in AD-1")
+	}
+	if !unavailableOfferings.IsUnavailable("VM.DenseIO.E4.Flex", "US-ASHBURN-AD-2", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected VM.DenseIO.E4.Flex unavailable in AD-2 where its smallest size is out of capacity")
+	}
+	// the larger size is out of capacity by the cached probe
+	if provider.Available(ctx, large, "US-ASHBURN-AD-1") {
+		t.Errorf("expected the larger size of VM.DenseIO.E4.Flex out of capacity in AD-1")
+	}
+}
+
+func TestAvailableFlexSize(t *testing.T) {
+	ctx, cli, unavailableOfferings, provider := setup("VM.DenseIO")
+	cli.InsufficientCapacityPools.Set([]fake.CapacityPool{{CapacityType: karpv1.CapacityTypeOnDemand, InstanceType: "VM.DenseIO.E4.Flex", Zone: "US-ASHBURN-AD-1"}})
+	if provider.Available(ctx, instanceType("VM.DenseIO.E4.Flex", true, "16", "262144", "US-ASHBURN-AD-1"), "US-ASHBURN-AD-1") {
+		t.Errorf("expected VM.DenseIO.E4.Flex out of capacity in AD-1")
+	}
+	// a single size doesn't tell whether the smaller sizes have capacity
+	if unavailableOfferings.IsUnavailable("VM.DenseIO.E4.Flex", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand) {
+		t.Errorf("expected the offerings of VM.DenseIO.E4.Flex not marked unavailable by a single size")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/utils"
+)
+
+type Provider struct {
+	sync.Mutex
+	client api.ComputeClient
+	cache  *cache.Cache
+}
+
+func NewProvider(client api.ComputeClient, cache *cache.Cache) *Provider {
+	return &Provider{client: client, cache: cache}
+}
+
+func (p *Provider) List(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) ([]core.ComputeCapacityReservation, error) {
+	if len(nodeClass.Spec.CapacityReservationSelector) == 0 {
+		return []core.ComputeCapacityReservation{}, nil
+	}
+	hash, err := hashstructure.Hash(nodeClass.Spec.CapacityReservationSelector, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if reservations, ok := p.cache.Get(fmt.Sprintf("%d", hash)); ok {
+		// shallow-copy of the slice
+		return append([]core.ComputeCapacityReservation{}, reservations.([]core.ComputeCapacityReservation)...), nil
+	}
+	reservations := make(map[string]core.ComputeCapacityReservation, 0)
+	for _, selector := range nodeClass.Spec.CapacityReservationSelector {
+		ids := make([]string, 0)
+		if selector.Id != "" {
+			ids = append(ids, selector.Id)
+		} else {
+			req := core.ListComputeCapacityReservationsRequest{
+				CompartmentId:  common.String(options.FromContext(ctx).CompartmentId),
+				LifecycleState: core.ComputeCapacityReservationLifecycleStateActive,
+			}
+			if selector.Name != "" {
+				req.DisplayName = common.String(selector.Name)
+			}
+			for {
+				resp, err := p.client.ListComputeCapacityReservations(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				for _, summary := range resp.Items {
+					if utils.MatchTags(selector.Tags, summary.FreeformTags, summary.DefinedTags) {
+						ids = append(ids, lo.FromPtr(summary.Id))
+					}
+				}
+				if req.Page = resp.OpcNextPage; req.Page == nil {
+					break
+				}
+			}
+		}
+		// the summary doesn't carry the reserved shapes, get the detail of each reservation
+		for _, id := range ids {
+			if _, ok := reservations[id]; ok {
+				continue
+			}
+			resp, err := p.client.GetComputeCapacityReservation(ctx, core.GetComputeCapacityReservationRequest{CapacityReservationId: common.String(id)})
+			if err != nil {
+				return nil, err
+			}
+			if resp.LifecycleState != core.ComputeCapacityReservationLifecycleStateActive {
+				continue
+			}
+			reservations[id] = resp.ComputeCapacityReservation
+		}
+	}
+	p.cache.SetDefault(fmt.Sprintf("%d", hash), lo.Values(reservations))
+	return lo.Values(reservations), nil
+}
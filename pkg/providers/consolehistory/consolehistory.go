@@ -0,0 +1,231 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consolehistory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+)
+
+const (
+	// DefaultMaxSizeInKiB is the size of the end of the console history which is kept when maxSizeInKiB isn't set
+	DefaultMaxSizeInKiB = 64
+	// ConfigMapPrefix is the prefix of the names of the configmaps which keep the console histories
+	ConfigMapPrefix = "karpenter-oci-console-history-"
+	// ConsoleHistoryKey is the key of the console history in the configmap
+	ConsoleHistoryKey = "console-history.log"
+	// InstanceIdKey is the key of the instance id in the configmap
+	InstanceIdKey = "instance-id"
+	// TruncatedKey is the key of whether the beginning of the console history was dropped in the configmap
+	TruncatedKey = "truncated"
+	// MaxConfigMaps is the number of the newest console histories which are kept, the older configmaps are deleted
+	MaxConfigMaps = 50
+)
+
+var (
+	// PollInterval is the interval of checking whether the console history is captured
+	PollInterval = 2 * time.Second
+	// PollTimeout is the longest time of waiting for the console history to be captured
+	PollTimeout = 30 * time.Second
+)
+
+type Provider struct {
+	compClient api.ComputeClient
+	kubeClient client.Client
+	apiReader  client.Reader
+	namespace  string
+	// cache keeps the instances whose console histories were kept in configmaps
+	cache *cache.Cache
+	// capturing keeps the instances whose console histories are being captured
+	capturing sync.Map
+	wg        sync.WaitGroup
+}
+
+// NewProvider creates the provider, the configmaps are listed with the api reader, so the configmaps aren't watched
+func NewProvider(compClient api.ComputeClient, kubeClient client.Client, apiReader client.Reader, namespace string, cache *cache.Cache) *Provider {
+	return &Provider{
+		compClient: compClient,
+		kubeClient: kubeClient,
+		apiReader:  apiReader,
+		namespace:  namespace,
+		cache:      cache,
+	}
+}
+
+// Capture requests the console history of the instance of the nodeclaim and keeps the end of the history in a
+// configmap in the background, so the deletion of the instance isn't delayed by the capture. The configmap is passed to
+// captured once it's created. The instance is deleted repeatedly until it's terminated, the history isn't requested
+// again while it's being captured or after it's kept, a failed capture is requested again.
+func (p *Provider) Capture(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, nodeClaim *karpv1.NodeClaim, captured func(*corev1.ConfigMap)) error {
+	id := nodeClaim.Status.ProviderID
+	if _, ok := p.cache.Get(id); ok {
+		return nil
+	}
+	if _, loaded := p.capturing.LoadOrStore(id, struct{}{}); loaded {
+		return nil
+	}
+	// the history is requested before the instance is terminated
+	resp, err := p.compClient.CaptureConsoleHistory(ctx, core.CaptureConsoleHistoryRequest{
+		CaptureConsoleHistoryDetails: core.CaptureConsoleHistoryDetails{InstanceId: common.String(id)},
+	})
+	if err != nil {
+		p.capturing.Delete(id)
+		return fmt.Errorf("capturing console history, %w", err)
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.capturing.Delete(id)
+		// the deletion of the nodeclaim doesn't cancel the capture
+		ctx := context.WithoutCancel(ctx)
+		configMap, err := p.keep(ctx, nodeClass, nodeClaim, resp.ConsoleHistory)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to capture the console history")
+			return
+		}
+		p.cache.SetDefault(id, struct{}{})
+		captured(configMap)
+	}()
+	return nil
+}
+
+// Wait blocks until the console histories being captured are kept or failed
+func (p *Provider) Wait() {
+	p.wg.Wait()
+}
+
+// keep reads the end of the captured console history into a configmap, the captured history is deleted after it's read
+func (p *Provider) keep(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, nodeClaim *karpv1.NodeClaim, history core.ConsoleHistory) (*corev1.ConfigMap, error) {
+	defer func() {
+		if _, err := p.compClient.DeleteConsoleHistory(ctx, core.DeleteConsoleHistoryRequest{InstanceConsoleHistoryId: history.Id}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to delete the console history", "console-history-id", lo.FromPtr(history.Id))
+		}
+	}()
+	maxSizeInKiB := int64(DefaultMaxSizeInKiB)
+	if nodeClass.Spec.ConsoleHistory != nil && nodeClass.Spec.ConsoleHistory.MaxSizeInKiB != nil {
+		maxSizeInKiB = *nodeClass.Spec.ConsoleHistory.MaxSizeInKiB
+	}
+	content, truncated, err := p.content(ctx, history, int(maxSizeInKiB*1024))
+	if err != nil {
+		return nil, err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapPrefix + nodeClaim.Name,
+			Namespace: p.namespace,
+			Labels: map[string]string{
+				v1alpha1.LabelConsoleHistory: "true",
+				v1alpha1.LabelNodeClaim:      nodeClaim.Name,
+				v1alpha1.LabelNodeClass:      nodeClass.Name,
+				karpv1.NodePoolLabelKey:      nodeClaim.Labels[karpv1.NodePoolLabelKey],
+			},
+		},
+		Data: map[string]string{
+			InstanceIdKey: nodeClaim.Status.ProviderID,
+			TruncatedKey:  strconv.FormatBool(truncated),
+			// the history is cut at a byte offset, which may split a multibyte character
+			ConsoleHistoryKey: strings.ToValidUTF8(content, ""),
+		},
+	}
+	if err := p.kubeClient.Create(ctx, configMap); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("creating configmap %s, %w", configMap.Name, err)
+	}
+	if err := p.prune(ctx); err != nil {
+		log.FromContext(ctx).Error(err, "failed to prune the console histories")
+	}
+	return configMap, nil
+}
+
+// content waits for the console history to be captured and returns its end of at most maxBytes, and whether the
+// beginning of the history was dropped
+func (p *Provider) content(ctx context.Context, history core.ConsoleHistory, maxBytes int) (string, bool, error) {
+	historyId := history.Id
+	if history.LifecycleState != core.ConsoleHistoryLifecycleStateSucceeded {
+		if err := wait.PollUntilContextTimeout(ctx, PollInterval, PollTimeout, false, func(ctx context.Context) (bool, error) {
+			out, err := p.compClient.GetConsoleHistory(ctx, core.GetConsoleHistoryRequest{InstanceConsoleHistoryId: historyId})
+			if err != nil {
+				return false, err
+			}
+			if out.LifecycleState == core.ConsoleHistoryLifecycleStateFailed {
+				return false, fmt.Errorf("console history %s failed", lo.FromPtr(historyId))
+			}
+			return out.LifecycleState == core.ConsoleHistoryLifecycleStateSucceeded, nil
+		}); err != nil {
+			return "", false, fmt.Errorf("waiting for console history, %w", err)
+		}
+	}
+	out, err := p.compClient.GetConsoleHistoryContent(ctx, core.GetConsoleHistoryContentRequest{
+		InstanceConsoleHistoryId: historyId,
+		Offset:                   common.Int(0),
+		Length:                   common.Int(maxBytes),
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("getting console history content, %w", err)
+	}
+	remaining := lo.FromPtr(out.OpcBytesRemaining)
+	if remaining <= 0 {
+		return lo.FromPtr(out.Value), false, nil
+	}
+	// the history is longer than maxBytes, the end of the history has the latest bootstrap output
+	out, err = p.compClient.GetConsoleHistoryContent(ctx, core.GetConsoleHistoryContentRequest{
+		InstanceConsoleHistoryId: historyId,
+		Offset:                   common.Int(len(lo.FromPtr(out.Value)) + remaining - maxBytes),
+		Length:                   common.Int(maxBytes),
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("getting console history content, %w", err)
+	}
+	return lo.FromPtr(out.Value), true, nil
+}
+
+// prune deletes the oldest configmaps of the console histories beyond MaxConfigMaps
+func (p *Provider) prune(ctx context.Context) error {
+	configMaps := &corev1.ConfigMapList{}
+	if err := p.apiReader.List(ctx, configMaps, client.InNamespace(p.namespace), client.HasLabels{v1alpha1.LabelConsoleHistory}); err != nil {
+		return fmt.Errorf("listing configmaps, %w", err)
+	}
+	if len(configMaps.Items) <= MaxConfigMaps {
+		return nil
+	}
+	sort.Slice(configMaps.Items, func(i, j int) bool {
+		return configMaps.Items[j].CreationTimestamp.Before(&configMaps.Items[i].CreationTimestamp)
+	})
+	for i := range configMaps.Items[MaxConfigMaps:] {
+		configMap := &configMaps.Items[MaxConfigMaps+i]
+		if err := p.kubeClient.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting configmap %s, %w", configMap.Name, err)
+		}
+	}
+	return nil
+}
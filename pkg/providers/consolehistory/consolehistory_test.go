@@ -0,0 +1,227 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consolehistory_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/fake"
+	"github.com/zoom/karpenter-oci/pkg/providers/consolehistory"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+)
+
+func setup(objects ...client.Object) (context.Context, *fake.CmpCli, client.Client, *consolehistory.Provider) {
+	cli := fake.NewCmpCli()
+	// the creation timestamps are set by the api server
+	kubeClient := fakeclient.NewClientBuilder().WithObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			obj.SetCreationTimestamp(metav1.Now())
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	provider := consolehistory.NewProvider(cli, kubeClient, kubeClient, "karpenter", cache.New(ocicache.ConsoleHistoryTTL, ocicache.DefaultCleanupInterval))
+	return context.Background(), cli, kubeClient, provider
+}
+
+func launched(cli *fake.CmpCli, name string, output string) *karpv1.NodeClaim {
+	id := fmt.Sprintf("ocid1.instance.oc1.iad.%s", name)
+	cli.Instances.Store(id, &core.Instance{Id: common.String(id)})
+	cli.ConsoleOutputs.Store(id, output)
+	return &karpv1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{karpv1.NodePoolLabelKey: "default"}},
+		Status:     karpv1.NodeClaimStatus{ProviderID: id},
+	}
+}
+
+func nodeClass(maxSizeInKiB *int64) *v1alpha1.OciNodeClass {
+	return &v1alpha1.OciNodeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1alpha1.OciNodeClassSpec{ConsoleHistory: &v1alpha1.ConsoleHistory{Enabled: true, MaxSizeInKiB: maxSizeInKiB}},
+	}
+}
+
+// capture captures the console history and waits until it's kept, the configmap is nil when it isn't kept
+func capture(ctx context.Context, provider *consolehistory.Provider, nodeClass *v1alpha1.OciNodeClass, nodeClaim *karpv1.NodeClaim) (*corev1.ConfigMap, error) {
+	var configMap *corev1.ConfigMap
+	err := provider.Capture(ctx, nodeClass, nodeClaim, func(captured *corev1.ConfigMap) { configMap = captured })
+	provider.Wait()
+	return configMap, err
+}
+
+func TestCapture(t *testing.T) {
+	ctx, cli, kubeClient, provider := setup()
+	nodeClaim := launched(cli, "nodeclaim-1", "cloud-init: bootstrap failed")
+	configMap, err := capture(ctx, provider, nodeClass(nil), nodeClaim)
+	if err != nil || configMap == nil {
+		t.Fatalf("expected the console history captured, got %v", err)
+	}
+	stored := &corev1.ConfigMap{}
+	if err := kubeClient.Get(ctx, types.NamespacedName{Namespace: "karpenter", Name: configMap.Name}, stored); err != nil {
+		t.Fatalf("expected the configmap created, got %s", err)
+	}
+	if stored.Data[consolehistory.ConsoleHistoryKey] != "cloud-init: bootstrap failed" || stored.Data[consolehistory.TruncatedKey] != "false" {
+		t.Errorf("expected the whole console history kept, got %v", stored.Data)
+	}
+	if stored.Labels[v1alpha1.LabelNodeClaim] != "nodeclaim-1" || stored.Labels[karpv1.NodePoolLabelKey] != "default" {
+		t.Errorf("expected the configmap labeled with the nodeclaim, got %v", stored.Labels)
+	}
+	histories := 0
+	cli.ConsoleHistories.Range(func(_, _ any) bool {
+		histories++
+		return true
+	})
+	if histories != 0 {
+		t.Errorf("expected the console history deleted after it's read, got %d", histories)
+	}
+	// the instance is deleted repeatedly, the console history is captured once
+	if configMap, err := capture(ctx, provider, nodeClass(nil), nodeClaim); err != nil || configMap != nil {
+		t.Errorf("expected the console history not captured again, got %v, %v", configMap, err)
+	}
+	if calls := cli.CaptureConsoleHistoryBehavior.Calls(); calls != 1 {
+		t.Errorf("expected the console history captured once, got %d", calls)
+	}
+}
+
+func TestCaptureTruncated(t *testing.T) {
+	ctx, cli, _, provider := setup()
+	nodeClaim := launched(cli, "nodeclaim-1", strings.Repeat("a", 3000)+"kubelet failed to start")
+	configMap, err := capture(ctx, provider, nodeClass(lo.ToPtr[int64](1)), nodeClaim)
+	if err != nil || configMap == nil {
+		t.Fatalf("expected the console history captured, got %v", err)
+	}
+	content := configMap.Data[consolehistory.ConsoleHistoryKey]
+	if len(content) != 1024 || !strings.HasSuffix(content, "kubelet failed to start") {
+		t.Errorf("expected the last 1KiB of the console history kept, got %d bytes", len(content))
+	}
+	if configMap.Data[consolehistory.TruncatedKey] != "true" {
+		t.Errorf("expected the console history truncated")
+	}
+}
+
+func TestCaptureFailed(t *testing.T) {
+	ctx, cli, kubeClient, provider := setup()
+	nodeClaim := launched(cli, "nodeclaim-1", "")
+	cli.CaptureConsoleHistoryBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: 500, Message: "internal error"})
+	if _, err := capture(ctx, provider, nodeClass(nil), nodeClaim); err == nil {
+		t.Fatalf("expected the capture failed")
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := kubeClient.List(ctx, configMaps); err != nil || len(configMaps.Items) != 0 {
+		t.Errorf("expected no configmap created, got %d, %v", len(configMaps.Items), err)
+	}
+}
+
+func TestCaptureNotKept(t *testing.T) {
+	ctx, cli, _, _ := setup()
+	created := 0
+	// the first configmap isn't created
+	kubeClient := fakeclient.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if created++; created == 1 {
+				return fmt.Errorf("internal error")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	provider := consolehistory.NewProvider(cli, kubeClient, kubeClient, "karpenter", cache.New(ocicache.ConsoleHistoryTTL, ocicache.DefaultCleanupInterval))
+	nodeClaim := launched(cli, "nodeclaim-1", "cloud-init: bootstrap failed")
+	if configMap, err := capture(ctx, provider, nodeClass(nil), nodeClaim); err != nil || configMap != nil {
+		t.Fatalf("expected the console history not kept, got %v, %v", configMap, err)
+	}
+	// the console history which wasn't kept is captured again
+	if configMap, err := capture(ctx, provider, nodeClass(nil), nodeClaim); err != nil || configMap == nil {
+		t.Fatalf("expected the console history captured again, got %v", err)
+	}
+	if calls := cli.CaptureConsoleHistoryBehavior.Calls(); calls != 2 {
+		t.Errorf("expected the console history captured twice, got %d", calls)
+	}
+}
+
+func TestCaptureInProgress(t *testing.T) {
+	interval := consolehistory.PollInterval
+	consolehistory.PollInterval = 10 * time.Millisecond
+	defer func() { consolehistory.PollInterval = interval }()
+	ctx, cli, kubeClient, provider := setup()
+	nodeClaim := launched(cli, "nodeclaim-1", "cloud-init: bootstrap failed")
+	// the console history isn't captured yet, the capture is polled in the background
+	pending := &core.ConsoleHistory{Id: common.String("ocid1.consolehistory.oc1.iad.pending"), InstanceId: common.String(nodeClaim.Status.ProviderID),
+		LifecycleState: core.ConsoleHistoryLifecycleStateRequested}
+	cli.ConsoleHistories.Store(*pending.Id, pending)
+	cli.CaptureConsoleHistoryBehavior.Output.Set(&core.CaptureConsoleHistoryResponse{ConsoleHistory: *pending})
+	for i := 0; i < 2; i++ {
+		if err := provider.Capture(ctx, nodeClass(nil), nodeClaim, func(*corev1.ConfigMap) {}); err != nil {
+			t.Fatalf("expected the console history requested, got %s", err)
+		}
+	}
+	if calls := cli.CaptureConsoleHistoryBehavior.Calls(); calls != 1 {
+		t.Errorf("expected the console history requested once while it's captured, got %d", calls)
+	}
+	cli.ConsoleHistories.Store(*pending.Id, &core.ConsoleHistory{Id: pending.Id, InstanceId: pending.InstanceId,
+		LifecycleState: core.ConsoleHistoryLifecycleStateSucceeded})
+	provider.Wait()
+	if err := kubeClient.Get(ctx, types.NamespacedName{Namespace: "karpenter", Name: consolehistory.ConfigMapPrefix + nodeClaim.Name}, &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the configmap created once the console history is captured, got %s", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	var objects []client.Object
+	for i := 0; i < consolehistory.MaxConfigMaps; i++ {
+		objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("%snodeclaim-old-%d", consolehistory.ConfigMapPrefix, i),
+			Namespace:         "karpenter",
+			Labels:            map[string]string{v1alpha1.LabelConsoleHistory: "true"},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Duration(i+1) * time.Hour)),
+		}})
+	}
+	// the other configmaps aren't pruned
+	objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "karpenter",
+		CreationTimestamp: metav1.NewTime(time.Now().Add(-48 * time.Hour))}})
+	ctx, cli, kubeClient, provider := setup(objects...)
+	if configMap, err := capture(ctx, provider, nodeClass(nil), launched(cli, "nodeclaim-new", "")); err != nil || configMap == nil {
+		t.Fatalf("expected the console history captured, got %v", err)
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := kubeClient.List(ctx, configMaps, client.HasLabels{v1alpha1.LabelConsoleHistory}); err != nil {
+		t.Fatalf("listing configmaps, %s", err)
+	}
+	if len(configMaps.Items) != consolehistory.MaxConfigMaps {
+		t.Errorf("expected %d console histories kept, got %d", consolehistory.MaxConfigMaps, len(configMaps.Items))
+	}
+	if lo.ContainsBy(configMaps.Items, func(cm corev1.ConfigMap) bool {
+		return cm.Name == fmt.Sprintf("%snodeclaim-old-%d", consolehistory.ConfigMapPrefix, consolehistory.MaxConfigMaps-1)
+	}) {
+		t.Errorf("expected the oldest console history pruned")
+	}
+	if err := kubeClient.Get(ctx, types.NamespacedName{Namespace: "karpenter", Name: "other"}, &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the other configmap kept, got %s", err)
+	}
+}
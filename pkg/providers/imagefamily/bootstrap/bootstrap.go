@@ -25,18 +25,28 @@ import (
 	"strings"
 )
 
+const (
+	kubeletRootDir = "/var/lib/kubelet"
+	// instanceStoreDevice is the RAID0 array of the local NVMe disks
+	instanceStoreDevice = "/dev/md/instance-store"
+	// instanceStoreMountPath is the path the RAID0 array is mounted at, the storage directories are bind mounted from it
+	instanceStoreMountPath = "/mnt/instance-store"
+)
+
 // Options is the node bootstrapping parameters passed from Karpenter to the provisioning node
 type Options struct {
-	ClusterName      string
-	ClusterEndpoint  string
-	ClusterDns       string
-	KubeletConfig    *v1alpha1.KubeletConfiguration
-	Taints           []core.Taint      `hash:"set"`
-	Labels           map[string]string `hash:"set"`
-	CABundle         *string
-	BootstrapToken   string
-	CustomUserData   *string
-	PreInstallScript *string
+	ClusterName         string
+	ClusterEndpoint     string
+	ClusterDns          string
+	KubeletConfig       *v1alpha1.KubeletConfiguration
+	Taints              []core.Taint      `hash:"set"`
+	Labels              map[string]string `hash:"set"`
+	CABundle            *string
+	BootstrapToken      string
+	CustomUserData      *string
+	PreInstallScript    *string
+	BlockDevices        []*v1alpha1.VolumeAttributes
+	InstanceStorePolicy *string
 }
 
 func (o Options) kubeletExtraArgs() (args []string) {
@@ -97,6 +107,49 @@ func (o Options) nodeLabelArg() string {
 	return fmt.Sprintf("--node-labels=%q", strings.Join(labelStrings, ","))
 }
 
+// volumeMountScript formats the block devices which have no file system yet and mounts them, it runs before
+// the node is bootstrapped so that the container runtime and kubelet storage can be moved onto the devices. The
+// bootstrap fails when a device never appears rather than the storage being left on the boot volume.
+func (o Options) volumeMountScript() string {
+	var script strings.Builder
+	for _, device := range o.BlockDevices {
+		if device.Device == nil || device.MountPath == nil {
+			continue
+		}
+		fileSystem := lo.Ternary(lo.FromPtr(device.FileSystem) != "", lo.FromPtr(device.FileSystem), v1alpha1.FileSystemExt4)
+		script.WriteString(fmt.Sprintf("for i in $(seq 1 60); do [ -b '%[1]s' ] && break; sleep 5; done\n"+
+			"[ -b '%[1]s' ] || { echo 'block device %[1]s of %[3]s never appeared, the iscsi volumes are logged in by the Block Volume Management plugin of the oracle cloud agent' >&2; exit 1; }\n"+
+			"blkid '%[1]s' || mkfs -t %[2]s '%[1]s'\n"+
+			"mkdir -p '%[3]s'\n"+
+			"mount -t %[2]s '%[1]s' '%[3]s'\n"+
+			"echo '%[1]s %[3]s %[2]s defaults,_netdev,nofail 0 2' >> /etc/fstab\n", *device.Device, fileSystem, *device.MountPath))
+	}
+	return script.String()
+}
+
+// instanceStoreScript assembles the local NVMe disks into a RAID0 array and moves the kubelet and container runtime
+// storage onto it, it runs before the node is bootstrapped like the volume mounts and takes precedence over them
+func (o Options) instanceStoreScript(runtimeDir string) string {
+	if lo.FromPtr(o.InstanceStorePolicy) != v1alpha1.InstanceStorePolicyRAID0 {
+		return ""
+	}
+	return fmt.Sprintf("devices=$(ls /dev/nvme*n1 2>/dev/null || true)\n"+
+		"if [ -n \"$devices\" ]; then\n"+
+		"  if [ ! -e '%[1]s' ]; then\n"+
+		"    mdadm --create --force --run '%[1]s' --level=0 --raid-devices=$(echo $devices | wc -w) $devices\n"+
+		"    mkfs -t %[3]s -F '%[1]s'\n"+
+		"  fi\n"+
+		"  mkdir -p '%[2]s'\n"+
+		"  mount -t %[3]s '%[1]s' '%[2]s'\n"+
+		"  echo \"UUID=$(blkid -s UUID -o value '%[1]s') %[2]s %[3]s defaults,nofail 0 2\" >> /etc/fstab\n"+
+		"  for dir in %[4]s %[5]s; do\n"+
+		"    mkdir -p \"%[2]s$dir\" \"$dir\"\n"+
+		"    mount --bind \"%[2]s$dir\" \"$dir\"\n"+
+		"    echo \"%[2]s$dir $dir none bind,nofail 0 0\" >> /etc/fstab\n"+
+		"  done\n"+
+		"fi\n", instanceStoreDevice, instanceStoreMountPath, v1alpha1.FileSystemExt4, kubeletRootDir, runtimeDir)
+}
+
 // joinParameterArgs joins a map of keys and values by their separator. The separator will sit between the
 // arguments in a comma-separated list i.e. arg1<sep>val1,arg2<sep>val2
 func joinParameterArgs[K comparable, V any](name string, m map[K]V, separator string) string {
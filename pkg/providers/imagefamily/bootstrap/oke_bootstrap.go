@@ -56,6 +56,9 @@ func (e OKE) okeBootstrapScript() string {
 	}
 	var userData bytes.Buffer
 	userData.WriteString("#!/bin/bash -xe\n")
+	userData.WriteString(e.volumeMountScript())
+	// the OKE images run cri-o
+	userData.WriteString(e.instanceStoreScript("/var/lib/containers"))
 	// Due to the way bootstrap.sh is written, parameters should not be passed to it with an equal sign
 	url, _ := url.Parse(e.ClusterEndpoint)
 	userData.WriteString(fmt.Sprintf("bash /etc/oke/oke-install.sh --apiserver-endpoint '%s' %s", url.Hostname(), caBundleArg))
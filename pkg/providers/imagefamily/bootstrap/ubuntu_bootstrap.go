@@ -91,6 +91,8 @@ func (c Ubuntu) customBootstrapScript() (string, error) {
 	userData.WriteString("#!/bin/bash\n")
 	userData.WriteString("mkdir -p \"/etc/self-k8s\"\n")
 	userData.WriteString("mkdir -p \"/etc/kubernetes\"\n")
+	userData.WriteString(c.volumeMountScript())
+	userData.WriteString(c.instanceStoreScript("/var/lib/containerd"))
 	if c.PreInstallScript != nil {
 		if err := createKubeletInstall(&userData, nbv); err != nil {
 			return "", err
@@ -26,7 +26,7 @@ type Custom struct {
 }
 
 // UserData returns the default userdata script for the AMI Family
-func (c Custom) UserData(_ *v1alpha1.KubeletConfiguration, _ []v1.Taint, _ map[string]string, customUserData *string, _ *string) bootstrap.Bootstrapper {
+func (c Custom) UserData(_ *v1alpha1.KubeletConfiguration, _ []v1.Taint, _ map[string]string, customUserData *string, _ *string, _ []*v1alpha1.VolumeAttributes, _ *string) bootstrap.Bootstrapper {
 	return bootstrap.Custom{
 		Options: bootstrap.Options{
 			CustomUserData: customUserData,
@@ -31,6 +31,7 @@ import (
 	"github.com/patrickmn/go-cache"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+	"github.com/zoom/karpenter-oci/pkg/utils"
 )
 
 type Provider struct {
@@ -64,16 +65,26 @@ func (p *Provider) List(ctx context.Context, nodeclass *v1alpha1.OciNodeClass) (
 		if selector.Id == "" {
 			req := core.ListImagesRequest{
 				CompartmentId:  common.String(selector.CompartmentId),
-				DisplayName:    common.String(selector.Name),
 				LifecycleState: core.ImageLifecycleStateAvailable}
-
-			// Send the request using the service client
-			resp, err := p.client.ListImages(ctx, req)
-			if err != nil {
-				return nil, err
+			if selector.Name != "" {
+				req.DisplayName = common.String(selector.Name)
 			}
-			for _, img := range resp.Items {
-				images[lo.FromPtr(img.Id)] = internalmodel.WrapImage{Image: img, Requirements: requirementsForImage(nodeclass.Spec.ImageFamily, img)}
+
+			// the tags are matched on the client side, so all the pages are listed
+			for {
+				resp, err := p.client.ListImages(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				for _, img := range resp.Items {
+					if !utils.MatchTags(selector.Tags, img.FreeformTags, img.DefinedTags) {
+						continue
+					}
+					images[lo.FromPtr(img.Id)] = internalmodel.WrapImage{Image: img, Requirements: requirementsForImage(nodeclass.Spec.ImageFamily, img)}
+				}
+				if req.Page = resp.OpcNextPage; req.Page == nil {
+					break
+				}
 			}
 		} else {
 			req := core.GetImageRequest{
@@ -90,8 +101,9 @@ func (p *Provider) List(ctx context.Context, nodeclass *v1alpha1.OciNodeClass) (
 	return lo.Values(images), nil
 }
 
-// parse the image arch info from name
-// gpu Oracle-Linux-8.10-Gen2-GPU-2025.05.19-0-OKE-1.31.1-764
+// parse the image arch and gpu manufacturer info from name
+// nvidia gpu Oracle-Linux-8.10-Gen2-GPU-2025.05.19-0-OKE-1.31.1-764
+// amd gpu Oracle-Linux-8.10-Gen2-GPU-AMD-2025.05.19-0-OKE-1.31.1-764
 // arm64 Oracle-Linux-8.10-aarch64-2025.05.19-0-OKE-1.31.1-764
 // x86 Oracle-Linux-8.10-2025.05.19-0-OKE-1.31.1-764
 func requirementsForImage(imageFamily string, image core.Image) scheduling.Requirements {
@@ -103,8 +115,22 @@ func requirementsForImage(imageFamily string, image core.Image) scheduling.Requi
 		arch = karpv1.ArchitectureArm64
 	}
 	requires := scheduling.NewRequirements(scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, arch))
-	if strings.Contains(strings.ToLower(lo.FromPtr(image.DisplayName)), "gpu") {
-		requires.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceGPU, corev1.NodeSelectorOpExists))
+	if manufacturer, ok := gpuManufacturer(lo.FromPtr(image.DisplayName)); ok {
+		requires.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceGPUManufacturer, corev1.NodeSelectorOpIn, manufacturer))
 	}
 	return requires
 }
+
+// gpuManufacturer returns the manufacturer of the gpus which the drivers of the image are built for, the gpu images
+// without a manufacturer in the name are the NVIDIA images
+func gpuManufacturer(displayName string) (string, bool) {
+	words := strings.FieldsFunc(strings.ToLower(displayName), func(r rune) bool { return r == '-' || r == '_' || r == ' ' })
+	gpu := lo.Contains(words, "gpu")
+	switch {
+	case lo.Contains(words, "rocm") || (gpu && lo.Contains(words, utils.GPUManufacturerAMD)):
+		return utils.GPUManufacturerAMD, true
+	case gpu || lo.Contains(words, "cuda") || lo.Contains(words, utils.GPUManufacturerNVIDIA):
+		return utils.GPUManufacturerNVIDIA, true
+	}
+	return "", false
+}
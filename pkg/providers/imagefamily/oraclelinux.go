@@ -25,19 +25,21 @@ type OracleOKELinux struct {
 	*Options
 }
 
-func (a OracleOKELinux) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []v1.Taint, labels map[string]string, customUserData *string, preInstallScript *string) bootstrap.Bootstrapper {
+func (a OracleOKELinux) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []v1.Taint, labels map[string]string, customUserData *string, preInstallScript *string, blockDevices []*v1alpha1.VolumeAttributes, instanceStorePolicy *string) bootstrap.Bootstrapper {
 	return bootstrap.OKE{
 		Options: bootstrap.Options{
-			ClusterName:      a.ClusterName,
-			ClusterEndpoint:  a.ClusterEndpoint,
-			ClusterDns:       a.ClusterDns,
-			CABundle:         a.CABundle,
-			BootstrapToken:   a.BootstrapToken,
-			KubeletConfig:    kubeletConfig,
-			Taints:           taints,
-			Labels:           labels,
-			CustomUserData:   customUserData,
-			PreInstallScript: preInstallScript,
+			ClusterName:         a.ClusterName,
+			ClusterEndpoint:     a.ClusterEndpoint,
+			ClusterDns:          a.ClusterDns,
+			CABundle:            a.CABundle,
+			BootstrapToken:      a.BootstrapToken,
+			KubeletConfig:       kubeletConfig,
+			Taints:              taints,
+			Labels:              labels,
+			CustomUserData:      customUserData,
+			PreInstallScript:    preInstallScript,
+			BlockDevices:        blockDevices,
+			InstanceStorePolicy: instanceStorePolicy,
 		},
 	}
 }
@@ -67,7 +67,7 @@ type LaunchTemplate struct {
 type DefaultFamily struct{}
 
 type ImageFamily interface {
-	UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []core.Taint, labels map[string]string, customUserData *string, preInstallScript *string) bootstrap.Bootstrapper
+	UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []core.Taint, labels map[string]string, customUserData *string, preInstallScript *string, blockDevices []*v1alpha1.VolumeAttributes, instanceStorePolicy *string) bootstrap.Bootstrapper
 }
 
 func (r Resolver) Resolve(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, nodeClaim *v1.NodeClaim, instanceType *cloudprovider.InstanceType, options *Options) ([]*LaunchTemplate, error) {
@@ -138,6 +138,8 @@ func (r Resolver) resolveLaunchTemplate(nodeClass *v1alpha1.OciNodeClass, nodeCl
 			options.Labels,
 			nodeClass.Spec.UserData,
 			nodeClass.Spec.PreInstallScript,
+			nodeClass.Spec.BlockDevices,
+			nodeClass.Spec.InstanceStorePolicy,
 		),
 		ImageId: imageId,
 	}
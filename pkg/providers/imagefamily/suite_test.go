@@ -20,10 +20,12 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/samber/lo"
 	"github.com/zoom/karpenter-oci/pkg/apis"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
 	"github.com/zoom/karpenter-oci/pkg/test"
+	"github.com/zoom/karpenter-oci/pkg/utils"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 	. "sigs.k8s.io/karpenter/pkg/utils/testing"
@@ -86,4 +88,76 @@ var _ = Describe("AMIProvider", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(amis).To(HaveLen(1))
 	})
+	It("should resolve images by tags", func() {
+		ociEnv.CmpCli.ListImagesOutput.Set(&core.ListImagesResponse{
+			Items: []core.Image{
+				{
+					Id:             common.String("ocid1.image.oc1.iad.aaaaaaaa"),
+					LifecycleState: core.ImageLifecycleStateAvailable,
+					DisplayName:    common.String("Oracle-Linux-8.9-2024.01.26-0-OKE-1.27.10-679"),
+					FreeformTags:   map[string]string{"team": "platform"},
+				},
+				{
+					Id:             common.String("ocid1.image.oc1.iad.aaaaaaab"),
+					LifecycleState: core.ImageLifecycleStateAvailable,
+					DisplayName:    common.String("Oracle-Linux-8.10-2025.05.19-0-OKE-1.31.1-764"),
+					DefinedTags:    map[string]map[string]interface{}{"images": {"release": "stable"}},
+				},
+			},
+		})
+		nodeClass.Spec.ImageSelector = []v1alpha1.ImageSelectorTerm{{Tags: map[string]string{"images.release": "stable"}}}
+		amis, err := ociEnv.AMIProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(amis).To(HaveLen(1))
+		Expect(lo.FromPtr(amis[0].Image.Id)).To(Equal("ocid1.image.oc1.iad.aaaaaaab"))
+	})
+	It("should resolve images by tags on the next pages", func() {
+		ociEnv.CmpCli.ListImagesOutput.Set(&core.ListImagesResponse{
+			Items: []core.Image{
+				{
+					Id:             common.String("ocid1.image.oc1.iad.aaaaaaaa"),
+					LifecycleState: core.ImageLifecycleStateAvailable,
+					DisplayName:    common.String("Oracle-Linux-8.9-2024.01.26-0-OKE-1.27.10-679"),
+				},
+				{
+					Id:             common.String("ocid1.image.oc1.iad.aaaaaaab"),
+					LifecycleState: core.ImageLifecycleStateAvailable,
+					DisplayName:    common.String("Oracle-Linux-8.10-2025.05.19-0-OKE-1.31.1-764"),
+					FreeformTags:   map[string]string{"team": "platform"},
+				},
+			},
+		})
+		ociEnv.CmpCli.PageSize.Set(lo.ToPtr(1))
+		nodeClass.Spec.ImageSelector = []v1alpha1.ImageSelectorTerm{{Tags: map[string]string{"team": "platform"}}}
+		amis, err := ociEnv.AMIProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(amis).To(HaveLen(1))
+		Expect(lo.FromPtr(amis[0].Image.Id)).To(Equal("ocid1.image.oc1.iad.aaaaaaab"))
+		Expect(ociEnv.CmpCli.CalledWithListImagesInput.Len()).To(Equal(2))
+	})
+	It("should require the gpu manufacturer of the gpu images", func() {
+		images := map[string]string{
+			"Oracle-Linux-8.10-Gen2-GPU-2025.05.19-0-OKE-1.31.1-764":     utils.GPUManufacturerNVIDIA,
+			"Oracle-Linux-8.10-Gen2-GPU-AMD-2025.05.19-0-OKE-1.31.1-764": utils.GPUManufacturerAMD,
+			"Oracle-Linux-8.10-2025.05.19-0-OKE-1.31.1-764":              "",
+		}
+		ociEnv.CmpCli.ListImagesOutput.Set(&core.ListImagesResponse{
+			Items: lo.MapToSlice(images, func(name string, _ string) core.Image {
+				return core.Image{Id: common.String("ocid1.image.oc1.iad." + name), LifecycleState: core.ImageLifecycleStateAvailable, DisplayName: common.String(name)}
+			}),
+		})
+		nodeClass.Spec.ImageFamily = v1alpha1.OracleOKELinuxImageFamily
+		nodeClass.Spec.ImageSelector = []v1alpha1.ImageSelectorTerm{{CompartmentId: "ocid1.compartment.oc1..aaaaaaaa"}}
+		amis, err := ociEnv.AMIProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(amis).To(HaveLen(3))
+		for _, ami := range amis {
+			manufacturer := images[lo.FromPtr(ami.Image.DisplayName)]
+			if manufacturer == "" {
+				Expect(ami.Requirements.Has(v1alpha1.LabelInstanceGPUManufacturer)).To(BeFalse())
+				continue
+			}
+			Expect(ami.Requirements.Get(v1alpha1.LabelInstanceGPUManufacturer).Values()).To(ConsistOf(manufacturer))
+		}
+	})
 })
@@ -18,22 +18,26 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/mitchellh/hashstructure/v2"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/oracle/oci-go-sdk/v65/workrequests"
 	"github.com/samber/lo"
-	"github.com/samber/lo/mutable"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/cache"
 	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityprobe"
+	"github.com/zoom/karpenter-oci/pkg/providers/instancetype"
 	"github.com/zoom/karpenter-oci/pkg/providers/launchtemplate"
 	"github.com/zoom/karpenter-oci/pkg/providers/securitygroup"
 	"github.com/zoom/karpenter-oci/pkg/providers/subnet"
 	"github.com/zoom/karpenter-oci/pkg/utils"
-	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	corev1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -42,36 +46,154 @@ import (
 
 type Provider struct {
 	compClient             api.ComputeClient
+	blockStorageClient     api.BlockStorageClient
+	workRequestClient      api.WorkRequestClient
 	subnetProvider         *subnet.Provider
 	securityGroupProvider  *securitygroup.Provider
 	launchTemplateProvider *launchtemplate.DefaultProvider
+	instanceTypeProvider   *instancetype.Provider
 	unavailableOfferings   *cache.UnavailableOfferings
+	capacityProbeProvider  *capacityprobe.Provider
+	kubeClient             client.Client
+	launchingZones         *launchingZones
+	launchThrottle         *launchThrottle
 }
 
-const Gi = 1024 * 1024 * 1024
+const (
+	Gi = 1024 * 1024 * 1024
+	// blockVolumeManagementPlugin is the oracle cloud agent plugin which logs in the iscsi volume attachments
+	blockVolumeManagementPlugin = "Block Volume Management"
+)
 
-func NewProvider(compClient api.ComputeClient, subnetProvider *subnet.Provider, securityGroupProvider *securitygroup.Provider, launchProvider *launchtemplate.DefaultProvider, unavailableOfferings *cache.UnavailableOfferings) *Provider {
+func NewProvider(compClient api.ComputeClient, blockStorageClient api.BlockStorageClient, workRequestClient api.WorkRequestClient, subnetProvider *subnet.Provider, securityGroupProvider *securitygroup.Provider, launchProvider *launchtemplate.DefaultProvider, instanceTypeProvider *instancetype.Provider, unavailableOfferings *cache.UnavailableOfferings, capacityProbeProvider *capacityprobe.Provider, kubeClient client.Client) *Provider {
 	return &Provider{
 		compClient:             compClient,
+		blockStorageClient:     blockStorageClient,
+		workRequestClient:      workRequestClient,
 		subnetProvider:         subnetProvider,
 		securityGroupProvider:  securityGroupProvider,
 		launchTemplateProvider: launchProvider,
+		instanceTypeProvider:   instanceTypeProvider,
 		unavailableOfferings:   unavailableOfferings,
+		capacityProbeProvider:  capacityProbeProvider,
+		kubeClient:             kubeClient,
+		launchingZones:         newLaunchingZones(),
+		launchThrottle:         newLaunchThrottle(),
 	}
 }
 
+// Reset releases the launches held by the throttled launch requests
+func (p *Provider) Reset() {
+	p.launchThrottle.reset()
+}
+
 func (p *Provider) Create(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, nodeClaim *corev1.NodeClaim, instanceTypes []*corecloudprovider.InstanceType) (*core.Instance, error) {
-	subnet, count, err := p.FindLeastUtilizedSubnet(ctx, nodeClass)
+	// the instance may be launched by a previous create which failed before the nodeclaim was updated
+	if instance, err := p.findNodeClaimInstance(ctx, nodeClaim); err != nil || instance != nil {
+		return instance, err
+	}
+	// the launches are held while the launch requests of the tenancy are throttled
+	if wait := p.launchThrottle.remaining(); wait > 0 {
+		return nil, corecloudprovider.NewCreateError(fmt.Errorf("launch requests are throttled, retry in %s", wait),
+			string(utils.OciErrorThrottled), "launch requests are throttled")
+	}
+	// the IPs reserved for the launching instances aren't available
+	subnets, err := p.listSubnetIPs(ctx, nodeClass, p.subnetProvider.AvailableIPs)
 	if err != nil {
 		return nil, err
 	}
-	if nodeClaim != nil && nodeClaim.Spec.Resources.Requests.Pods().Value() > int64(count) {
-		return nil, fmt.Errorf("not enough IPs are available on all subnets")
+	// no instance type can be launched when the subnets can't fit the IPs of the smallest one, the nodeclaim is
+	// scheduled again with the nodeclasses and nodepools which may have IPs
+	requiredIPs := max(lo.Min(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) int {
+		return instancetype.RequiredIPs(nodeClass, it.Capacity.Pods().Value())
+	})), 1)
+	if _, count := leastUtilizedSubnet(subnets); count < requiredIPs {
+		return nil, corecloudprovider.NewInsufficientCapacityError(fmt.Errorf("not enough IPs are available on all subnets %s, required IPs: %d",
+			strings.Join(lo.Map(subnets, func(s subnetIPs, _ int) string { return lo.FromPtr(s.subnet.Id) }), ", "), requiredIPs))
 	}
 	sgsIds := lo.Map[*v1alpha1.SecurityGroup, string](nodeClass.Status.SecurityGroups, func(item *v1alpha1.SecurityGroup, index int) string {
 		return item.Id
 	})
-	instanceType, zone := pickBestInstanceType(nodeClaim, instanceTypes)
+	stats, err := p.zoneStats(ctx, nodeClaim, subnets)
+	if err != nil {
+		return nil, err
+	}
+	instanceTypes = p.fitFlexInstanceTypes(ctx, nodeClass, nodeClaim, instanceTypes)
+	// the offering which is out of capacity is marked unavailable, then the next cheapest offering is launched
+	// until the launch attempts run out
+	var launchErr error
+	for attempt := 1; attempt <= options.FromContext(ctx).MaxLaunchAttempts; attempt++ {
+		// reserved capacity is preferred, fallback to on-demand or preemptible when no reservation can serve the nodeclaim
+		instanceType, offering := p.pickReservedInstanceType(nodeClass, nodeClaim, instanceTypes, stats)
+		if offering == nil {
+			instanceType, offering = p.pickBestInstanceType(nodeClass, nodeClaim, instanceTypes, stats)
+		}
+		if instanceType == nil {
+			if launchErr != nil {
+				return nil, launchErr
+			}
+			log.FromContext(ctx).V(1).Error(corecloudprovider.NewInsufficientCapacityError(fmt.Errorf("no instance types available")), "")
+			return nil, corecloudprovider.NewInsufficientCapacityError(fmt.Errorf("no instance types available"))
+		}
+		// the scarce shapes are probed before launching, the fixed shapes out of capacity are marked unavailable by the probe
+		if offering.CapacityType() != corev1.CapacityTypeReserved && !p.capacityProbeProvider.Available(ctx, instanceType, offering.Zone()) {
+			launchErr = corecloudprovider.NewInsufficientCapacityError(fmt.Errorf("capacity report of %s in %s is out of capacity", instanceType.Name, offering.Zone()))
+			// the sizes of a flex shape share the unavailable offerings of the shape, the size out of capacity in the zone
+			// isn't picked again
+			instanceTypes = lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) *corecloudprovider.InstanceType {
+				return lo.Ternary(it == instanceType, unavailableInZone(it, offering.Zone()), it)
+			})
+			log.FromContext(ctx).V(1).Info("capacity probe is out of capacity, try the next cheapest offering", "attempt", attempt,
+				"instance-type", instanceType.Name, "zone", offering.Zone(), "capacity-type", offering.CapacityType())
+			if zs, ok := stats[offering.Zone()]; ok {
+				zs.unavailableOfferings++
+			}
+			continue
+		}
+		// the subnet must be in the zone of the instance
+		subnet := stats[offering.Zone()].subnet.subnet
+		release := p.launchingZones.track(nodeClaim.Labels[corev1.NodePoolLabelKey], offering.Zone())
+		releaseIPs := p.subnetProvider.ReserveIPs(lo.FromPtr(subnet.Id), instancetype.RequiredIPs(nodeClass, instanceType.Capacity.Pods().Value()))
+		instance, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceType, offering, subnet, sgsIds, launchRetryToken(nodeClaim, instanceType, offering))
+		releaseIPs(err == nil)
+		release()
+		if err == nil || !corecloudprovider.IsInsufficientCapacityError(err) {
+			return instance, err
+		}
+		log.FromContext(ctx).V(1).Info("instance launch is out of capacity, try the next cheapest offering", "attempt", attempt,
+			"instance-type", instanceType.Name, "zone", offering.Zone(), "capacity-type", offering.CapacityType(), "error", err)
+		launchErr = err
+		// the zone is less preferred for the next attempt
+		if zs, ok := stats[offering.Zone()]; ok {
+			zs.unavailableOfferings++
+		}
+	}
+	return nil, launchErr
+}
+
+// fitFlexInstanceTypes sizes the dynamic flex shapes to fit the resource requests of the nodeclaim before an instance
+// type is picked, so the sizes are launched and ordered by their prices. The shapes which can't fit are skipped, otherwise
+// the same shape would be picked again by every launch attempt.
+func (p *Provider) fitFlexInstanceTypes(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, nodeClaim *corev1.NodeClaim,
+	instanceTypes []*corecloudprovider.InstanceType) []*corecloudprovider.InstanceType {
+	return lo.FilterMap(instanceTypes, func(instanceType *corecloudprovider.InstanceType, _ int) (*corecloudprovider.InstanceType, bool) {
+		if !instancetype.IsDynamicFlex(instanceType) {
+			return instanceType, true
+		}
+		fitted, err := p.instanceTypeProvider.FitFlexInstanceType(ctx, nodeClass, instanceType, nodeClaim)
+		if err != nil {
+			log.FromContext(ctx).V(1).Info("skipping flex shape which can't fit the nodeclaim", "instance-type", instanceType.Name, "error", err)
+			return nil, false
+		}
+		return fitted, true
+	})
+}
+
+// launchInstance launches the instance type in the zone and with the capacity type of the offering, the offering is
+// marked unavailable when the launch is out of capacity
+func (p *Provider) launchInstance(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, nodeClaim *corev1.NodeClaim, instanceType *corecloudprovider.InstanceType,
+	offering *corecloudprovider.Offering, subnet *core.Subnet, sgsIds []string, retryToken *string) (*core.Instance, error) {
+	zone, capacityType := offering.Zone(), offering.CapacityType()
 	ad, ok := lo.Find(options.FromContext(ctx).AvailableDomains, func(item string) bool {
 		return strings.Contains(item, zone)
 	})
@@ -79,15 +201,9 @@ func (p *Provider) Create(ctx context.Context, nodeClass *v1alpha1.OciNodeClass,
 		log.FromContext(ctx).V(1).Error(fmt.Errorf("failed to find a zone for %s, available az: %s", zone, options.FromContext(ctx).AvailableDomains), "")
 		return nil, fmt.Errorf("failed to find a zone for %s, available az: %s", zone, options.FromContext(ctx).AvailableDomains)
 	}
-	if instanceType == nil {
-		log.FromContext(ctx).V(1).Error(corecloudprovider.NewInsufficientCapacityError(fmt.Errorf("no instance types available")), "")
-		return nil, corecloudprovider.NewInsufficientCapacityError(fmt.Errorf("no instance types available"))
-	}
 	blockDevices := lo.Map[*v1alpha1.VolumeAttributes, core.LaunchAttachVolumeDetails](nodeClass.Spec.BlockDevices,
 		func(item *v1alpha1.VolumeAttributes, index int) core.LaunchAttachVolumeDetails {
-			return core.LaunchAttachIScsiVolumeDetails{
-				LaunchCreateVolumeDetails: core.LaunchCreateVolumeFromAttributes{VpusPerGB: common.Int64(item.VpusPerGB),
-					SizeInGBs: common.Int64(item.SizeInGBs)}}
+			return launchAttachVolumeDetails(item, index)
 		})
 	template, err := p.launchTemplateProvider.CreateLaunchTemplate(ctx, nodeClass, nodeClaim, instanceType)
 	if err != nil {
@@ -110,27 +226,29 @@ func (p *Provider) Create(ctx context.Context, nodeClass *v1alpha1.OciNodeClass,
 		return nil, err
 	}
 	metadata["user_data"] = userdata
-	// Determine capacity type based on node requirements
-	capacityType := corev1.CapacityTypeOnDemand
-	nodeReqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
-	if nodeReqs.Get(corev1.CapacityTypeLabelKey).Has(v1alpha1.CapacityTypePreemptible) {
-		capacityType = v1alpha1.CapacityTypePreemptible
-	}
 	req := core.LaunchInstanceRequest{LaunchInstanceDetails: core.LaunchInstanceDetails{
 		CreateVnicDetails:       &core.CreateVnicDetails{SubnetId: subnet.Id, NsgIds: sgsIds},
 		LaunchVolumeAttachments: blockDevices,
 		SourceDetails: core.InstanceSourceViaImageDetails{
 			ImageId:             common.String(template[0].ImageId),
 			BootVolumeVpusPerGB: common.Int64(nodeClass.Spec.BootConfig.BootVolumeVpusPerGB),
-			BootVolumeSizeInGBs: common.Int64(nodeClass.Spec.BootConfig.BootVolumeSizeInGBs)},
+			BootVolumeSizeInGBs: common.Int64(nodeClass.Spec.BootConfig.BootVolumeSizeInGBs),
+			KmsKeyId:            nodeClass.Spec.BootConfig.KmsKeyId},
 		DefinedTags:        getTags(ctx, nodeClass, nodeClaim),
 		CompartmentId:      common.String(options.FromContext(ctx).CompartmentId),
 		DisplayName:        common.String(nodeClaim.Name),
 		AvailabilityDomain: common.String(ad),
+		FaultDomain:        lo.EmptyableToPtr(pickFaultDomain(nodeClaim)),
 		Shape:              common.String(instanceType.Name),
 		Metadata:           metadata,
 		InstanceOptions:    &core.InstanceOptions{AreLegacyImdsEndpointsDisabled: common.Bool(true)},
-	}}
+	}, OpcRetryToken: retryToken}
+	if lo.FromPtr(nodeClass.Spec.BootConfig.IsPvEncryptionInTransitEnabled) {
+		req.IsPvEncryptionInTransitEnabled = common.Bool(true)
+	}
+	if capacityType == corev1.CapacityTypeReserved {
+		req.CapacityReservationId = common.String(offering.ReservationID())
+	}
 	// Set preemptible flag if needed
 	if capacityType == v1alpha1.CapacityTypePreemptible {
 		req.PreemptibleInstanceConfig = &core.PreemptibleInstanceConfigDetails{
@@ -139,10 +257,18 @@ func (p *Provider) Create(ctx context.Context, nodeClass *v1alpha1.OciNodeClass,
 			},
 		}
 	}
+	// the platform config type must match the platform of the shape
+	if nodeClass.Spec.PlatformConfig != nil {
+		shape, err := p.instanceTypeProvider.GetShape(ctx, instanceType.Name)
+		if err != nil {
+			return nil, err
+		}
+		req.PlatformConfig = launchPlatformConfig(*shape, nodeClass.Spec.PlatformConfig)
+	}
 
 	// for flexible instance, specify the ocpu and memory
 	if instanceType.Requirements.Get(v1alpha1.LabelIsFlexible).Has("true") {
-		vcpu, err := getIntValFromRequirements(v1alpha1.LabelInstanceCPU, instanceType.Requirements)
+		ocpus, err := getIntValFromRequirements(v1alpha1.LabelInstanceOcpus, instanceType.Requirements)
 		if err != nil {
 			return nil, fmt.Errorf("failed to calculate cpu flex instance when creating instance, nodecliam: %s, err: %s", nodeClaim.Name, err.Error())
 		}
@@ -150,14 +276,14 @@ func (p *Provider) Create(ctx context.Context, nodeClass *v1alpha1.OciNodeClass,
 		if err != nil {
 			return nil, fmt.Errorf("failed to calculate memory for flex instance when creating instance, nodecliam: %s, err: %s", nodeClaim.Name, err.Error())
 		}
-		// Determine if it's an A1 shape (1 OCPU = 1 vCPU), otherwise assume 1 OCPU = 2 vCPU
-		ocpus := float32(vcpu)
-		if !utils.IsA1FlexShape(instanceType.Name) {
-			ocpus = ocpus / 2.0
-		}
 		req.ShapeConfig = &core.LaunchInstanceShapeConfigDetails{
 			MemoryInGBs: common.Float32(float32(memoryInMi / 1024)),
 			Ocpus:       common.Float32(float32(ocpus))}
+		// burstable instance, only the baseline of the ocpus is guaranteed
+		if baseline := instanceType.Requirements.Get(v1alpha1.LabelInstanceBaselineOcpuUtilization).Any(); baseline != "" &&
+			baseline != string(core.ShapeBaselineOcpuUtilizations1) {
+			req.ShapeConfig.BaselineOcpuUtilization = core.LaunchInstanceShapeConfigDetailsBaselineOcpuUtilizationEnum(baseline)
+		}
 	}
 	if nodeClass.Spec.LaunchOptions != nil {
 		launchOpts, err := utils.ConvertLaunchOptions(nodeClass.Spec.LaunchOptions)
@@ -166,9 +292,9 @@ func (p *Provider) Create(ctx context.Context, nodeClass *v1alpha1.OciNodeClass,
 			return nil, err
 		}
 	}
-	if len(nodeClass.Spec.AgentList) != 0 {
+	if plugins := agentPlugins(nodeClass); len(plugins) != 0 {
 		req.AgentConfig = &core.LaunchInstanceAgentConfigDetails{
-			PluginsConfig: lo.Map(nodeClass.Spec.AgentList, func(item string, index int) core.InstanceAgentPluginConfigDetails {
+			PluginsConfig: lo.Map(plugins, func(item string, index int) core.InstanceAgentPluginConfigDetails {
 				return core.InstanceAgentPluginConfigDetails{Name: common.String(item), DesiredState: core.InstanceAgentPluginConfigDetailsDesiredStateEnabled}
 			}),
 		}
@@ -176,43 +302,113 @@ func (p *Provider) Create(ctx context.Context, nodeClass *v1alpha1.OciNodeClass,
 	// Send the request using the service client
 	resp, err := p.compClient.LaunchInstance(ctx, req)
 	if err != nil {
-		if typed, ok := err.(common.ServiceError); ok {
-			// two cases treat as ICE, out of capacity and reach service limit
-			// for the second, you can request a service limit increase from the Console's Limits, Quotas and Usage page or from the Help menu.
-			if (typed.GetHTTPStatusCode() == 500 && strings.Contains(typed.GetMessage(), "Out of host capacity")) ||
-				(typed.GetHTTPStatusCode() == 400 && strings.Contains(typed.GetMessage(), "service limits were exceeded")) {
-				p.unavailableOfferings.MarkUnavailableForLaunchInstanceErr(ctx, err, corev1.CapacityTypeOnDemand, instanceType.Name, zone)
-				return nil, corecloudprovider.NewInsufficientCapacityError(fmt.Errorf("InsufficientCapacityError: %s", typed.GetMessage()))
-			}
+		// two cases treat as ICE, out of capacity and reach service limit or quota
+		// for the second, you can request a service limit increase from the Console's Limits, Quotas and Usage page or from the Help menu.
+		reason := utils.ClassifyOciError(err)
+		if reason.IsInsufficientCapacity() {
+			p.unavailableOfferings.MarkUnavailableForLaunchInstanceErr(ctx, err, capacityType, instanceType.Name, zone)
 		}
-		return nil, err
+		if reason == utils.OciErrorThrottled {
+			wait := p.launchThrottle.throttled(retryAfter(resp.RawResponse))
+			log.FromContext(ctx).V(1).Info("launch requests are throttled, holding the launches", "retry-after", wait)
+		}
+		return nil, utils.LaunchError(err)
 	}
+	p.launchThrottle.succeeded()
 	return &resp.Instance, nil
 }
 
 func (p *Provider) FindLeastUtilizedSubnet(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) (*core.Subnet, int, error) {
-	subnets, err := p.subnetProvider.List(ctx, nodeClass)
+	subnets, err := p.listSubnetIPs(ctx, nodeClass, p.subnetProvider.GetSubnetAvailableIPv4Count)
 	if err != nil {
 		return nil, 0, err
 	}
+	subnet, count := leastUtilizedSubnet(subnets)
+	return subnet, count, nil
+}
+
+// listSubnetIPs returns the subnets of the nodeclass with their available IPs
+func (p *Provider) listSubnetIPs(ctx context.Context, nodeClass *v1alpha1.OciNodeClass,
+	availableIPs func(context.Context, *core.Subnet) (int, error)) ([]subnetIPs, error) {
+	subnets, err := p.subnetProvider.List(ctx, nodeClass)
+	if err != nil {
+		return nil, err
+	}
 	if len(subnets) == 0 {
-		return nil, 0, fmt.Errorf("no subnets found for vcn: %s, selector: %v", nodeClass.Spec.VcnId, nodeClass.Spec.SubnetSelector)
+		return nil, fmt.Errorf("no subnets found for vcn: %s, selector: %v", nodeClass.Spec.VcnId, nodeClass.Spec.SubnetSelector)
 	}
-	var subnet *core.Subnet
-	availableIPCount := 0
-	subnet = &subnets[0]
+	res := make([]subnetIPs, 0, len(subnets))
 	for i := range subnets {
-		count, err1 := p.subnetProvider.GetSubnetAvailableIPv4Count(ctx, &subnets[i])
-		if err1 != nil {
-			err = err1
-			return nil, 0, fmt.Errorf("GetSubnetAvailableIPv4Count failed. subnet:%s, error:%s", *subnets[i].Id, err.Error())
+		count, err := availableIPs(ctx, &subnets[i])
+		if err != nil {
+			return nil, fmt.Errorf("GetSubnetAvailableIPv4Count failed. subnet:%s, error:%s", *subnets[i].Id, err.Error())
 		}
-		if count > availableIPCount {
-			subnet = &subnets[i]
-			availableIPCount = count
+		res = append(res, subnetIPs{subnet: &subnets[i], available: count})
+	}
+	return res, nil
+}
+
+func leastUtilizedSubnet(subnets []subnetIPs) (*core.Subnet, int) {
+	subnet := subnets[0].subnet
+	availableIPCount := 0
+	for _, s := range subnets {
+		if s.available > availableIPCount {
+			subnet = s.subnet
+			availableIPCount = s.available
 		}
 	}
-	return subnet, availableIPCount, nil
+	return subnet, availableIPCount
+}
+
+// findNodeClaimInstance returns the live instance tagged with the nodeclaim, nil if the nodeclaim has no instance
+func (p *Provider) findNodeClaimInstance(ctx context.Context, nodeClaim *corev1.NodeClaim) (*core.Instance, error) {
+	req := core.ListInstancesRequest{
+		CompartmentId: common.String(options.FromContext(ctx).CompartmentId),
+		DisplayName:   common.String(nodeClaim.Name),
+		SortBy:        core.ListInstancesSortByTimecreated,
+		SortOrder:     core.ListInstancesSortOrderDesc,
+	}
+	tagNamespace := options.FromContext(ctx).TagNamespace
+	// the tags are matched on the client side, so all the pages are listed
+	for {
+		resp, err := p.compClient.ListInstances(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("list instances of nodeclaim %s, %w", nodeClaim.Name, err)
+		}
+		instance, ok := lo.Find(resp.Items, func(item core.Instance) bool {
+			return item.DefinedTags[tagNamespace][utils.SafeTagKey(v1alpha1.LabelNodeClaim)] == nodeClaim.Name &&
+				item.DefinedTags[tagNamespace][utils.SafeTagKey(v1alpha1.ManagedByAnnotationKey)] == options.FromContext(ctx).ClusterName &&
+				item.LifecycleState != core.InstanceLifecycleStateTerminating && item.LifecycleState != core.InstanceLifecycleStateTerminated
+		})
+		if ok {
+			log.FromContext(ctx).Info("found the launched instance of the nodeclaim", "instance", lo.FromPtr(instance.Id))
+			return &instance, nil
+		}
+		if req.Page = resp.OpcNextPage; req.Page == nil {
+			return nil, nil
+		}
+	}
+}
+
+// launchRetryToken derives the retry token of the launch from the uid of the nodeclaim and the offering, so a create
+// retried after a timeout gets the instance launched before instead of launching another one. A retried create may
+// pick another offering, which is another request for the same token, so the offering is part of the token.
+func launchRetryToken(nodeClaim *corev1.NodeClaim, instanceType *corecloudprovider.InstanceType, offering *corecloudprovider.Offering) *string {
+	if nodeClaim.UID == "" {
+		return nil
+	}
+	hash, err := hashstructure.Hash([]string{
+		instanceType.Name,
+		instanceType.Requirements.Get(v1alpha1.LabelInstanceOcpus).Any(),
+		instanceType.Requirements.Get(v1alpha1.LabelInstanceMemory).Any(),
+		offering.Zone(),
+		offering.CapacityType(),
+		offering.ReservationID(),
+	}, hashstructure.FormatV2, nil)
+	if err != nil {
+		return nil
+	}
+	return common.String(fmt.Sprintf("%s-%x", nodeClaim.UID, hash))
 }
 
 func getTags(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, nodeClaim *corev1.NodeClaim) map[string]map[string]interface{} {
@@ -245,27 +441,168 @@ func removeExcludingChars(sizeLimit int, tags ...map[string]string) map[string]i
 	return res
 }
 
-func pickBestInstanceType(nodeClaim *corev1.NodeClaim, instanceTypes corecloudprovider.InstanceTypes) (*corecloudprovider.InstanceType, string) {
+// pickBestInstanceType walks the instance types from the cheapest one, and returns the first one which still has an
+// available offering compatible with the nodeclaim, the offerings marked unavailable by the previous launches and the
+// offerings in the zones without a subnet for the nodeclaim are skipped.
+// The zone and the capacity type are taken from the same offering, so preemptible falls back to on-demand only when no
+// preemptible offering is available. The zone is balanced by the stats of the zones.
+func (p *Provider) pickBestInstanceType(nodeClass *v1alpha1.OciNodeClass, nodeClaim *corev1.NodeClaim, instanceTypes corecloudprovider.InstanceTypes, stats map[string]*zoneStats) (*corecloudprovider.InstanceType, *corecloudprovider.Offering) {
 	if len(instanceTypes) == 0 {
-		return nil, ""
+		return nil, nil
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	for _, it := range instanceTypes.OrderByPrice(reqs) {
+		offerings := lo.Filter(it.Offerings.Available().Compatible(reqs), func(o *corecloudprovider.Offering, _ int) bool {
+			return o.CapacityType() != corev1.CapacityTypeReserved && !p.unavailableOfferings.IsUnavailable(it.Name, o.Zone(), o.CapacityType()) &&
+				fitsSubnet(stats, o.Zone(), instancetype.RequiredIPs(nodeClass, it.Capacity.Pods().Value()))
+		})
+		if len(offerings) == 0 {
+			continue
+		}
+		// the cheapest capacity type is launched, preemptible is cheaper than on-demand
+		capacityType := corecloudprovider.Offerings(offerings).Cheapest().CapacityType()
+		offerings = lo.Filter(offerings, func(o *corecloudprovider.Offering, _ int) bool {
+			return o.CapacityType() == capacityType
+		})
+		return it, pickZoneOffering(offerings, stats)
+	}
+	return nil, nil
+}
+
+// unavailableInZone returns a copy of the instance type whose on-demand and preemptible offerings in the zone are unavailable
+func unavailableInZone(instanceType *corecloudprovider.InstanceType, zone string) *corecloudprovider.InstanceType {
+	return &corecloudprovider.InstanceType{
+		Name:         instanceType.Name,
+		Requirements: instanceType.Requirements,
+		Capacity:     instanceType.Capacity,
+		Overhead:     instanceType.Overhead,
+		Offerings: lo.Map(instanceType.Offerings, func(o *corecloudprovider.Offering, _ int) *corecloudprovider.Offering {
+			if o.Zone() != zone || o.CapacityType() == corev1.CapacityTypeReserved {
+				return o
+			}
+			return &corecloudprovider.Offering{Requirements: o.Requirements, Price: o.Price, ReservationCapacity: o.ReservationCapacity}
+		}),
 	}
+}
 
-	sortedInstanceType := instanceTypes.OrderByPrice(scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...))
-	instanceType := sortedInstanceType[0]
-	// Zone - ideally random/spread from requested zones that support given Priority
-	requestedZones := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone)
-	priorityOfferings := lo.Filter(instanceType.Offerings.Available(), func(o *corecloudprovider.Offering, _ int) bool {
-		return requestedZones.Has(o.Requirements.Get(v1.LabelTopologyZone).Any())
+// pickFaultDomain returns a fault domain allowed by the nodeclaim requirements, the nodeclaim requirements are narrowed
+// by the scheduler when pods spread across fault domains. An empty fault domain lets OCI choose the best one.
+func pickFaultDomain(nodeClaim *corev1.NodeClaim) string {
+	requested := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1alpha1.LabelFaultDomain)
+	faultDomains := lo.Filter(v1alpha1.FaultDomains, func(fd string, _ int) bool {
+		return requested.Has(fd)
 	})
-	if len(priorityOfferings) == 0 {
-		return nil, ""
+	if len(faultDomains) == 0 || len(faultDomains) == len(v1alpha1.FaultDomains) {
+		return ""
 	}
-	zonesWithPriority := lo.Map(priorityOfferings, func(o *corecloudprovider.Offering, _ int) string {
-		return o.Requirements.Get(v1.LabelTopologyZone).Any()
-	})
-	// todo balance between different zone
-	mutable.Shuffle(zonesWithPriority)
-	return instanceType, zonesWithPriority[0]
+	return lo.Sample(faultDomains)
+}
+
+// pickReservedInstanceType returns the cheapest available reserved offering which is compatible with the nodeclaim
+func (p *Provider) pickReservedInstanceType(nodeClass *v1alpha1.OciNodeClass, nodeClaim *corev1.NodeClaim, instanceTypes corecloudprovider.InstanceTypes, stats map[string]*zoneStats) (*corecloudprovider.InstanceType, *corecloudprovider.Offering) {
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	if !reqs.Get(corev1.CapacityTypeLabelKey).Has(corev1.CapacityTypeReserved) {
+		return nil, nil
+	}
+	var instanceType *corecloudprovider.InstanceType
+	var offering *corecloudprovider.Offering
+	for _, it := range instanceTypes {
+		reserved := lo.Filter(it.Offerings.Available().Compatible(reqs).Compatible(corecloudprovider.ReservedRequirement), func(o *corecloudprovider.Offering, _ int) bool {
+			return !p.unavailableOfferings.IsUnavailable(it.Name, o.Zone(), o.CapacityType()) &&
+				fitsSubnet(stats, o.Zone(), instancetype.RequiredIPs(nodeClass, it.Capacity.Pods().Value()))
+		})
+		if len(reserved) == 0 {
+			continue
+		}
+		if cheapest := corecloudprovider.Offerings(reserved).Cheapest(); offering == nil || cheapest.Price < offering.Price {
+			instanceType, offering = it, cheapest
+		}
+	}
+	return instanceType, offering
+}
+
+// blockDeviceDisplayName names the attachment of the block device, the attachments are matched to the block devices by name after launch
+func blockDeviceDisplayName(index int) string {
+	return fmt.Sprintf("block-device-%d", index)
+}
+
+// agentPlugins returns the oracle cloud agent plugins enabled on the instance, the block volume management plugin logs
+// in the iscsi volumes which are mounted by the bootstrap script, their devices aren't present otherwise
+func agentPlugins(nodeClass *v1alpha1.OciNodeClass) []string {
+	plugins := nodeClass.Spec.AgentList
+	if lo.SomeBy(nodeClass.Spec.BlockDevices, func(item *v1alpha1.VolumeAttributes) bool {
+		return item.MountPath != nil && lo.FromPtr(item.AttachmentType) != v1alpha1.VolumeAttachmentTypeParavirtualized
+	}) && !lo.Contains(plugins, blockVolumeManagementPlugin) {
+		plugins = append(slices.Clone(plugins), blockVolumeManagementPlugin)
+	}
+	return plugins
+}
+
+// launchAttachVolumeDetails converts the block device into the attach details of its attachment type
+func launchAttachVolumeDetails(item *v1alpha1.VolumeAttributes, index int) core.LaunchAttachVolumeDetails {
+	createDetails := core.LaunchCreateVolumeFromAttributes{VpusPerGB: common.Int64(item.VpusPerGB),
+		SizeInGBs: common.Int64(item.SizeInGBs), KmsKeyId: item.KmsKeyId}
+	if lo.FromPtr(item.AttachmentType) == v1alpha1.VolumeAttachmentTypeParavirtualized {
+		return core.LaunchAttachParavirtualizedVolumeDetails{
+			DisplayName:               common.String(blockDeviceDisplayName(index)),
+			Device:                    item.Device,
+			IsReadOnly:                item.IsReadOnly,
+			IsShareable:               item.IsShareable,
+			LaunchCreateVolumeDetails: createDetails,
+		}
+	}
+	details := core.LaunchAttachIScsiVolumeDetails{
+		DisplayName:               common.String(blockDeviceDisplayName(index)),
+		Device:                    item.Device,
+		IsReadOnly:                item.IsReadOnly,
+		IsShareable:               item.IsShareable,
+		LaunchCreateVolumeDetails: createDetails,
+	}
+	// the iscsi device is only present after login, let the oracle cloud agent login before the bootstrap mounts it
+	if item.MountPath != nil {
+		details.IsAgentAutoIscsiLoginEnabled = common.Bool(true)
+	}
+	return details
+}
+
+// launchPlatformConfig converts the platform config into the launch details of the shape platform type,
+// returns nil when the shape doesn't support platform config
+func launchPlatformConfig(shape core.Shape, platformConfig *v1alpha1.PlatformConfig) core.LaunchInstancePlatformConfig {
+	if shape.PlatformConfigOptions == nil {
+		return nil
+	}
+	secureBoot, tpm, measuredBoot, memoryEncryption := platformConfig.IsSecureBootEnabled, platformConfig.IsTrustedPlatformModuleEnabled,
+		platformConfig.IsMeasuredBootEnabled, platformConfig.IsMemoryEncryptionEnabled
+	switch shape.PlatformConfigOptions.Type {
+	case core.ShapePlatformConfigOptionsTypeAmdVm:
+		return core.AmdVmLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeIntelVm:
+		return core.IntelVmLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeAmdMilanBm:
+		return core.AmdMilanBmLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeAmdMilanBmGpu:
+		return core.AmdMilanBmGpuLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeAmdRomeBm:
+		return core.AmdRomeBmLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeAmdRomeBmGpu:
+		return core.AmdRomeBmGpuLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeIntelIcelakeBm:
+		return core.IntelIcelakeBmLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeIntelSkylakeBm:
+		return core.IntelSkylakeBmLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	case core.ShapePlatformConfigOptionsTypeGenericBm:
+		return core.GenericBmLaunchInstancePlatformConfig{IsSecureBootEnabled: secureBoot, IsTrustedPlatformModuleEnabled: tpm,
+			IsMeasuredBootEnabled: measuredBoot, IsMemoryEncryptionEnabled: memoryEncryption}
+	}
+	return nil
 }
 
 func getIntValFromRequirements(key string, requirements scheduling.Requirements) (int, error) {
@@ -283,6 +620,9 @@ func (p *Provider) Delete(ctx context.Context, id string) error {
 		PreserveBootVolume:                 common.Bool(false),
 		PreserveDataVolumesCreatedAtLaunch: common.Bool(false)}
 	resp, err := p.compClient.TerminateInstance(ctx, req)
+	if utils.ClassifyOciError(err) == utils.OciErrorNotAuthorizedOrNotFound {
+		return corecloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance already terminated, %w", err))
+	}
 	if resp.HTTPResponse() != nil {
 		statusCode := resp.HTTPResponse().StatusCode
 		if statusCode == http.StatusNotFound || statusCode == http.StatusNoContent {
@@ -295,6 +635,9 @@ func (p *Provider) Delete(ctx context.Context, id string) error {
 
 func (p *Provider) Get(ctx context.Context, id string) (*core.Instance, error) {
 	out, err := p.compClient.GetInstance(ctx, core.GetInstanceRequest{InstanceId: common.String(id)})
+	if utils.ClassifyOciError(err) == utils.OciErrorNotAuthorizedOrNotFound {
+		return nil, corecloudprovider.NewNodeClaimNotFoundError(err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get instances, %w", err)
 	}
@@ -307,6 +650,63 @@ func (p *Provider) Get(ctx context.Context, id string) (*core.Instance, error) {
 	return &out.Instance, nil
 }
 
+// LaunchFailure is the failure of a launch which was accepted by LaunchInstance
+type LaunchFailure struct {
+	Reason  utils.OciErrorReason
+	Message string
+}
+
+// GetLaunchFailure returns the failure of the launch of the instance, or nil while the launch is in progress or after
+// it succeeded. The capacity and image errors may only appear after LaunchInstance returns, the launch failed when its
+// work request failed or the instance is terminated before the node registers, the failure is read from the errors of
+// the work request
+func (p *Provider) GetLaunchFailure(ctx context.Context, id string) (*LaunchFailure, error) {
+	out, err := p.compClient.GetInstance(ctx, core.GetInstanceRequest{InstanceId: common.String(id)})
+	if utils.ClassifyOciError(err) == utils.OciErrorNotAuthorizedOrNotFound || (out.RawResponse != nil && out.RawResponse.StatusCode == http.StatusNotFound) {
+		return nil, corecloudprovider.NewNodeClaimNotFoundError(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instances, %w", err)
+	}
+	resp, err := p.workRequestClient.ListWorkRequests(ctx, workrequests.ListWorkRequestsRequest{
+		CompartmentId: lo.CoalesceOrEmpty(out.CompartmentId, common.String(options.FromContext(ctx).CompartmentId)),
+		ResourceId:    common.String(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing work requests of instance %s, %w", id, err)
+	}
+	workRequest, failed := lo.Find(resp.Items, func(item workrequests.WorkRequestSummary) bool {
+		return strings.Contains(strings.ToLower(lo.FromPtr(item.OperationType)), "launch") &&
+			(item.Status == workrequests.WorkRequestSummaryStatusFailed || item.Status == workrequests.WorkRequestSummaryStatusCanceled)
+	})
+	terminated := out.LifecycleState == core.InstanceLifecycleStateTerminating || out.LifecycleState == core.InstanceLifecycleStateTerminated
+	if !failed && !terminated {
+		return nil, nil
+	}
+	failure := &LaunchFailure{Reason: utils.OciErrorUnknown, Message: fmt.Sprintf("instance is %s", strings.ToLower(string(out.LifecycleState)))}
+	if !failed {
+		return failure, nil
+	}
+	// the latest error is the cause of the failure
+	errs, err := p.workRequestClient.ListWorkRequestErrors(ctx, workrequests.ListWorkRequestErrorsRequest{
+		WorkRequestId: workRequest.Id,
+		SortOrder:     workrequests.ListWorkRequestErrorsSortOrderDesc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing errors of work request %s, %w", lo.FromPtr(workRequest.Id), err)
+	}
+	if len(errs.Items) != 0 {
+		latest := errs.Items[0]
+		failure = &LaunchFailure{
+			Reason:  utils.ClassifyWorkRequestError(lo.FromPtr(latest.Code), lo.FromPtr(latest.Message)),
+			Message: lo.FromPtr(latest.Message),
+		}
+	} else {
+		failure.Message = fmt.Sprintf("work request %s is %s", lo.FromPtr(workRequest.Id), strings.ToLower(string(workRequest.Status)))
+	}
+	return failure, nil
+}
+
 func (p *Provider) List(ctx context.Context) ([]core.Instance, error) {
 	nextPage := "0"
 	instances := make([]core.Instance, 0)
@@ -378,3 +778,64 @@ func (p *Provider) CreateTags(ctx context.Context, id string, tags map[string]st
 	}
 	return nil
 }
+
+// UpdateAutotunePolicies applies the auto-tune policies of the boot volume and the block volumes once they are attached,
+// the policies can't be specified when launching the instance
+func (p *Provider) UpdateAutotunePolicies(ctx context.Context, instance *core.Instance, nodeClass *v1alpha1.OciNodeClass) error {
+	if nodeClass.Spec.BootConfig != nil && len(nodeClass.Spec.BootConfig.AutotunePolicies) != 0 {
+		resp, err := p.compClient.ListBootVolumeAttachments(ctx, core.ListBootVolumeAttachmentsRequest{
+			AvailabilityDomain: instance.AvailabilityDomain,
+			CompartmentId:      common.String(options.FromContext(ctx).CompartmentId),
+			InstanceId:         instance.Id,
+		})
+		if err != nil {
+			return fmt.Errorf("listing boot volume attachments, %w", err)
+		}
+		if len(resp.Items) == 0 {
+			return fmt.Errorf("boot volume of instance %s isn't attached yet", lo.FromPtr(instance.Id))
+		}
+		if _, err = p.blockStorageClient.UpdateBootVolume(ctx, core.UpdateBootVolumeRequest{
+			BootVolumeId:            resp.Items[0].BootVolumeId,
+			UpdateBootVolumeDetails: core.UpdateBootVolumeDetails{AutotunePolicies: autotunePolicies(nodeClass.Spec.BootConfig.AutotunePolicies)},
+		}); err != nil {
+			return fmt.Errorf("updating boot volume auto-tune policies, %w", err)
+		}
+	}
+	if lo.NoneBy(nodeClass.Spec.BlockDevices, func(item *v1alpha1.VolumeAttributes) bool { return len(item.AutotunePolicies) != 0 }) {
+		return nil
+	}
+	resp, err := p.compClient.ListVolumeAttachments(ctx, core.ListVolumeAttachmentsRequest{
+		CompartmentId: common.String(options.FromContext(ctx).CompartmentId),
+		InstanceId:    instance.Id,
+	})
+	if err != nil {
+		return fmt.Errorf("listing volume attachments, %w", err)
+	}
+	for index, device := range nodeClass.Spec.BlockDevices {
+		if len(device.AutotunePolicies) == 0 {
+			continue
+		}
+		attachment, ok := lo.Find(resp.Items, func(item core.VolumeAttachment) bool {
+			return lo.FromPtr(item.GetDisplayName()) == blockDeviceDisplayName(index)
+		})
+		if !ok {
+			return fmt.Errorf("block volume %s of instance %s isn't attached yet", blockDeviceDisplayName(index), lo.FromPtr(instance.Id))
+		}
+		if _, err = p.blockStorageClient.UpdateVolume(ctx, core.UpdateVolumeRequest{
+			VolumeId:            attachment.GetVolumeId(),
+			UpdateVolumeDetails: core.UpdateVolumeDetails{AutotunePolicies: autotunePolicies(device.AutotunePolicies)},
+		}); err != nil {
+			return fmt.Errorf("updating block volume auto-tune policies, %w", err)
+		}
+	}
+	return nil
+}
+
+func autotunePolicies(policies []*v1alpha1.AutotunePolicy) []core.AutotunePolicy {
+	return lo.Map(policies, func(item *v1alpha1.AutotunePolicy, _ int) core.AutotunePolicy {
+		if item.AutotuneType == string(core.AutotunePolicyAutotuneTypePerformanceBased) {
+			return core.PerformanceBasedAutotunePolicy{MaxVpusPerGB: item.MaxVpusPerGB}
+		}
+		return core.DetachedVolumeAutotunePolicy{}
+	})
+}
@@ -16,8 +16,11 @@ package instance
 
 import (
 	"fmt"
+	"github.com/samber/lo"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	v1 "k8s.io/api/core/v1"
+	corev1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"testing"
 )
@@ -44,3 +47,77 @@ func TestFlexibleRequirement(t *testing.T) {
 		return
 	}
 }
+
+func TestPickFaultDomain(t *testing.T) {
+	nodeClaim := &corev1.NodeClaim{}
+	if fd := pickFaultDomain(nodeClaim); fd != "" {
+		t.Errorf("expected no fault domain without requirements, got %s", fd)
+	}
+	nodeClaim.Spec.Requirements = []corev1.NodeSelectorRequirementWithMinValues{{
+		NodeSelectorRequirement: v1.NodeSelectorRequirement{
+			Key:      v1alpha1.LabelFaultDomain,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{"FAULT-DOMAIN-2"},
+		},
+	}}
+	if fd := pickFaultDomain(nodeClaim); fd != "FAULT-DOMAIN-2" {
+		t.Errorf("expected FAULT-DOMAIN-2, got %s", fd)
+	}
+	nodeClaim.Spec.Requirements[0].Operator = v1.NodeSelectorOpNotIn
+	for i := 0; i < 10; i++ {
+		if fd := pickFaultDomain(nodeClaim); fd == "FAULT-DOMAIN-2" || fd == "" {
+			t.Errorf("expected a fault domain other than FAULT-DOMAIN-2, got %q", fd)
+		}
+	}
+}
+
+func TestPickZoneOffering(t *testing.T) {
+	offerings := lo.Map([]string{"US-ASHBURN-AD-1", "US-ASHBURN-AD-2", "US-ASHBURN-AD-3"}, func(zone string, _ int) *corecloudprovider.Offering {
+		return &corecloudprovider.Offering{Available: true, Requirements: scheduling.NewRequirements(
+			scheduling.NewRequirement(corev1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1.CapacityTypeOnDemand),
+			scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, zone))}
+	})
+	for _, tc := range []struct {
+		name     string
+		stats    map[string]*zoneStats
+		expected string
+	}{
+		{name: "fewest nodeclaims", expected: "US-ASHBURN-AD-3", stats: map[string]*zoneStats{
+			"US-ASHBURN-AD-1": {nodeClaims: 2, freeIPs: 100}, "US-ASHBURN-AD-2": {nodeClaims: 1, freeIPs: 100}, "US-ASHBURN-AD-3": {freeIPs: 100}}},
+		{name: "recent insufficient capacity errors", expected: "US-ASHBURN-AD-2", stats: map[string]*zoneStats{
+			"US-ASHBURN-AD-1": {unavailableOfferings: 1, freeIPs: 100}, "US-ASHBURN-AD-2": {freeIPs: 100}, "US-ASHBURN-AD-3": {unavailableOfferings: 2, freeIPs: 100}}},
+		{name: "most free ips", expected: "US-ASHBURN-AD-1", stats: map[string]*zoneStats{
+			"US-ASHBURN-AD-1": {freeIPs: 100}, "US-ASHBURN-AD-2": {freeIPs: 50}, "US-ASHBURN-AD-3": {}}},
+		{name: "nodeclaims weighted by free ips", expected: "US-ASHBURN-AD-1", stats: map[string]*zoneStats{
+			"US-ASHBURN-AD-1": {nodeClaims: 1, freeIPs: 400}, "US-ASHBURN-AD-2": {freeIPs: 100}, "US-ASHBURN-AD-3": {freeIPs: 100}}},
+	} {
+		if zone := pickZoneOffering(offerings, tc.stats).Zone(); zone != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, zone)
+		}
+	}
+	// zones without stats are picked evenly
+	picked := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		picked[pickZoneOffering(offerings, nil).Zone()] = true
+	}
+	if len(picked) != 3 {
+		t.Errorf("expected all the zones to be picked, got %v", lo.Keys(picked))
+	}
+}
+
+func TestLaunchingZones(t *testing.T) {
+	zones := newLaunchingZones()
+	release1 := zones.track("default", "US-ASHBURN-AD-1")
+	release2 := zones.track("default", "US-ASHBURN-AD-1")
+	if count := zones.count("default", "US-ASHBURN-AD-1"); count != 2 {
+		t.Errorf("expected 2 launching instances, got %d", count)
+	}
+	if count := zones.count("other", "US-ASHBURN-AD-1"); count != 0 {
+		t.Errorf("expected no launching instances for the other nodepool, got %d", count)
+	}
+	release1()
+	release2()
+	if count := zones.count("default", "US-ASHBURN-AD-1"); count != 0 {
+		t.Errorf("expected no launching instances, got %d", count)
+	}
+}
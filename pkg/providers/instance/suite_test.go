@@ -16,7 +16,9 @@ package instance_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -64,7 +66,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	ociEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, ociEnv.AMIProvider)
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -130,6 +132,205 @@ var _ = Describe("InstanceProvider", func() {
 		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
 		Expect(instance).To(BeNil())
 	})
+	Context("Launch Idempotency", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "shape-1" })
+		})
+		It("should send a retry token derived from the nodeclaim uid", func() {
+			_, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			var tokens []string
+			ociEnv.CmpCli.RetryTokens.Range(func(k, _ any) bool {
+				tokens = append(tokens, k.(string))
+				return true
+			})
+			Expect(tokens).To(HaveLen(1))
+			Expect(tokens[0]).To(HavePrefix(string(nodeClaim.UID) + "-"))
+		})
+		It("should launch with another retry token when the retried create picks another offering", func() {
+			launched, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			ociEnv.CmpCli.ListInstanceBehavior.Output.Set(&core.ListInstancesResponse{})
+			// the retried create picks another shape, e.g. when the shape launched before is out of capacity
+			allInstanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, lo.Filter(allInstanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool {
+				return i.Name == "shape-2"
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Id).ToNot(Equal(launched.Id))
+			Expect(lo.FromPtr(instance.Shape)).To(Equal("shape-2"))
+			var tokens []string
+			ociEnv.CmpCli.RetryTokens.Range(func(k, _ any) bool {
+				tokens = append(tokens, k.(string))
+				return true
+			})
+			Expect(tokens).To(HaveLen(2))
+		})
+		It("should return the instance of the nodeclaim listed on the next pages", func() {
+			// the instances are listed by id, the instance of another nodeclaim with the name is on the first page
+			ociEnv.CmpCli.Instances.Store("00000000", &core.Instance{Id: lo.ToPtr("00000000"), DisplayName: lo.ToPtr(nodeClaim.Name),
+				LifecycleState: core.InstanceLifecycleStateRunning})
+			launched, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			ociEnv.CmpCli.PageSize.Set(lo.ToPtr(1))
+			ociEnv.CmpCli.RetryTokens.Range(func(k, _ any) bool {
+				ociEnv.CmpCli.RetryTokens.Delete(k)
+				return true
+			})
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Id).To(Equal(launched.Id))
+			Expect(ociEnv.CmpCli.ListInstanceBehavior.Calls()).To(Equal(3))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+		})
+		It("should return the instance launched before for the nodeclaim", func() {
+			launched, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Id).To(Equal(launched.Id))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+		})
+		It("should return the instance launched with the same retry token when the instance isn't listed yet", func() {
+			launched, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			ociEnv.CmpCli.ListInstanceBehavior.Output.Set(&core.ListInstancesResponse{})
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Id).To(Equal(launched.Id))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(2))
+		})
+		It("should not return the terminated instance of the nodeclaim", func() {
+			launched, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			terminated := *launched
+			terminated.LifecycleState = core.InstanceLifecycleStateTerminated
+			ociEnv.CmpCli.Instances.Store(lo.FromPtr(launched.Id), &terminated)
+			ociEnv.CmpCli.RetryTokens.Range(func(k, _ any) bool {
+				ociEnv.CmpCli.RetryTokens.Delete(k)
+				return true
+			})
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Id).ToNot(Equal(launched.Id))
+		})
+	})
+	Context("Launch Errors", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "shape-1" })
+		})
+		It("should return an ICE error and mark the offering unavailable when the limits are exceeded", func() {
+			ociEnv.CmpCli.LaunchInstanceBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "LimitExceeded", Message: "Tenancy has reached the limit"}, fake.MaxCalls(3))
+			_, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(ociEnv.UnavailableOfferingsCache.ZoneUnavailableCount("US-ASHBURN-AD-1") +
+				ociEnv.UnavailableOfferingsCache.ZoneUnavailableCount("US-ASHBURN-AD-2") +
+				ociEnv.UnavailableOfferingsCache.ZoneUnavailableCount("US-ASHBURN-AD-3")).To(Equal(3))
+		})
+		It("should return a nodeclass not ready error without another attempt when the launch isn't authorized", func() {
+			ociEnv.CmpCli.LaunchInstanceBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "Authorization failed or requested resource not found"})
+			_, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsNodeClassNotReadyError(err)).To(BeTrue())
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.FailedCalls()).To(Equal(1))
+			Expect(ociEnv.UnavailableOfferingsCache.ZoneUnavailableCount("US-ASHBURN-AD-1")).To(BeZero())
+		})
+		It("should return a create error without another attempt when the launch is throttled", func() {
+			ociEnv.CmpCli.LaunchInstanceBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: http.StatusTooManyRequests, Code: "TooManyRequests", Message: "Too many requests for the tenancy"})
+			_, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			var createErr *corecloudprovider.CreateError
+			Expect(errors.As(err, &createErr)).To(BeTrue())
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.FailedCalls()).To(Equal(1))
+		})
+		It("should hold the launches of the other nodeclaims once the launch is throttled", func() {
+			ociEnv.CmpCli.LaunchInstanceBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: http.StatusTooManyRequests, Code: "TooManyRequests", Message: "Too many requests for the tenancy"}, fake.MaxCalls(1))
+			_, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).To(HaveOccurred())
+			other := coretest.NodeClaim(v1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{v1.NodePoolLabelKey: nodePool.Name}}, Spec: nodeClaim.Spec})
+			ExpectApplied(ctx, env.Client, other)
+			_, err = ociEnv.InstanceProvider.Create(ctx, nodeClass, other, instanceTypes)
+			var createErr *corecloudprovider.CreateError
+			Expect(errors.As(err, &createErr)).To(BeTrue())
+			Expect(createErr.ConditionReason).To(Equal("TooManyRequests"))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.Calls()).To(Equal(1))
+		})
+		It("should return a create error when the launch fails with an internal error", func() {
+			ociEnv.CmpCli.LaunchInstanceBehavior.Error.Set(&fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: "Internal error occurred"})
+			_, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			var createErr *corecloudprovider.CreateError
+			Expect(errors.As(err, &createErr)).To(BeTrue())
+			Expect(createErr.ConditionReason).To(Equal("InternalError"))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.FailedCalls()).To(Equal(1))
+		})
+	})
+	Context("Launch Attempts", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = []v1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: v1core.NodeSelectorRequirement{
+				Key: v1core.LabelTopologyZone, Operator: v1core.NodeSelectorOpIn, Values: []string{"US-ASHBURN-AD-1"}}}}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			ociEnv.CmpCli.InsufficientCapacityPools.Set([]fake.CapacityPool{
+				{CapacityType: v1.CapacityTypeOnDemand, InstanceType: "shape-1", Zone: "US-ASHBURN-AD-1"},
+			})
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool {
+				return i.Name == "shape-1" || i.Name == "shape-2"
+			})
+		})
+		It("should launch the next cheapest offering in a single create when the cheapest one returns an ICE error", func() {
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.FromPtr(instance.Shape)).To(Equal("shape-2"))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(2))
+			Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", v1.CapacityTypeOnDemand)).To(BeTrue())
+		})
+		It("should return an ICE error when the launch attempts run out", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxLaunchAttempts: lo.ToPtr(1),
+				AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2", "JPqd:US-ASHBURN-AD-3"}}))
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(instance).To(BeNil())
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+		})
+		It("should skip the launch of the offering whose capacity report is out of capacity", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{CapacityProbeShapes: lo.ToPtr("shape-1"),
+				AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2", "JPqd:US-ASHBURN-AD-3"}}))
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.FromPtr(instance.Shape)).To(Equal("shape-2"))
+			// shape-1 is probed rather than launched, shape-2 isn't probed
+			Expect(ociEnv.CmpCli.CapacityReportBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", v1.CapacityTypeOnDemand)).To(BeTrue())
+		})
+		It("should release the IPs reserved for the failed launches and take the IPs of the launched instance", func() {
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			reserved := 0
+			for _, subnet := range fake.DefaultSubnets {
+				expected, err := ociEnv.SubnetProvider.GetSubnetAvailableIPv4Count(ctx, &subnet)
+				Expect(err).ToNot(HaveOccurred())
+				if available, ok := ociEnv.SubnetProvider.CachedAvailableIPs(lo.FromPtr(subnet.Id)); ok {
+					reserved += expected - available
+				}
+			}
+			// only the IP of the VNIC of the launched instance is taken
+			Expect(reserved).To(Equal(1))
+			Expect(lo.FromPtr(instance.Shape)).To(Equal("shape-2"))
+		})
+	})
 	It("should return all NodePool-owned instances from List", func() {
 		ids := sets.New[string]()
 		// Provision instances that have the karpenter.sh/nodepool key
@@ -171,6 +372,8 @@ var _ = Describe("InstanceProvider", func() {
 		Expect(ids.Equal(retrievedIDs)).To(BeTrue())
 	})
 	It("should create preemptible instance when requested", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{PreemptibleShapes: lo.ToPtr("shape-1"),
+			AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2", "JPqd:US-ASHBURN-AD-3"}}))
 		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
 		ociEnv.CmpCli.InsufficientCapacityPools.Set([]fake.CapacityPool{
 			{CapacityType: v1.CapacityTypeOnDemand, InstanceType: "m5.xlarge", Zone: "US-ASHBURN-AD-1"},
@@ -202,7 +405,144 @@ var _ = Describe("InstanceProvider", func() {
 
 		Expect(err).ToNot(HaveOccurred())
 		Expect(instance).ToNot(BeNil())
+		Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop().PreemptibleInstanceConfig).ToNot(BeNil())
+	})
+	It("should balance the instances of the nodepool across the zones", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		for _, zone := range []string{"US-ASHBURN-AD-1", "US-ASHBURN-AD-2"} {
+			ExpectApplied(ctx, env.Client, coretest.NodeClaim(v1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				v1.NodePoolLabelKey:      nodePool.Name,
+				v1core.LabelTopologyZone: zone,
+			}}}))
+		}
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "shape-1" })
 
+		instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lo.FromPtr(instance.AvailabilityDomain)).To(Equal("JPqd:US-ASHBURN-AD-3"))
+	})
+	It("should launch the instance into a subnet of its availability domain", func() {
+		ociEnv.VcnCli.ListSubnetsOutput.Set(&core.ListSubnetsResponse{Items: []core.Subnet{
+			{Id: common.String("subnet-ad-1"), DisplayName: common.String("private-1"), CidrBlock: common.String("10.0.0.0/24"),
+				AvailabilityDomain: common.String("JPqd:US-ASHBURN-AD-1"), LifecycleState: core.SubnetLifecycleStateAvailable},
+			{Id: common.String("subnet-ad-2"), DisplayName: common.String("private-1"), CidrBlock: common.String("10.0.1.0/24"),
+				AvailabilityDomain: common.String("JPqd:US-ASHBURN-AD-2"), LifecycleState: core.SubnetLifecycleStateAvailable},
+		}})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "shape-1" })
+
+		for i := 0; i < 5; i++ {
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			// no subnet is in US-ASHBURN-AD-3
+			Expect(lo.FromPtr(instance.AvailabilityDomain)).ToNot(Equal("JPqd:US-ASHBURN-AD-3"))
+			req := ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop()
+			Expect(lo.FromPtr(req.CreateVnicDetails.SubnetId)).To(Equal(lo.Ternary(lo.FromPtr(instance.AvailabilityDomain) == "JPqd:US-ASHBURN-AD-1", "subnet-ad-1", "subnet-ad-2")))
+		}
+	})
+	Context("Capacity Types", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{PreemptibleShapes: lo.ToPtr("shape-1"),
+				AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2", "JPqd:US-ASHBURN-AD-3"}}))
+			nodeClaim.Spec.Requirements = []v1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1core.NodeSelectorRequirement{Key: v1core.LabelTopologyZone, Operator: v1core.NodeSelectorOpIn, Values: []string{"US-ASHBURN-AD-1"}}},
+				{NodeSelectorRequirement: v1core.NodeSelectorRequirement{Key: v1.CapacityTypeLabelKey, Operator: v1core.NodeSelectorOpIn, Values: []string{v1alpha1.CapacityTypePreemptible, v1.CapacityTypeOnDemand}}},
+			}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "shape-1" })
+		})
+		It("should launch the cheaper preemptible offering when both capacity types are allowed", func() {
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.PreemptibleInstanceConfig).ToNot(BeNil())
+		})
+		It("should fallback to on-demand and mark the preemptible offering unavailable when preemptible is out of capacity", func() {
+			ociEnv.CmpCli.InsufficientCapacityPools.Set([]fake.CapacityPool{
+				{CapacityType: v1alpha1.CapacityTypePreemptible, InstanceType: "shape-1", Zone: "US-ASHBURN-AD-1"},
+			})
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.PreemptibleInstanceConfig).To(BeNil())
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(2))
+			Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", v1alpha1.CapacityTypePreemptible)).To(BeTrue())
+			Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", v1.CapacityTypeOnDemand)).To(BeFalse())
+		})
+	})
+	It("should launch the boot volume and block volumes with the kms keys", func() {
+		nodeClass.Spec.BootConfig.KmsKeyId = lo.ToPtr("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaaa")
+		nodeClass.Spec.BootConfig.IsPvEncryptionInTransitEnabled = lo.ToPtr(true)
+		nodeClass.Spec.BlockDevices = []*v1alpha1.VolumeAttributes{{SizeInGBs: 100, VpusPerGB: 10, KmsKeyId: lo.ToPtr("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaab")}}
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+		input := ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop()
+		Expect(lo.FromPtr(input.IsPvEncryptionInTransitEnabled)).To(BeTrue())
+		Expect(lo.FromPtr(input.SourceDetails.(core.InstanceSourceViaImageDetails).KmsKeyId)).To(Equal("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaaa"))
+		Expect(input.LaunchVolumeAttachments).To(HaveLen(1))
+		volumeDetails := input.LaunchVolumeAttachments[0].GetLaunchCreateVolumeDetails().(core.LaunchCreateVolumeFromAttributes)
+		Expect(lo.FromPtr(volumeDetails.KmsKeyId)).To(Equal("ocid1.key.oc1.iad.bbpnr2ukaaeuk.aaaaaaab"))
+	})
+	Context("Capacity Reservations", func() {
+		BeforeEach(func() {
+			ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{ReservedCapacity: lo.ToPtr(true)}}))
+			nodeClass.Status.CapacityReservations = []*v1alpha1.CapacityReservation{{
+				Id:                 "ocid1.capacityreservation.oc1.iad.aaaaaaaa",
+				AvailabilityDomain: "US-ASHBURN-AD-1",
+				InstanceShape:      "shape-1",
+				ReservedCount:      1,
+			}}
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, v1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1core.NodeSelectorRequirement{
+					Key:      v1.CapacityTypeLabelKey,
+					Operator: v1core.NodeSelectorOpIn,
+					Values:   []string{v1.CapacityTypeReserved, v1.CapacityTypeOnDemand},
+				},
+			})
+		})
+		It("should launch into the capacity reservation before on-demand", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.FromPtr(instance.Shape)).To(Equal("shape-1"))
+			Expect(lo.FromPtr(instance.AvailabilityDomain)).To(Equal("JPqd:US-ASHBURN-AD-1"))
+			Expect(lo.FromPtr(instance.CapacityReservationId)).To(Equal("ocid1.capacityreservation.oc1.iad.aaaaaaaa"))
+		})
+		It("should not launch into the capacity reservation without the reserved capacity feature gate", func() {
+			ctx = coreoptions.ToContext(ctx, coretest.Options())
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.CapacityReservationId).To(BeNil())
+		})
+		It("should fallback to on-demand when the capacity reservation is used up", func() {
+			nodeClass.Status.CapacityReservations[0].UsedCount = 1
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.CapacityReservationId).To(BeNil())
+			Expect(instance.PreemptibleInstanceConfig).To(BeNil())
+		})
 	})
 	It("should balance instances across multiple subnets", func() {
 		ociEnv.VcnCli.ListSubnetsOutput.Set(&core.ListSubnetsResponse{
@@ -294,7 +634,28 @@ var _ = Describe("InstanceProvider", func() {
 		}
 		instance, err := ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
 		Expect(instance).To(BeNil())
-		Expect(err).ToNot(BeNil())
-		Expect(err.Error() == "not enough IPs are available on all subnets").To(BeTrue())
+		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("not enough IPs are available on all subnets subnet-id-1, subnet-id-2, subnet-id-3, required IPs: 1"))
+
+		// case 4, the subnets can't fit the pod IPs of the native pod networking
+		ociEnv.VcnCli.GetSubnetCidrUtilizationOutput.Set(&map[string]core.GetSubnetCidrUtilizationResponse{
+			"subnet-id-1": core.GetSubnetCidrUtilizationResponse{IpInventoryCidrUtilizationCollection: core.IpInventoryCidrUtilizationCollection{
+				Count:                             common.Int(1),
+				IpInventoryCidrUtilizationSummary: []core.IpInventoryCidrUtilizationSummary{{Cidr: common.String("10.0.0.0/24"), Utilization: common.Float32(95)}}}},
+			"subnet-id-2": core.GetSubnetCidrUtilizationResponse{IpInventoryCidrUtilizationCollection: core.IpInventoryCidrUtilizationCollection{
+				Count:                             common.Int(1),
+				IpInventoryCidrUtilizationSummary: []core.IpInventoryCidrUtilizationSummary{{Cidr: common.String("10.0.0.10/24"), Utilization: common.Float32(95)}}}},
+			"subnet-id-3": core.GetSubnetCidrUtilizationResponse{IpInventoryCidrUtilizationCollection: core.IpInventoryCidrUtilizationCollection{
+				Count:                             common.Int(1),
+				IpInventoryCidrUtilizationSummary: []core.IpInventoryCidrUtilizationSummary{{Cidr: common.String("10.0.0.20/24"), Utilization: common.Float32(95)}}}},
+		})
+		ociEnv.SubnetCache.Flush()
+		nodeClass.Spec.MetaData = map[string]string{"oke-native-pod-networking": "true"}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		instance, err = ociEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(instance).To(BeNil())
+		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("not enough IPs are available on all subnets subnet-id-1, subnet-id-2, subnet-id-3"))
+		Expect(ociEnv.CmpCli.LaunchInstanceBehavior.Calls()).To(Equal(0))
 	})
 })
@@ -0,0 +1,89 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// minLaunchBackoff is the first backoff of the throttled launches when the response has no Retry-After
+	minLaunchBackoff = time.Second
+	// maxLaunchBackoff caps the backoff of the throttled launches
+	maxLaunchBackoff = time.Minute
+)
+
+// launchThrottle backs off the launches of all the nodeclaims once a launch is throttled, as the request limits are
+// shared by the tenancy. The launches are held until the Retry-After of the throttled response, or an exponential
+// backoff when the response has none, the backoff is reset by a successful launch.
+type launchThrottle struct {
+	mu      sync.Mutex
+	until   time.Time
+	backoff time.Duration
+	now     func() time.Time
+}
+
+func newLaunchThrottle() *launchThrottle {
+	return &launchThrottle{now: time.Now}
+}
+
+// remaining returns how long the launches are held, zero when the launches aren't throttled
+func (t *launchThrottle) remaining() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return max(t.until.Sub(t.now()), 0)
+}
+
+// throttled holds the launches for retryAfter, or for the next exponential backoff when retryAfter is zero
+func (t *launchThrottle) throttled(retryAfter time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.backoff = min(max(t.backoff*2, minLaunchBackoff), maxLaunchBackoff)
+	wait := t.backoff
+	if retryAfter > 0 {
+		wait = min(retryAfter, maxLaunchBackoff)
+	}
+	t.until = t.now().Add(wait)
+	return wait
+}
+
+// succeeded resets the backoff once a launch is accepted
+func (t *launchThrottle) succeeded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.backoff = 0
+}
+
+// reset releases the held launches and resets the backoff
+func (t *launchThrottle) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.until, t.backoff = time.Time{}, 0
+}
+
+// retryAfter returns the Retry-After of the throttled response in seconds, zero when it isn't returned
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,71 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLaunchThrottle(t *testing.T) {
+	now := time.Now()
+	throttle := &launchThrottle{now: func() time.Time { return now }}
+	if wait := throttle.remaining(); wait != 0 {
+		t.Fatalf("expected the launches not held, got %s", wait)
+	}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if wait := throttle.throttled(0); wait != want {
+			t.Errorf("expected the backoff %s, got %s", want, wait)
+		}
+	}
+	if wait := throttle.throttled(30 * time.Second); wait != 30*time.Second {
+		t.Errorf("expected the Retry-After to be honoured, got %s", wait)
+	}
+	if wait := throttle.remaining(); wait != 30*time.Second {
+		t.Errorf("expected the launches held for the Retry-After, got %s", wait)
+	}
+	if wait := throttle.throttled(time.Hour); wait != maxLaunchBackoff {
+		t.Errorf("expected the Retry-After capped at %s, got %s", maxLaunchBackoff, wait)
+	}
+	now = now.Add(maxLaunchBackoff)
+	if wait := throttle.remaining(); wait != 0 {
+		t.Errorf("expected the launches released after the backoff, got %s", wait)
+	}
+	throttle.succeeded()
+	if wait := throttle.throttled(0); wait != minLaunchBackoff {
+		t.Errorf("expected the backoff reset by a successful launch, got %s", wait)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "5", want: 5 * time.Second},
+		{header: "", want: 0},
+		{header: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tc.header)
+		if got := retryAfter(resp); got != tc.want {
+			t.Errorf("expected %s for %q, got %s", tc.want, tc.header, got)
+		}
+	}
+	if got := retryAfter(nil); got != 0 {
+		t.Errorf("expected no Retry-After without a response, got %s", got)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/samber/lo"
+	"github.com/zoom/karpenter-oci/pkg/providers/subnet"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	corev1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// zoneStats is used to balance the instances of a nodepool between the zones
+type zoneStats struct {
+	// nodeClaims is the number of the live and launching nodeclaims of the nodepool in the zone
+	nodeClaims int
+	// unavailableOfferings is the number of the offerings in the zone which recently returned an insufficient capacity error
+	unavailableOfferings int
+	// freeIPs is the number of the available IPs of the subnets in the zone
+	freeIPs int
+	// subnet is the least utilized subnet in the zone
+	subnet *subnetIPs
+}
+
+// launchingZones counts the instances being launched for the nodepools in each zone, the nodeclaims are labeled with
+// the zone only after the launch, so the concurrent launches of a nodepool have to be counted here
+type launchingZones struct {
+	mu sync.Mutex
+	// key: <nodepool>/<zone>
+	counts map[string]int
+}
+
+func newLaunchingZones() *launchingZones {
+	return &launchingZones{counts: map[string]int{}}
+}
+
+// track counts the launch until the returned func is called
+func (l *launchingZones) track(nodePool, zone string) func() {
+	key := fmt.Sprintf("%s/%s", nodePool, zone)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts[key]++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.counts[key]--; l.counts[key] <= 0 {
+			delete(l.counts, key)
+		}
+	}
+}
+
+func (l *launchingZones) count(nodePool, zone string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.counts[fmt.Sprintf("%s/%s", nodePool, zone)]
+}
+
+// subnetIPs is a subnet with the number of its available IPs
+type subnetIPs struct {
+	subnet    *core.Subnet
+	available int
+}
+
+// zoneStats collects the stats of the zones for the nodepool of the nodeclaim
+func (p *Provider) zoneStats(ctx context.Context, nodeClaim *corev1.NodeClaim, subnets []subnetIPs) (map[string]*zoneStats, error) {
+	stats := map[string]*zoneStats{}
+	get := func(zone string) *zoneStats {
+		if _, ok := stats[zone]; !ok {
+			stats[zone] = &zoneStats{unavailableOfferings: p.unavailableOfferings.ZoneUnavailableCount(zone)}
+			if nodePool := nodeClaim.Labels[corev1.NodePoolLabelKey]; nodePool != "" {
+				stats[zone].nodeClaims = p.launchingZones.count(nodePool, zone)
+			}
+		}
+		return stats[zone]
+	}
+	for i := range subnets {
+		for _, zone := range subnet.Zones(ctx, subnets[i].subnet) {
+			zs := get(zone)
+			zs.freeIPs += subnets[i].available
+			if zs.subnet == nil || subnets[i].available > zs.subnet.available {
+				zs.subnet = &subnets[i]
+			}
+		}
+	}
+	nodePool := nodeClaim.Labels[corev1.NodePoolLabelKey]
+	if nodePool == "" {
+		return stats, nil
+	}
+	nodeClaims := &corev1.NodeClaimList{}
+	if err := p.kubeClient.List(ctx, nodeClaims, client.MatchingLabels{corev1.NodePoolLabelKey: nodePool}); err != nil {
+		return nil, fmt.Errorf("listing nodeclaims of nodepool %s, %w", nodePool, err)
+	}
+	for _, nc := range nodeClaims.Items {
+		if zone, ok := nc.Labels[v1.LabelTopologyZone]; ok && nc.DeletionTimestamp.IsZero() {
+			get(zone).nodeClaims++
+		}
+	}
+	return stats, nil
+}
+
+// fitsSubnet returns true if the zone has a subnet with enough IPs for the instance
+func fitsSubnet(stats map[string]*zoneStats, zone string, ips int) bool {
+	zs, ok := stats[zone]
+	return ok && zs.subnet != nil && zs.subnet.available >= ips
+}
+
+// pickZoneOffering returns the offering in the zone with the lowest score, the score of a zone grows with the nodeclaims
+// of the nodepool and the recent insufficient capacity errors in the zone, and shrinks with the share of the free IPs
+// in the zone. The offerings with the same score are picked randomly.
+func pickZoneOffering(offerings []*corecloudprovider.Offering, stats map[string]*zoneStats) *corecloudprovider.Offering {
+	maxFreeIPs := lo.Max(lo.Map(offerings, func(o *corecloudprovider.Offering, _ int) int {
+		return lo.FromPtr(stats[o.Zone()]).freeIPs
+	}))
+	score := func(o *corecloudprovider.Offering) float64 {
+		zs := lo.FromPtr(stats[o.Zone()])
+		ipShare := 1.0
+		if maxFreeIPs > 0 {
+			ipShare = float64(zs.freeIPs) / float64(maxFreeIPs)
+		}
+		if ipShare == 0 {
+			return math.Inf(1)
+		}
+		return float64(zs.nodeClaims+1) * float64(zs.unavailableOfferings+1) / ipShare
+	}
+	var picked []*corecloudprovider.Offering
+	lowest := math.Inf(1)
+	for _, o := range offerings {
+		switch s := score(o); {
+		case s < lowest:
+			picked, lowest = []*corecloudprovider.Offering{o}, s
+		case s == lowest:
+			picked = append(picked, o)
+		}
+	}
+	if len(picked) == 0 {
+		return lo.Sample(offerings)
+	}
+	return lo.Sample(picked)
+}
@@ -17,10 +17,14 @@ package instancetype
 import (
 	"context"
 	"fmt"
+	"math"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/mitchellh/hashstructure/v2"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/patrickmn/go-cache"
@@ -31,17 +35,23 @@ import (
 	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
 	"github.com/zoom/karpenter-oci/pkg/providers/internalmodel"
+	"github.com/zoom/karpenter-oci/pkg/providers/limits"
 	"github.com/zoom/karpenter-oci/pkg/providers/pricing"
+	"github.com/zoom/karpenter-oci/pkg/providers/subnet"
 	"github.com/zoom/karpenter-oci/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	v1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
 )
 
 const (
 	InstanceTypesCacheKey = "types"
+	ShapesCacheKey        = "shapes"
 )
 
 var supportInstanceTypes = []string{v1.CapacityTypeOnDemand, v1alpha1.CapacityTypePreemptible}
@@ -53,58 +63,89 @@ type Provider struct {
 	cache                *cache.Cache
 	unavailableOfferings *ocicache.UnavailableOfferings
 	priceProvider        pricing.Provider
+	subnetProvider       *subnet.Provider
+	limitsProvider       *limits.Provider
 }
 
-func NewProvider(region string, compClient api.ComputeClient, cache *cache.Cache, unavailableOfferings *ocicache.UnavailableOfferings, priceProvide pricing.Provider) *Provider {
-	return &Provider{region: region, compClient: compClient, cache: cache, unavailableOfferings: unavailableOfferings, priceProvider: priceProvide}
+func NewProvider(region string, compClient api.ComputeClient, cache *cache.Cache, unavailableOfferings *ocicache.UnavailableOfferings, priceProvide pricing.Provider, subnetProvider *subnet.Provider, limitsProvider *limits.Provider) *Provider {
+	return &Provider{region: region, compClient: compClient, cache: cache, unavailableOfferings: unavailableOfferings, priceProvider: priceProvide, subnetProvider: subnetProvider, limitsProvider: limitsProvider}
 }
 
 func (p *Provider) List(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) ([]*cloudprovider.InstanceType, error) {
 
-	wrapShapes, err := p.ListInstanceType(ctx)
+	wrapShapes, err := p.ListInstanceType(ctx, nodeClass)
 	if err != nil {
 		return nil, err
 	}
+	p.resetQuotaExceeded(ctx, nodeClass, wrapShapes)
 	instanceTypes := make([]*cloudprovider.InstanceType, 0)
 	for _, wrapped := range wrapShapes {
-		instanceTypes = append(instanceTypes, NewInstanceType(ctx, wrapped, nodeClass, p.region, wrapped.AvailableDomains, p.CreateOfferings(ctx, wrapped, sets.New(wrapped.AvailableDomains...))))
+		if !SupportPlatformConfig(wrapped.Shape, nodeClass.Spec.PlatformConfig) {
+			continue
+		}
+		instanceTypes = append(instanceTypes, NewInstanceType(ctx, wrapped, nodeClass, p.region, wrapped.AvailableDomains, p.CreateOfferings(ctx, nodeClass, wrapped, sets.New(wrapped.AvailableDomains...))))
 	}
 	return instanceTypes, nil
 
 }
 
-func (p *Provider) CreateOfferings(ctx context.Context, shape *internalmodel.WrapShape, zones sets.Set[string]) []*cloudprovider.Offering {
+func (p *Provider) CreateOfferings(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, shape *internalmodel.WrapShape, zones sets.Set[string]) []*cloudprovider.Offering {
 	var offerings []*cloudprovider.Offering
+	volumePrice := p.volumePrice(nodeClass)
+	// the dynamic flex shapes are sized at launch, their offerings are split per size and priced by the size, so a node
+	// sized at launch is priced by its size. Consolidation prices a replacement by its most expensive offering, so the
+	// dynamic flex shape isn't cheaper than the nodes which it would be sized to.
+	sizes := []*internalmodel.WrapShape{shape}
+	if shape.CalDynamic {
+		sizes = dynamicFlexSizes(shape, nodeClass.Spec.FlexShapeConfig)
+	}
+	subnetZones := p.subnetZones(nodeClass, RequiredIPs(nodeClass, pods(shape, nodeClass.Spec.Kubelet).Value()))
 
 	for zone := range zones {
+		// the smallest instance of a dynamic flex shape must fit the limits
+		limitExhausted := p.limitExhausted(ctx, sizes[0], zone)
 		for _, capacityType := range supportInstanceTypes {
 			// exclude any offerings that have recently seen an insufficient capacity error
 			isUnavailable := p.unavailableOfferings.IsUnavailable(*shape.Shape.Shape, zone, capacityType)
-
-			price := float64(p.priceProvider.Price(shape))
-			if capacityType == v1alpha1.CapacityTypePreemptible {
-				// Filters shapes that preemptible is supported
-				if supportPreemptible(ctx, *shape.Shape.Shape) {
+			// instances can't be launched in a zone without a subnet which can fit the IPs of the instance
+			if subnetZones != nil && !subnetZones.Has(zone) {
+				isUnavailable = true
+			}
+			// on-demand and preemptible instances count against the same limits, reserved instances are counted by
+			// their reservations
+			if limitExhausted {
+				isUnavailable = true
+			}
+			// Non-VM shapes aren't supported as preemptible
+			if capacityType == v1alpha1.CapacityTypePreemptible && !supportPreemptible(ctx, *shape.Shape.Shape) {
+				isUnavailable = true
+			}
+			for _, size := range sizes {
+				price := float64(p.priceProvider.Price(size))
+				if capacityType == v1alpha1.CapacityTypePreemptible {
 					// Preemptible is 50% OFF of on-demand price
 					price = price * 0.5
-				} else {
-					// Non-VM shapes aren't supported as preemptible
-					isUnavailable = true
 				}
+				// volumes aren't discounted for preemptible instances
+				price += volumePrice
+				offerReq := scheduling.NewRequirements(
+					scheduling.NewRequirement(v1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType),
+					scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, zone),
+				)
+				if shape.CalDynamic {
+					offerReq.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceCPU, corev1.NodeSelectorOpIn, fmt.Sprintf("%d", size.CalcCpu)),
+						scheduling.NewRequirement(v1alpha1.LabelInstanceBaselineOcpuUtilization, corev1.NodeSelectorOpIn, baselineOcpuUtilization(shape)))
+				} else if lo.FromPtr(shape.IsFlexible) {
+					offerReq.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceCPU, corev1.NodeSelectorOpIn, fmt.Sprintf("%d", shape.CalcCpu)),
+						scheduling.NewRequirement(v1alpha1.LabelInstanceMemory, corev1.NodeSelectorOpIn, fmt.Sprintf("%d", shape.CalMemInGBs*1024)),
+						scheduling.NewRequirement(v1alpha1.LabelInstanceBaselineOcpuUtilization, corev1.NodeSelectorOpIn, baselineOcpuUtilization(shape)))
+				}
+				offerings = append(offerings, &cloudprovider.Offering{
+					Requirements: offerReq,
+					Price:        price,
+					Available:    !isUnavailable,
+				})
 			}
-			offerReq := scheduling.NewRequirements(
-				scheduling.NewRequirement(v1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType),
-				scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, zone),
-			)
-			if lo.FromPtr(shape.IsFlexible) {
-				offerReq.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceCPU, corev1.NodeSelectorOpIn, fmt.Sprintf("%d", shape.CalcCpu)),
-					scheduling.NewRequirement(v1alpha1.LabelInstanceMemory, corev1.NodeSelectorOpIn, fmt.Sprintf("%d", shape.CalMemInGBs*1024)))
-			}
-			offerings = append(offerings, &cloudprovider.Offering{
-				Requirements: offerReq,
-				Price:        price,
-				Available:    !isUnavailable,
-			})
 			// metric
 			// add ondemand instances metrics
 			instanceTypeOfferingAvailable.With(prometheus.Labels{
@@ -113,16 +154,181 @@ func (p *Provider) CreateOfferings(ctx context.Context, shape *internalmodel.Wra
 				zoneLabel:         zone,
 			}).Set(float64(lo.Ternary(!isUnavailable, 1, 0)))
 
+			// the dynamic flex shapes report the price of the smallest size
 			instanceTypeOfferingPriceEstimate.With(prometheus.Labels{
-				instanceTypeLabel: fmt.Sprintf("%s_%d_%d", *shape.Shape.Shape, shape.CalcCpu/2, shape.CalMemInGBs),
+				instanceTypeLabel: fmt.Sprintf("%s_%d_%d", *shape.Shape.Shape, sizes[0].CalcCpu/2, sizes[0].CalMemInGBs),
 				capacityTypeLabel: capacityType,
 				zoneLabel:         zone,
-			}).Set(price)
+			}).Set(offerings[len(offerings)-len(sizes)].Price)
+		}
+		// the reservations aren't tracked by the scheduling without the ReservedCapacity feature gate
+		if coreoptions.FromContext(ctx).FeatureGates.ReservedCapacity {
+			offerings = append(offerings, p.createReservedOfferings(shape, zone, nodeClass.Status.CapacityReservations, volumePrice, subnetZones == nil || subnetZones.Has(zone))...)
+		}
+	}
+	return offerings
+}
+
+// subnetZones returns the zones of the subnets in the nodeclass status which can fit the IPs of another instance, nil
+// if the zones of the subnets aren't resolved. The subnets whose utilization isn't cached are assumed to fit.
+func (p *Provider) subnetZones(nodeClass *v1alpha1.OciNodeClass, ips int) sets.Set[string] {
+	if !lo.SomeBy(nodeClass.Status.Subnets, func(subnet *v1alpha1.Subnet) bool { return len(subnet.AvailabilityDomains) != 0 }) {
+		return nil
+	}
+	zones := sets.New[string]()
+	for _, subnet := range nodeClass.Status.Subnets {
+		if available, ok := p.subnetProvider.CachedAvailableIPs(subnet.Id); ok && available < ips {
+			continue
+		}
+		zones.Insert(subnet.AvailabilityDomains...)
+	}
+	return zones
+}
+
+// limitExhausted returns true if the remaining service limits or compartment quotas of the shape family in the zone
+// can't fit another instance of the shape, the offerings are available when the limits can't be resolved
+func (p *Provider) limitExhausted(ctx context.Context, shape *internalmodel.WrapShape, zone string) bool {
+	remaining, ok := p.remainingLimits(ctx, shape, zone)
+	return ok && !remaining.Fits(limitOcpus(shape), float64(shape.CalMemInGBs))
+}
+
+// resetQuotaExceeded makes the offerings which exceeded the compartment quotas available again once the quotas can fit
+// the smallest instance of the shape in the zone
+func (p *Provider) resetQuotaExceeded(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, wrapShapes map[string]*internalmodel.WrapShape) {
+	p.unavailableOfferings.ResetQuotaExceeded(func(instanceType, zone string) bool {
+		return lo.SomeBy(lo.Values(wrapShapes), func(shape *internalmodel.WrapShape) bool {
+			if *shape.Shape.Shape != instanceType {
+				return false
+			}
+			if shape.CalDynamic {
+				shape = dynamicFlexSizes(shape, nodeClass.Spec.FlexShapeConfig)[0]
+			}
+			remaining, ok := p.remainingLimits(ctx, shape, zone)
+			return ok && (remaining.Cores != nil || remaining.MemoryInGBs != nil) && remaining.Fits(limitOcpus(shape), float64(shape.CalMemInGBs))
+		})
+	})
+}
+
+// remainingLimits returns the remaining limits of the shape family in the zone, false if the limits can't be resolved.
+// The failures are cached by the limits provider, so a failure is logged once until it expires.
+func (p *Provider) remainingLimits(ctx context.Context, shape *internalmodel.WrapShape, zone string) (limits.Remaining, bool) {
+	remaining, err := p.limitsProvider.Remaining(ctx, *shape.Shape.Shape, zone)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to resolve the remaining limits, ignoring the limits", "instance-type", *shape.Shape.Shape, "zone", zone)
+		return limits.Remaining{}, false
+	}
+	if remaining.Cores != nil {
+		instanceTypeLimitRemaining.With(prometheus.Labels{instanceTypeLabel: *shape.Shape.Shape, zoneLabel: zone, resourceLabel: "cores"}).Set(*remaining.Cores)
+	}
+	if remaining.MemoryInGBs != nil {
+		instanceTypeLimitRemaining.With(prometheus.Labels{instanceTypeLabel: *shape.Shape.Shape, zoneLabel: zone, resourceLabel: "memory_gbs"}).Set(*remaining.MemoryInGBs)
+	}
+	return remaining, true
+}
+
+// limitOcpus returns the ocpus of the shape, the limits are sized in ocpus
+func limitOcpus(shape *internalmodel.WrapShape) float64 {
+	return float64(shape.CalcCpu) / float64(utils.VcpusPerOcpu(shape.Shape))
+}
+
+// volumePrice returns the hourly price of the boot volume and the block volumes launched with each instance
+func (p *Provider) volumePrice(nodeClass *v1alpha1.OciNodeClass) float64 {
+	var price float32
+	if nodeClass.Spec.BootConfig != nil {
+		price += p.priceProvider.VolumePrice(nodeClass.Spec.BootConfig.BootVolumeSizeInGBs, nodeClass.Spec.BootConfig.BootVolumeVpusPerGB)
+	}
+	for _, device := range nodeClass.Spec.BlockDevices {
+		price += p.priceProvider.VolumePrice(device.SizeInGBs, device.VpusPerGB)
+	}
+	return float64(price)
+}
+
+func (p *Provider) createReservedOfferings(shape *internalmodel.WrapShape, zone string, reservations []*v1alpha1.CapacityReservation, volumePrice float64, hasSubnet bool) []*cloudprovider.Offering {
+	var offerings []*cloudprovider.Offering
+	for _, reservation := range reservations {
+		if reservation.AvailabilityDomain != zone || !matchReservation(shape, reservation) {
+			continue
+		}
+		isUnavailable := p.unavailableOfferings.IsUnavailable(*shape.Shape.Shape, zone, v1.CapacityTypeReserved) || !hasSubnet
+		remaining := int(max(0, reservation.ReservedCount-reservation.UsedCount))
+		// reserved capacity is already paid for, keep a tiny price so it's preferred while the relative order stays,
+		// the volumes are charged separately
+		price := float64(p.priceProvider.Price(shape))/10_000_000.0 + volumePrice
+		offerReq := scheduling.NewRequirements(
+			scheduling.NewRequirement(v1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, v1.CapacityTypeReserved),
+			scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, zone),
+			scheduling.NewRequirement(cloudprovider.ReservationIDLabel, corev1.NodeSelectorOpIn, reservation.Id),
+		)
+		if lo.FromPtr(shape.IsFlexible) {
+			offerReq.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceCPU, corev1.NodeSelectorOpIn, fmt.Sprintf("%d", shape.CalcCpu)),
+				scheduling.NewRequirement(v1alpha1.LabelInstanceMemory, corev1.NodeSelectorOpIn, fmt.Sprintf("%d", shape.CalMemInGBs*1024)),
+				scheduling.NewRequirement(v1alpha1.LabelInstanceBaselineOcpuUtilization, corev1.NodeSelectorOpIn, baselineOcpuUtilization(shape)))
 		}
+		offerings = append(offerings, &cloudprovider.Offering{
+			Requirements:        offerReq,
+			Price:               price,
+			Available:           !isUnavailable && remaining > 0,
+			ReservationCapacity: remaining,
+		})
+		instanceTypeOfferingAvailable.With(prometheus.Labels{
+			instanceTypeLabel: *shape.Shape.Shape,
+			capacityTypeLabel: v1.CapacityTypeReserved,
+			zoneLabel:         zone,
+		}).Set(float64(lo.Ternary(!isUnavailable && remaining > 0, 1, 0)))
 	}
 	return offerings
 }
 
+// matchReservation checks the reserved shape config, for flexible shapes the ocpus and memory must be the same as the wrapped shape
+func matchReservation(shape *internalmodel.WrapShape, reservation *v1alpha1.CapacityReservation) bool {
+	if reservation.InstanceShape != *shape.Shape.Shape {
+		return false
+	}
+	if !lo.FromPtr(shape.IsFlexible) {
+		return true
+	}
+	// burstable instances can't be launched into the reservations, the dynamic flex shapes aren't sized to the reservations
+	if shape.CalBaselineOcpuUtilization != "" || shape.CalDynamic {
+		return false
+	}
+	ratioFactor := int64(utils.VcpusPerOcpu(shape.Shape))
+	return reservation.Ocpus*ratioFactor == shape.CalcCpu && reservation.MemoryInGBs == shape.CalMemInGBs
+}
+
+// GetShape returns the shape details of the instance type
+func (p *Provider) GetShape(ctx context.Context, name string) (*core.Shape, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	adShapesMap, err := p.listShapes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, shapes := range adShapesMap {
+		for i := range shapes {
+			if lo.FromPtr(shapes[i].Shape) == name {
+				return &shapes[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("shape %s not found", name)
+}
+
+// SupportPlatformConfig returns true if the shape allows all the features enabled in the platform config
+func SupportPlatformConfig(shape core.Shape, platformConfig *v1alpha1.PlatformConfig) bool {
+	if platformConfig == nil {
+		return true
+	}
+	allowed := func(enabled *bool, allowedValues []bool) bool {
+		return !lo.FromPtr(enabled) || lo.Contains(allowedValues, true)
+	}
+	platformOptions := lo.FromPtr(shape.PlatformConfigOptions)
+	return allowed(platformConfig.IsSecureBootEnabled, lo.FromPtr(platformOptions.SecureBootOptions).AllowedValues) &&
+		allowed(platformConfig.IsTrustedPlatformModuleEnabled, lo.FromPtr(platformOptions.TrustedPlatformModuleOptions).AllowedValues) &&
+		allowed(platformConfig.IsMeasuredBootEnabled, lo.FromPtr(platformOptions.MeasuredBootOptions).AllowedValues) &&
+		allowed(platformConfig.IsMemoryEncryptionEnabled, lo.FromPtr(platformOptions.MemoryEncryptionOptions).AllowedValues)
+}
+
 func supportPreemptible(ctx context.Context, shapeName string) bool {
 	preemptibleList := strings.Split(options.FromContext(ctx).PreemptibleShapes, ",")
 	excludeList := strings.Split(options.FromContext(ctx).PreemptibleExcludeShapes, ",")
@@ -130,14 +336,51 @@ func supportPreemptible(ctx context.Context, shapeName string) bool {
 		!lo.ContainsBy(excludeList, func(s string) bool { return strings.HasPrefix(shapeName, s) })
 }
 
-func (p *Provider) ListInstanceType(ctx context.Context) (map[string]*internalmodel.WrapShape, error) {
+// ListInstanceType returns the instance types of the shapes, the flexible shapes are split by the flex shape config of the nodeclass
+func (p *Provider) ListInstanceType(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) (map[string]*internalmodel.WrapShape, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if cached, ok := p.cache.Get(InstanceTypesCacheKey); ok {
+	flexShapeConfig := nodeClass.Spec.FlexShapeConfig
+	hash, err := hashstructure.Hash(flexShapeConfig, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := fmt.Sprintf("%s-%d", InstanceTypesCacheKey, hash)
+	if cached, ok := p.cache.Get(cacheKey); ok {
 		return cached.(map[string]*internalmodel.WrapShape), nil
 	}
-	adShapesMap := make(map[string][]*internalmodel.WrapShape, 0)
+	adShapesMap, err := p.listShapes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// combine zones
+	wrapShapes := make(map[string]*internalmodel.WrapShape, 0)
+	for ad, adShapes := range adShapesMap {
+		for _, shape := range toWrapShape(ctx, adShapes, ad, flexShapeConfig) {
+			// metric
+			instanceTypeVCPU.With(prometheus.Labels{instanceTypeLabel: *shape.Shape.Shape}).Set(float64(shape.CalcCpu))
+			instanceTypeMemory.With(prometheus.Labels{instanceTypeLabel: *shape.Shape.Shape}).Set(float64(lo.FromPtr(shape.MemoryInGBs)) * 1024 * 1024 * 1024)
+
+			key := fmt.Sprintf("%s-%d-%d%s", *shape.Shape.Shape, shape.CalcCpu, shape.CalMemInGBs, shape.CalBaselineOcpuUtilization)
+			if wrapped, ok := wrapShapes[key]; !ok {
+				wrapShapes[key] = shape
+			} else {
+				wrapped.AvailableDomains = append(wrapped.AvailableDomains, ad)
+			}
+		}
+	}
+
+	p.cache.SetDefault(cacheKey, wrapShapes)
+	return wrapShapes, nil
+}
+
+// listShapes returns the shapes of the available domains keyed by the zone, the caller must hold the lock
+func (p *Provider) listShapes(ctx context.Context) (map[string][]core.Shape, error) {
+	if cached, ok := p.cache.Get(ShapesCacheKey); ok {
+		return cached.(map[string][]core.Shape), nil
+	}
+	adShapesMap := make(map[string][]core.Shape, 0)
 	for _, availableDomain := range options.FromContext(ctx).AvailableDomains {
 		shapes := make([]core.Shape, 0)
 		nextPage := "0"
@@ -165,44 +408,23 @@ func (p *Provider) ListInstanceType(ctx context.Context) (map[string]*internalmo
 		}
 
 		ad := strings.Split(availableDomain, ":")[1]
-
-		if old, ok := adShapesMap[ad]; ok {
-			adShapesMap[ad] = append(old, toWrapShape(ctx, shapes, ad)...)
-		} else {
-			adShapesMap[ad] = toWrapShape(ctx, shapes, ad)
-		}
-
+		adShapesMap[ad] = append(adShapesMap[ad], shapes...)
 	}
-	// combine zones
-	wrapShapes := make(map[string]*internalmodel.WrapShape, 0)
-	for ad, shapes := range adShapesMap {
-		for _, shape := range shapes {
-			// metric
-			instanceTypeVCPU.With(prometheus.Labels{instanceTypeLabel: *shape.Shape.Shape}).Set(float64(shape.CalcCpu))
-			instanceTypeMemory.With(prometheus.Labels{instanceTypeLabel: *shape.Shape.Shape}).Set(float64(lo.FromPtr(shape.MemoryInGBs)) * 1024 * 1024 * 1024)
-
-			if wrapped, ok := wrapShapes[fmt.Sprintf("%s-%d-%d", *shape.Shape.Shape, shape.CalcCpu, shape.CalMemInGBs)]; !ok {
-				wrapShapes[fmt.Sprintf("%s-%d-%d", *shape.Shape.Shape, shape.CalcCpu, shape.CalMemInGBs)] = shape
-			} else {
-				wrapped.AvailableDomains = append(wrapped.AvailableDomains, ad)
-			}
-		}
-	}
-
-	p.cache.SetDefault(InstanceTypesCacheKey, wrapShapes)
-	return wrapShapes, nil
+	p.cache.SetDefault(ShapesCacheKey, adShapesMap)
+	return adShapesMap, nil
 }
 
-func toWrapShape(ctx context.Context, shapes []core.Shape, ad string) []*internalmodel.WrapShape {
+func toWrapShape(ctx context.Context, shapes []core.Shape, ad string, flexShapeConfig *v1alpha1.FlexShapeConfig) []*internalmodel.WrapShape {
 	wrapShapes := make([]*internalmodel.WrapShape, 0)
 	for _, shape := range shapes {
-		if *shape.IsFlexible {
-			wrapShapes = append(wrapShapes, splitFlexCpuMem(ctx, shape, ad)...)
+		if *shape.IsFlexible && isDynamicSizing(flexShapeConfig) {
+			wrapShapes = append(wrapShapes, dynamicFlexShapes(ctx, shape, ad, flexShapeConfig)...)
+		} else if *shape.IsFlexible {
+			wrapShapes = append(wrapShapes, splitFlexCpuMem(ctx, shape, ad, flexShapeConfig)...)
 		} else {
 			wrapShapes = append(wrapShapes, &internalmodel.WrapShape{
-				Shape: shape,
-				// ocpus is twice vcpu
-				CalcCpu:               int64(*shape.Ocpus) * 2,
+				Shape:                 shape,
+				CalcCpu:               int64(*shape.Ocpus) * int64(utils.VcpusPerOcpu(shape)),
 				CalMemInGBs:           int64(*shape.MemoryInGBs),
 				AvailableDomains:      []string{ad},
 				CalMaxVnic:            int64(*shape.MaxVnicAttachments),
@@ -213,62 +435,265 @@ func toWrapShape(ctx context.Context, shapes []core.Shape, ad string) []*interna
 	return wrapShapes
 }
 
-func splitFlexCpuMem(ctx context.Context, shape core.Shape, ad string) []*internalmodel.WrapShape {
-	flexCpuMemRatios := strings.Split(options.FromContext(ctx).FlexCpuMemRatios, ",")
-	constrainCpus := strings.Split(options.FromContext(ctx).FlexCpuConstrainList, ",")
+func splitFlexCpuMem(ctx context.Context, shape core.Shape, ad string, flexShapeConfig *v1alpha1.FlexShapeConfig) []*internalmodel.WrapShape {
+	constrainCpus, flexCpuMemRatios := flexShapeSizes(ctx, flexShapeConfig)
+	burstableBaselines := flexBurstableBaselines(ctx, shape)
 	wrapShapes := make([]*internalmodel.WrapShape, 0)
 
 	// Determine OCPU-to-vCPU multiplier based on shape
-	shapeName := *shape.Shape
-	ratioFactor := 2
-	if utils.IsA1FlexShape(shapeName) {
-		ratioFactor = 1
-	}
-	for i := 0; i < len(constrainCpus); i++ {
-		for _, ratio := range flexCpuMemRatios {
-			ratioInt, covErr := strconv.Atoi(ratio)
-			if covErr != nil {
-				continue
-			}
-			cpus, covErr := strconv.Atoi(constrainCpus[i])
-			if covErr != nil {
+	ratioFactor := utils.VcpusPerOcpu(shape)
+	for _, cpus := range constrainCpus {
+		for _, ratioInt := range flexCpuMemRatios {
+			memInGBs := cpus * ratioFactor * ratioInt
+			if !inMemoryRanges(flexShapeConfig, memInGBs) {
 				continue
 			}
-			memInGBs := cpus * ratioFactor * ratioInt
 			if cpus < int(*shape.OcpuOptions.Min) || memInGBs < int(*shape.MemoryOptions.MinInGBs) {
 				continue
 			}
 			if cpus > int(*shape.OcpuOptions.Max) || memInGBs > int(*shape.MemoryOptions.MaxInGBs) {
 				continue
 			}
-			var calMaxVnic int64
-			// https://docs.oracle.com/en-us/iaas/Content/Compute/References/computeshapes.htm
-			if shape.MaxVnicAttachmentOptions != nil && shape.MaxVnicAttachmentOptions.DefaultPerOcpu != nil {
-				if cpus == 1 {
-					calMaxVnic = 2
-				} else {
-					calMaxVnic = int64(*shape.MaxVnicAttachmentOptions.DefaultPerOcpu) * int64(cpus)
-				}
-				calMaxVnic = min(24, calMaxVnic)
-			} else {
-				calMaxVnic = int64(*shape.MaxVnicAttachments)
-			}
-			var calMaxBandwidth int64
-			if shape.NetworkingBandwidthOptions != nil && shape.NetworkingBandwidthOptions.DefaultPerOcpuInGbps != nil {
-				calMaxBandwidth = int64(*shape.NetworkingBandwidthOptions.DefaultPerOcpuInGbps) * int64(cpus)
-				calMaxBandwidth = max(int64(*shape.NetworkingBandwidthOptions.MinInGbps), min(int64(*shape.NetworkingBandwidthOptions.MaxInGbps), calMaxBandwidth))
-			} else {
-				calMaxBandwidth = int64(*shape.NetworkingBandwidthInGbps)
+			wrapShapes = append(wrapShapes, newFlexWrapShape(shape, []string{ad}, cpus, memInGBs, ""))
+			for _, baseline := range burstableBaselines {
+				wrapShapes = append(wrapShapes, newFlexWrapShape(shape, []string{ad}, cpus, memInGBs, baseline))
 			}
-			wrapShapes = append(wrapShapes, &internalmodel.WrapShape{
-				Shape:                 shape,
-				CalcCpu:               int64(cpus * ratioFactor),
-				CalMemInGBs:           int64(memInGBs),
-				AvailableDomains:      []string{ad},
-				CalMaxVnic:            calMaxVnic,
-				CalMaxBandwidthInGbps: calMaxBandwidth,
-			})
 		}
 	}
 	return wrapShapes
 }
+
+// dynamicFlexShapes returns one wrapped shape per baseline of the flex shape, the instance types are created with the
+// max sizes of the shape and sized to fit the nodeclaim at launch
+func dynamicFlexShapes(ctx context.Context, shape core.Shape, ad string, flexShapeConfig *v1alpha1.FlexShapeConfig) []*internalmodel.WrapShape {
+	ocpus := dynamicFlexOcpus(shape, flexShapeConfig)
+	if len(ocpus) == 0 {
+		return nil
+	}
+	maxOcpus := lo.Max(ocpus)
+	_, maxMemInGBs := flexMemoryBounds(shape, maxOcpus)
+	if flexShapeConfig != nil && len(flexShapeConfig.MemoryRanges) != 0 {
+		maxMemInGBs = min(maxMemInGBs, int(lo.MaxBy(flexShapeConfig.MemoryRanges, func(a, b v1alpha1.MemoryRange) bool {
+			return a.MaxInGBs > b.MaxInGBs
+		}).MaxInGBs))
+	}
+	wrapShapes := make([]*internalmodel.WrapShape, 0)
+	for _, baseline := range append([]string{""}, flexBurstableBaselines(ctx, shape)...) {
+		wrapped := newFlexWrapShape(shape, []string{ad}, maxOcpus, maxMemInGBs, baseline)
+		wrapped.CalDynamic = true
+		wrapShapes = append(wrapShapes, wrapped)
+	}
+	return wrapShapes
+}
+
+// newFlexWrapShape wraps the flex shape sized with the ocpus and memory
+func newFlexWrapShape(shape core.Shape, ads []string, cpus int, memInGBs int, baseline string) *internalmodel.WrapShape {
+	var calMaxVnic int64
+	// https://docs.oracle.com/en-us/iaas/Content/Compute/References/computeshapes.htm
+	if shape.MaxVnicAttachmentOptions != nil && shape.MaxVnicAttachmentOptions.DefaultPerOcpu != nil {
+		if cpus == 1 {
+			calMaxVnic = 2
+		} else {
+			calMaxVnic = int64(*shape.MaxVnicAttachmentOptions.DefaultPerOcpu) * int64(cpus)
+		}
+		calMaxVnic = min(24, calMaxVnic)
+	} else {
+		calMaxVnic = int64(*shape.MaxVnicAttachments)
+	}
+	var calMaxBandwidth int64
+	if shape.NetworkingBandwidthOptions != nil && shape.NetworkingBandwidthOptions.DefaultPerOcpuInGbps != nil {
+		calMaxBandwidth = int64(*shape.NetworkingBandwidthOptions.DefaultPerOcpuInGbps) * int64(cpus)
+		calMaxBandwidth = max(int64(*shape.NetworkingBandwidthOptions.MinInGbps), min(int64(*shape.NetworkingBandwidthOptions.MaxInGbps), calMaxBandwidth))
+	} else {
+		calMaxBandwidth = int64(*shape.NetworkingBandwidthInGbps)
+	}
+	return &internalmodel.WrapShape{
+		Shape:                      shape,
+		CalcCpu:                    int64(cpus * utils.VcpusPerOcpu(shape)),
+		CalMemInGBs:                int64(memInGBs),
+		AvailableDomains:           ads,
+		CalMaxVnic:                 calMaxVnic,
+		CalMaxBandwidthInGbps:      calMaxBandwidth,
+		CalBaselineOcpuUtilization: baseline,
+	}
+}
+
+// flexBurstableBaselines returns the burstable baselines of the options which are supported by the shape
+func flexBurstableBaselines(ctx context.Context, shape core.Shape) []string {
+	return lo.Filter(strings.Split(options.FromContext(ctx).FlexBurstableBaselines, ","), func(item string, _ int) bool {
+		return lo.Contains(shape.BaselineOcpuUtilizations, core.ShapeBaselineOcpuUtilizationsEnum(item))
+	})
+}
+
+func isDynamicSizing(flexShapeConfig *v1alpha1.FlexShapeConfig) bool {
+	return flexShapeConfig != nil && lo.FromPtr(flexShapeConfig.Sizing) == v1alpha1.FlexShapeSizingDynamic
+}
+
+// dynamicFlexOcpus returns the ocpu counts the dynamic flex shape can be sized with in ascending order,
+// limited by the ocpus and ocpu ranges of the flex shape config when they are set
+func dynamicFlexOcpus(shape core.Shape, flexShapeConfig *v1alpha1.FlexShapeConfig) []int {
+	minOcpus, maxOcpus := int(math.Ceil(float64(lo.FromPtr(shape.OcpuOptions.Min)))), int(lo.FromPtr(shape.OcpuOptions.Max))
+	ocpus := lo.RangeFrom(minOcpus, max(0, maxOcpus-minOcpus+1))
+	if flexShapeConfig != nil && (len(flexShapeConfig.Ocpus) != 0 || len(flexShapeConfig.OcpuRanges) != 0) {
+		ocpus = lo.Uniq(flexShapeOcpus(flexShapeConfig))
+	}
+	ocpus = lo.Filter(ocpus, func(item int, _ int) bool { return item >= minOcpus && item <= maxOcpus })
+	slices.Sort(ocpus)
+	return ocpus
+}
+
+// flexMemoryBounds returns the min and max memory sizes of the flex shape with the ocpus, limited by the per ocpu memory of the shape
+func flexMemoryBounds(shape core.Shape, ocpus int) (int, int) {
+	memoryOptions := lo.FromPtr(shape.MemoryOptions)
+	minInGBs, maxInGBs := int(math.Ceil(float64(lo.FromPtr(memoryOptions.MinInGBs)))), int(lo.FromPtr(memoryOptions.MaxInGBs))
+	if memoryOptions.MinPerOcpuInGBs != nil {
+		minInGBs = max(minInGBs, int(math.Ceil(float64(*memoryOptions.MinPerOcpuInGBs)*float64(ocpus))))
+	}
+	if memoryOptions.MaxPerOcpuInGBs != nil {
+		maxInGBs = min(maxInGBs, int(*memoryOptions.MaxPerOcpuInGBs*float32(ocpus)))
+	}
+	return minInGBs, maxInGBs
+}
+
+// dynamicFlexSizes returns the sizes of the dynamic flex shape in ascending order, one per ocpu count with the smallest
+// memory of the ocpus which is allowed by the flex shape config
+func dynamicFlexSizes(shape *internalmodel.WrapShape, flexShapeConfig *v1alpha1.FlexShapeConfig) []*internalmodel.WrapShape {
+	sizes := lo.FilterMap(dynamicFlexOcpus(shape.Shape, flexShapeConfig), func(ocpus int, _ int) (*internalmodel.WrapShape, bool) {
+		minMemInGBs, maxMemInGBs := flexMemoryBounds(shape.Shape, ocpus)
+		memInGBs, ok := lo.Find(lo.RangeFrom(minMemInGBs, max(0, min(maxMemInGBs, int(shape.CalMemInGBs))-minMemInGBs+1)), func(memInGBs int) bool {
+			return inMemoryRanges(flexShapeConfig, memInGBs)
+		})
+		if !ok {
+			return nil, false
+		}
+		return newFlexWrapShape(shape.Shape, shape.AvailableDomains, ocpus, memInGBs, shape.CalBaselineOcpuUtilization), true
+	})
+	// the memory ranges may exclude every size, the instance type is priced by the smallest size of the shape then
+	if len(sizes) == 0 {
+		return []*internalmodel.WrapShape{smallestFlexShape(shape)}
+	}
+	return sizes
+}
+
+// smallestFlexShape returns the smallest size of the dynamic flex shape
+func smallestFlexShape(shape *internalmodel.WrapShape) *internalmodel.WrapShape {
+	ocpus := int(math.Ceil(float64(lo.FromPtr(shape.OcpuOptions.Min))))
+	minMemInGBs, _ := flexMemoryBounds(shape.Shape, ocpus)
+	return newFlexWrapShape(shape.Shape, shape.AvailableDomains, ocpus, minMemInGBs, shape.CalBaselineOcpuUtilization)
+}
+
+// IsDynamicFlex returns true if the instance type is a dynamic flex shape which is sized at launch
+func IsDynamicFlex(instanceType *cloudprovider.InstanceType) bool {
+	return instanceType.Requirements.Get(v1alpha1.LabelIsFlexible).Has("true") &&
+		instanceType.Requirements.Get(v1alpha1.LabelInstanceCPU).Len() != 1
+}
+
+// FitFlexInstanceType returns the instance type of the dynamic flex shape sized with the smallest ocpus and memory which
+// fit the resource requests of the nodeclaim, the sizes are limited by the shape, the flex shape config and the nodeclaim requirements
+func (p *Provider) FitFlexInstanceType(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, instanceType *cloudprovider.InstanceType, nodeClaim *v1.NodeClaim) (*cloudprovider.InstanceType, error) {
+	shape, err := p.GetShape(ctx, instanceType.Name)
+	if err != nil {
+		return nil, err
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	baseline := dynamicBaseline(instanceType)
+	ratioFactor := utils.VcpusPerOcpu(*shape)
+	fits := func(ocpus int, memInGBs int) bool {
+		wrapped := newFlexWrapShape(*shape, nil, ocpus, memInGBs, baseline)
+		return resources.Fits(nodeClaim.Spec.Resources.Requests, NewInstanceType(ctx, wrapped, nodeClass, p.region, nil, nil).Allocatable())
+	}
+	for _, ocpus := range dynamicFlexOcpus(*shape, nodeClass.Spec.FlexShapeConfig) {
+		if !reqs.Get(v1alpha1.LabelInstanceCPU).Has(fmt.Sprint(ocpus * ratioFactor)) {
+			continue
+		}
+		minMemInGBs, maxMemInGBs := flexMemoryBounds(*shape, ocpus)
+		memSizes := lo.Filter(lo.RangeFrom(minMemInGBs, max(0, maxMemInGBs-minMemInGBs+1)), func(memInGBs int, _ int) bool {
+			return inMemoryRanges(nodeClass.Spec.FlexShapeConfig, memInGBs) && reqs.Get(v1alpha1.LabelInstanceMemory).Has(fmt.Sprint(memInGBs*1024))
+		})
+		if len(memSizes) == 0 || !fits(ocpus, memSizes[len(memSizes)-1]) {
+			continue
+		}
+		// the allocatable memory grows with the memory size, take the smallest one which fits
+		memInGBs := memSizes[sort.Search(len(memSizes), func(i int) bool { return fits(ocpus, memSizes[i]) })]
+		return p.sizedFlexInstanceType(ctx, nodeClass, instanceType, *shape, ocpus, memInGBs), nil
+	}
+	return nil, fmt.Errorf("no size of flex shape %s fits the resource requests of nodeclaim %s", instanceType.Name, nodeClaim.Name)
+}
+
+// ResolveFlexInstanceType returns the instance type of the dynamic flex shape sized with the shape config of the launched instance
+func (p *Provider) ResolveFlexInstanceType(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, instanceType *cloudprovider.InstanceType, shapeConfig *core.InstanceShapeConfig) (*cloudprovider.InstanceType, error) {
+	if shapeConfig == nil {
+		return nil, fmt.Errorf("shape config of flex shape %s is missing", instanceType.Name)
+	}
+	shape, err := p.GetShape(ctx, instanceType.Name)
+	if err != nil {
+		return nil, err
+	}
+	return p.sizedFlexInstanceType(ctx, nodeClass, instanceType, *shape, int(lo.FromPtr(shapeConfig.Ocpus)), int(lo.FromPtr(shapeConfig.MemoryInGBs))), nil
+}
+
+func (p *Provider) sizedFlexInstanceType(ctx context.Context, nodeClass *v1alpha1.OciNodeClass, instanceType *cloudprovider.InstanceType, shape core.Shape, ocpus int, memInGBs int) *cloudprovider.InstanceType {
+	zones := instanceType.Requirements.Get(corev1.LabelTopologyZone).Values()
+	wrapped := newFlexWrapShape(shape, zones, ocpus, memInGBs, dynamicBaseline(instanceType))
+	return NewInstanceType(ctx, wrapped, nodeClass, p.region, zones, p.CreateOfferings(ctx, nodeClass, wrapped, sets.New(zones...)))
+}
+
+// dynamicBaseline returns the baseline of the burstable dynamic flex shape, empty for the full OCPUs
+func dynamicBaseline(instanceType *cloudprovider.InstanceType) string {
+	baseline := instanceType.Requirements.Get(v1alpha1.LabelInstanceBaselineOcpuUtilization).Any()
+	if baseline == string(core.ShapeBaselineOcpuUtilizations1) {
+		return ""
+	}
+	return baseline
+}
+
+// flexShapeSizes returns the ocpu counts and the cpu mem ratios the flex shapes are split into,
+// the flex shape config of the nodeclass overrides the global options
+func flexShapeSizes(ctx context.Context, flexShapeConfig *v1alpha1.FlexShapeConfig) (ocpus []int, ratios []int) {
+	ocpus = parseInts(options.FromContext(ctx).FlexCpuConstrainList)
+	ratios = parseInts(options.FromContext(ctx).FlexCpuMemRatios)
+	if flexShapeConfig == nil {
+		return ocpus, ratios
+	}
+	if len(flexShapeConfig.CpuMemRatios) != 0 {
+		ratios = lo.Map(flexShapeConfig.CpuMemRatios, func(item int64, _ int) int { return int(item) })
+	}
+	if configured := flexShapeOcpus(flexShapeConfig); len(configured) != 0 {
+		ocpus = configured
+	}
+	return lo.Uniq(ocpus), lo.Uniq(ratios)
+}
+
+// flexShapeOcpus returns the ocpu counts of the ocpus and ocpu ranges of the flex shape config
+func flexShapeOcpus(flexShapeConfig *v1alpha1.FlexShapeConfig) []int {
+	ocpus := lo.Map(flexShapeConfig.Ocpus, func(item int64, _ int) int { return int(item) })
+	for _, ocpuRange := range flexShapeConfig.OcpuRanges {
+		step := lo.FromPtrOr(ocpuRange.Step, 1)
+		for cpus := ocpuRange.Min; cpus <= ocpuRange.Max; cpus += step {
+			ocpus = append(ocpus, int(cpus))
+		}
+	}
+	return ocpus
+}
+
+// inMemoryRanges returns true if the memory size is allowed by the memory ranges of the flex shape config
+func inMemoryRanges(flexShapeConfig *v1alpha1.FlexShapeConfig, memInGBs int) bool {
+	if flexShapeConfig == nil || len(flexShapeConfig.MemoryRanges) == 0 {
+		return true
+	}
+	return lo.ContainsBy(flexShapeConfig.MemoryRanges, func(memoryRange v1alpha1.MemoryRange) bool {
+		return int64(memInGBs) >= memoryRange.MinInGBs && int64(memInGBs) <= memoryRange.MaxInGBs
+	})
+}
+
+func parseInts(list string) []int {
+	ints := make([]int, 0)
+	for _, item := range strings.Split(list, ",") {
+		i, err := strconv.Atoi(item)
+		if err != nil {
+			continue
+		}
+		ints = append(ints, i)
+	}
+	return ints
+}
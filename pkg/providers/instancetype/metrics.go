@@ -26,6 +26,7 @@ const (
 	instanceTypeLabel      = "instance_type"
 	capacityTypeLabel      = "capacity_type"
 	zoneLabel              = "zone"
+	resourceLabel          = "resource"
 )
 
 var (
@@ -76,8 +77,20 @@ var (
 			capacityTypeLabel,
 			zoneLabel,
 		})
+	instanceTypeLimitRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "instance_type_limit_remaining",
+			Help:      "Remaining service limits or compartment quotas of the shape family of an instance type, in ocpus for cores and in GBs for memory, based on instance type, zone, and resource.",
+		},
+		[]string{
+			instanceTypeLabel,
+			zoneLabel,
+			resourceLabel,
+		})
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(instanceTypeVCPU, instanceTypeMemory, instanceTypeOfferingAvailable, instanceTypeOfferingPriceEstimate)
+	crmetrics.Registry.MustRegister(instanceTypeVCPU, instanceTypeMemory, instanceTypeOfferingAvailable, instanceTypeOfferingPriceEstimate, instanceTypeLimitRemaining)
 }
@@ -23,9 +23,11 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
+	ocilimits "github.com/oracle/oci-go-sdk/v65/limits"
 	"github.com/samber/lo"
 	"github.com/zoom/karpenter-oci/pkg/apis"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
 	"github.com/zoom/karpenter-oci/pkg/cloudprovider"
 	"github.com/zoom/karpenter-oci/pkg/fake"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
@@ -35,6 +37,7 @@ import (
 	"github.com/zoom/karpenter-oci/pkg/utils"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
 	clock "k8s.io/utils/clock/testing"
@@ -47,6 +50,7 @@ import (
 	"testing"
 
 	"sigs.k8s.io/karpenter/pkg/controllers/provisioning"
+	pscheduling "sigs.k8s.io/karpenter/pkg/controllers/provisioning/scheduling"
 	"sigs.k8s.io/karpenter/pkg/controllers/state"
 	"sigs.k8s.io/karpenter/pkg/events"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
@@ -77,7 +81,7 @@ var _ = BeforeSuite(func() {
 	ociEnv = test.NewEnvironment(ctx, env)
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, ociEnv.AMIProvider)
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster, fakeClock)
 })
@@ -129,6 +133,14 @@ var _ = Describe("InstanceTypeProvider", func() {
 	})
 
 	It("should support individual instance type labels", func() {
+		ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{ReservedCapacity: lo.ToPtr(true)}}))
+		nodePool.Spec.Template.Spec.Requirements[0].Values = []string{karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeReserved}
+		nodeClass.Status.CapacityReservations = []*v1alpha1.CapacityReservation{{
+			Id:                 "ocid1.capacityreservation.oc1.iad.aaaaaaaa",
+			AvailabilityDomain: "US-ASHBURN-AD-1",
+			InstanceShape:      "shape-gpu",
+			ReservedCount:      1,
+		}}
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 
 		nodeSelector := map[string]string{
@@ -147,14 +159,24 @@ var _ = Describe("InstanceTypeProvider", func() {
 
 			karpv1.CapacityTypeLabelKey: "on-demand",
 			// Well Known to OCI
-			v1alpha1.LabelInstanceShapeName:        "shape-gpu",
-			v1alpha1.LabelInstanceCPU:              "2",
-			v1alpha1.LabelInstanceMemory:           "8192",
-			v1alpha1.LabelInstanceNetworkBandwidth: "10240",
-			v1alpha1.LabelInstanceMaxVNICs:         "2",
-			v1alpha1.LabelIsFlexible:               "false",
-			v1alpha1.LabelInstanceGPU:              "1",
-			v1alpha1.LabelInstanceGPUDescription:   "A100",
+			v1alpha1.LabelInstanceShapeName:               "shape-gpu",
+			v1alpha1.LabelInstanceCPU:                     "2",
+			v1alpha1.LabelInstanceMemory:                  "8192",
+			v1alpha1.LabelInstanceNetworkBandwidth:        "10240",
+			v1alpha1.LabelInstanceMaxVNICs:                "2",
+			v1alpha1.LabelIsFlexible:                      "false",
+			v1alpha1.LabelInstanceGPU:                     "1",
+			v1alpha1.LabelInstanceGPUDescription:          "A100",
+			v1alpha1.LabelCapacityReservationId:           "ocid1.capacityreservation.oc1.iad.aaaaaaaa",
+			v1alpha1.LabelFaultDomain:                     "FAULT-DOMAIN-2",
+			v1alpha1.LabelInstanceBaselineOcpuUtilization: "BASELINE_1_1",
+			v1alpha1.LabelInstanceFamily:                  "shape-gpu",
+			v1alpha1.LabelInstanceGeneration:              "icelake",
+			v1alpha1.LabelInstanceProcessorVendor:         "intel",
+			v1alpha1.LabelInstanceOcpus:                   "2",
+			v1alpha1.LabelInstanceGPUManufacturer:         "nvidia",
+			v1alpha1.LabelInstanceGPUModel:                "a100",
+			v1alpha1.LabelInstanceGPUMemory:               "40960",
 		}
 
 		// Ensure that we're exercising all well known labels
@@ -171,6 +193,28 @@ var _ = Describe("InstanceTypeProvider", func() {
 			ExpectScheduled(ctx, env.Client, pod)
 		}
 	})
+	It("should spread pods across fault domains", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		labels := map[string]string{"app": "spread"}
+		pods := coretest.UnschedulablePods(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			NodeSelector: map[string]string{
+				v1.LabelTopologyZone: "US-ASHBURN-AD-1",
+			},
+			TopologySpreadConstraints: []v1.TopologySpreadConstraint{{
+				MaxSkew:           1,
+				TopologyKey:       v1alpha1.LabelFaultDomain,
+				WhenUnsatisfiable: v1.DoNotSchedule,
+				LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+			}},
+		}, 3)
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+		faultDomains := sets.New[string]()
+		for _, pod := range pods {
+			faultDomains.Insert(ExpectScheduled(ctx, env.Client, pod).Labels[v1alpha1.LabelFaultDomain])
+		}
+		Expect(faultDomains.UnsortedList()).To(ConsistOf(v1alpha1.FaultDomains))
+	})
 	It("should order the instance types by price and only consider the cheapest ones", func() {
 		instances := fake.MakeInstances()
 		lo.ForEach(instances, func(item *internalmodel.WrapShape, index int) {
@@ -219,7 +263,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectNotScheduled(ctx, env.Client, pod)
 	})
 	It("calculate max-pods by max MaxVnicAttachments", func() {
-		instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx)
+		instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
 		Expect(err).To(BeNil())
 		for _, info := range instanceInfo {
 			it := instancetype.NewInstanceType(ctx,
@@ -227,7 +271,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 				nodeClass,
 				"us-ashburn-1",
 				[]string{"us-east-1"},
-				ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+				ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 			)
 			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 31))
 		}
@@ -254,7 +298,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 		nodeClass.Spec.Kubelet = &v1alpha1.KubeletConfiguration{
 			MaxPods: &customMaxPod,
 		}
-		instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx)
+		instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
 		Expect(err).To(BeNil())
 		for _, info := range instanceInfo {
 			it := instancetype.NewInstanceType(ctx,
@@ -262,7 +306,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 				nodeClass,
 				"us-ashburn-1",
 				[]string{"us-east-1"},
-				ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+				ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 			)
 			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", min(int64(customMaxPod), (info.CalMaxVnic-1)*31)))
 		}
@@ -325,7 +369,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			}))
 
 			var ok bool
-			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx)
+			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
 			Expect(err).To(BeNil())
 			for _, val := range instanceInfo {
 				if *val.Shape.Shape == "shape-1" {
@@ -344,7 +388,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Overhead.SystemReserved.Cpu().String()).To(Equal("100m"))
 				Expect(it.Overhead.SystemReserved.Memory().String()).To(Equal("100Mi"))
@@ -363,7 +407,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Overhead.SystemReserved.Cpu().String()).To(Equal("2"))
 				Expect(it.Overhead.SystemReserved.Memory().String()).To(Equal("20Gi"))
@@ -379,7 +423,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Overhead.KubeReserved.Cpu().String()).To(Equal("70m"))
 				Expect(it.Overhead.KubeReserved.Memory().String()).To(Equal("1Gi"))
@@ -404,7 +448,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Overhead.KubeReserved.Cpu().String()).To(Equal("2"))
 				Expect(it.Overhead.KubeReserved.Memory().String()).To(Equal("10Gi"))
@@ -425,13 +469,13 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("100Mi"))
 			})
 		})
 		It("should set max-pods to user-defined value if specified", func() {
-			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx)
+			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
 			Expect(err).To(BeNil())
 			nodeClass.Spec.Kubelet = &v1alpha1.KubeletConfiguration{
 				MaxPods: ptr.Int32(10),
@@ -443,13 +487,13 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 10))
 			}
 		})
 		It("should override pods-per-core value", func() {
-			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx)
+			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
 			Expect(err).To(BeNil())
 			nodeClass.Spec.Kubelet = &v1alpha1.KubeletConfiguration{
 				PodsPerCore: ptr.Int32(1),
@@ -461,13 +505,13 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", info.CalcCpu))
 			}
 		})
 		It("should take the minimum of pods-per-core and max-pods", func() {
-			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx)
+			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
 			Expect(err).To(BeNil())
 			nodeClass.Spec.Kubelet = &v1alpha1.KubeletConfiguration{
 				PodsPerCore: ptr.Int32(4),
@@ -480,7 +524,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass,
 					"us-ashburn-1",
 					[]string{"us-east-1"},
-					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, info, sets.New[string]("us-east-1")),
+					ociEnv.InstanceTypesProvider.CreateOfferings(ctx, nodeClass, info, sets.New[string]("us-east-1")),
 				)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", lo.Min([]int64{20, info.CalcCpu * 4})))
 			}
@@ -556,6 +600,117 @@ var _ = Describe("InstanceTypeProvider", func() {
 			}
 		})
 	})
+	It("should mark the offerings unavailable in the zones without a subnet", func() {
+		nodeClass.Status.Subnets = []*v1alpha1.Subnet{{Id: "ocid1.subnet.oc1.iad.aaaaaaaa", AvailabilityDomains: []string{"US-ASHBURN-AD-1"}}}
+		instanceTypes, err := ociEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).ToNot(BeEmpty())
+		for _, it := range instanceTypes {
+			for _, of := range it.Offerings {
+				if of.Zone() != "US-ASHBURN-AD-1" {
+					Expect(of.Available).To(BeFalse())
+				}
+			}
+			Expect(it.Offerings.Available().Compatible(scheduling.NewRequirements(
+				scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, "US-ASHBURN-AD-1")))).ToNot(BeEmpty())
+		}
+	})
+	It("should mark the offerings unavailable in the zones whose subnets can't fit the IPs of the instance", func() {
+		nodeClass.Spec.MetaData = map[string]string{"oke-native-pod-networking": "true"}
+		nodeClass.Status.Subnets = []*v1alpha1.Subnet{
+			{Id: "ocid1.subnet.oc1.iad.aaaaaaaa", AvailabilityDomains: []string{"US-ASHBURN-AD-1"}},
+			{Id: "ocid1.subnet.oc1.iad.aaaaaaab", AvailabilityDomains: []string{"US-ASHBURN-AD-2"}},
+		}
+		// the utilization of the subnet in AD-2 is cached with the IPs reserved for a launching instance
+		_, err := ociEnv.SubnetProvider.AvailableIPs(ctx, &fake.DefaultSubnets[1])
+		Expect(err).ToNot(HaveOccurred())
+		available, ok := ociEnv.SubnetProvider.CachedAvailableIPs("ocid1.subnet.oc1.iad.aaaaaaab")
+		Expect(ok).To(BeTrue())
+		release := ociEnv.SubnetProvider.ReserveIPs("ocid1.subnet.oc1.iad.aaaaaaab", available-1)
+		defer release(false)
+
+		instanceTypes, err := ociEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).ToNot(BeEmpty())
+		for _, it := range instanceTypes {
+			for _, of := range it.Offerings {
+				if of.Zone() == "US-ASHBURN-AD-2" {
+					Expect(of.Available).To(BeFalse())
+				}
+			}
+			Expect(it.Offerings.Available().Compatible(scheduling.NewRequirements(
+				scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, "US-ASHBURN-AD-1")))).ToNot(BeEmpty())
+		}
+	})
+	It("should mark the offerings unavailable in the zones whose limits can't fit the instance", func() {
+		for _, ad := range []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2", "JPqd:US-ASHBURN-AD-3"} {
+			ociEnv.LimitsCli.LimitValues.Add(&ocilimits.LimitValueSummary{Name: common.String("shape-1-core-count"),
+				ScopeType: ocilimits.LimitValueSummaryScopeTypeAd, AvailabilityDomain: common.String(ad), Value: common.Int64(10)})
+		}
+		ociEnv.LimitsCli.ResourceAvailabilities.Set(&map[string]ocilimits.ResourceAvailability{
+			"shape-1-core-count/JPqd:US-ASHBURN-AD-1": {Available: common.Int64(0)},
+			"shape-1-core-count/JPqd:US-ASHBURN-AD-2": {Available: common.Int64(10)},
+			"shape-1-core-count/JPqd:US-ASHBURN-AD-3": {Available: common.Int64(10)},
+		})
+		instanceTypes, err := ociEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		shape, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "shape-1" })
+		Expect(ok).To(BeTrue())
+		for _, of := range shape.Offerings {
+			if of.CapacityType() == karpv1.CapacityTypeOnDemand {
+				Expect(of.Available).To(Equal(of.Zone() != "US-ASHBURN-AD-1"))
+			}
+		}
+		// the limits of the other shape families aren't affected
+		for _, it := range instanceTypes {
+			if it.Name != "shape-1" {
+				Expect(it.Offerings.Available().Compatible(scheduling.NewRequirements(
+					scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, "US-ASHBURN-AD-1")))).ToNot(BeEmpty())
+			}
+		}
+	})
+	It("should make the offerings which exceeded the quotas available once the quotas fit the instance", func() {
+		for _, ad := range []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2", "JPqd:US-ASHBURN-AD-3"} {
+			ociEnv.LimitsCli.LimitValues.Add(&ocilimits.LimitValueSummary{Name: common.String("shape-1-core-count"),
+				ScopeType: ocilimits.LimitValueSummaryScopeTypeAd, AvailabilityDomain: common.String(ad), Value: common.Int64(10)})
+		}
+		ociEnv.LimitsCli.ResourceAvailabilities.Set(&map[string]ocilimits.ResourceAvailability{
+			"shape-1-core-count/JPqd:US-ASHBURN-AD-1": {Available: common.Int64(0)},
+			"shape-1-core-count/JPqd:US-ASHBURN-AD-2": {Available: common.Int64(10)},
+			"shape-1-core-count/JPqd:US-ASHBURN-AD-3": {Available: common.Int64(10)},
+		})
+		for _, zone := range []string{"US-ASHBURN-AD-1", "US-ASHBURN-AD-2"} {
+			ociEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, ocicache.UnavailableReasonQuotaExceeded, "shape-1", zone, karpv1.CapacityTypeOnDemand)
+		}
+		instanceTypes, err := ociEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		shape, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "shape-1" })
+		Expect(ok).To(BeTrue())
+		for _, of := range shape.Offerings {
+			if of.CapacityType() == karpv1.CapacityTypeOnDemand {
+				Expect(of.Available).To(Equal(of.Zone() != "US-ASHBURN-AD-1"))
+			}
+		}
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-1", karpv1.CapacityTypeOnDemand)).To(BeTrue())
+		Expect(ociEnv.UnavailableOfferingsCache.IsUnavailable("shape-1", "US-ASHBURN-AD-2", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+	})
+	It("should not list the limits again on every listing when they can't be read", func() {
+		ociEnv.LimitsCli.ListLimitValuesError.Set(&fake.FakeServicefailure{StatusCode: 404, Code: "NotAuthorizedOrNotFound", Message: "not authorized"}, fake.MaxCalls(0))
+		for i := 0; i < 2; i++ {
+			instanceTypes, err := ociEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			for _, it := range instanceTypes {
+				Expect(it.Offerings.Available()).ToNot(BeEmpty())
+			}
+		}
+		Expect(ociEnv.LimitsCli.CalledWithListLimitValues.Len()).To(Equal(1))
+	})
+	It("should count the IPs of the pods for the native pod networking", func() {
+		Expect(instancetype.RequiredIPs(nodeClass, 110)).To(Equal(1))
+		nodeClass.Spec.MetaData = map[string]string{"oke-native-pod-networking": "true"}
+		Expect(instancetype.RequiredIPs(nodeClass, 110)).To(Equal(1 + 4 + 110))
+		Expect(instancetype.RequiredIPs(nodeClass, 31)).To(Equal(1 + 1 + 31))
+	})
 	Context("Insufficient Capacity Error Cache", func() {
 		It("should launch instances of different type on second reconciliation attempt with Insufficient Capacity Error Cache fallback", func() {
 			ociEnv.CmpCli.InsufficientCapacityPools.Set([]fake.CapacityPool{{CapacityType: karpv1.CapacityTypeOnDemand, InstanceType: "shape-2", Zone: "US-ASHBURN-AD-1"}})
@@ -745,6 +900,225 @@ var _ = Describe("InstanceTypeProvider", func() {
 				Expect(lo.FromPtr(volumeAttr.VpusPerGB)).To(Equal(int64(20)))
 			})
 		})
+		It("should use the size of the block device mounted to the kubelet root dir", func() {
+			nodeClass.Spec.BlockDevices = []*v1alpha1.VolumeAttributes{
+				{SizeInGBs: 200, VpusPerGB: 20, Device: lo.ToPtr("/dev/oracleoci/oraclevdb"), MountPath: lo.ToPtr("/var/lib")},
+				{SizeInGBs: 300, VpusPerGB: 20, Device: lo.ToPtr("/dev/oracleoci/oraclevdc"), MountPath: lo.ToPtr("/var/lib/kubelet")},
+				{SizeInGBs: 400, VpusPerGB: 20, Device: lo.ToPtr("/dev/oracleoci/oraclevdd"), MountPath: lo.ToPtr("/var/lib/containerd")},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse("300Gi")))
+		})
+		It("should use the boot volume size when no block device is mounted to the kubelet root dir", func() {
+			nodeClass.Spec.BootConfig.BootVolumeSizeInGBs = 150
+			nodeClass.Spec.BlockDevices = []*v1alpha1.VolumeAttributes{
+				{SizeInGBs: 400, VpusPerGB: 20, Device: lo.ToPtr("/dev/oracleoci/oraclevdb"), MountPath: lo.ToPtr("/var/lib/kube")},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse("150Gi")))
+		})
+		Context("Local NVMe", func() {
+			BeforeEach(func() {
+				ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("BM.DenseIO.E4.128"),
+					IsFlexible: common.Bool(false), Ocpus: common.Float32(128), MemoryInGBs: common.Float32(2048),
+					NetworkingBandwidthInGbps: common.Float32(50), MaxVnicAttachments: common.Int(256),
+					LocalDisks: common.Int(8), LocalDisksTotalSizeInGBs: common.Float32(54400)}})
+				ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("VM.DenseIO.E4.Flex"),
+					IsFlexible: common.Bool(true), OcpuOptions: &core.ShapeOcpuOptions{Min: common.Float32(8), Max: common.Float32(32)},
+					MemoryOptions:             &core.ShapeMemoryOptions{MinInGBs: common.Float32(1), MaxInGBs: common.Float32(512)},
+					NetworkingBandwidthInGbps: common.Float32(40), MaxVnicAttachments: common.Int(24),
+					LocalDisks: common.Int(4), LocalDisksTotalSizeInGBs: common.Float32(27200)}})
+				ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("VM.Standard.E4.8"),
+					IsFlexible: common.Bool(false), Ocpus: common.Float32(8), MemoryInGBs: common.Float32(64),
+					NetworkingBandwidthInGbps: common.Float32(8), MaxVnicAttachments: common.Int(4)}})
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					FlexCpuMemRatios: common.String("4"), FlexCpuConstrainList: common.String("8,16"), AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1"},
+				}))
+			})
+			It("should use the size of the local NVMe disks with the RAID0 instance store policy", func() {
+				nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1alpha1.InstanceStorePolicyRAID0)
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "BM.DenseIO.E4.128"}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				node := ExpectScheduled(ctx, env.Client, pod)
+				Expect(node.Labels).To(HaveKeyWithValue(v1alpha1.LabelInstanceLocalNVMe, "54400"))
+				Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse("54400G")))
+			})
+			It("should use the boot volume size without the instance store policy", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "BM.DenseIO.E4.128"}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				node := ExpectScheduled(ctx, env.Client, pod)
+				Expect(node.Labels).To(HaveKeyWithValue(v1alpha1.LabelInstanceLocalNVMe, "54400"))
+				Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse(fmt.Sprintf("%dGi", nodeClass.Spec.BootConfig.BootVolumeSizeInGBs))))
+			})
+			It("should size the local NVMe disks of the flex shapes with the ocpus", func() {
+				nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1alpha1.InstanceStorePolicyRAID0)
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{
+					NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "VM.DenseIO.E4.Flex"},
+					NodeRequirements: []v1.NodeSelectorRequirement{
+						{Key: v1alpha1.LabelInstanceLocalNVMe, Operator: v1.NodeSelectorOpGt, Values: []string{"10000"}},
+					},
+				})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				node := ExpectScheduled(ctx, env.Client, pod)
+				Expect(node.Labels).To(HaveKeyWithValue(v1alpha1.LabelInstanceOcpus, "16"))
+				Expect(node.Labels).To(HaveKeyWithValue(v1alpha1.LabelInstanceLocalNVMe, "13600"))
+				Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse("13600G")))
+			})
+			It("should not label the shapes without local NVMe disks", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "VM.Standard.E4.8"}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				node := ExpectScheduled(ctx, env.Client, pod)
+				Expect(node.Labels).ToNot(HaveKey(v1alpha1.LabelInstanceLocalNVMe))
+			})
+		})
+	})
+	Context("Volume Price", func() {
+		It("should include the cost of the block volumes in the offering price", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			its, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			prices := map[string]float64{}
+			for _, it := range its {
+				for _, of := range it.Offerings {
+					prices[it.Name+of.CapacityType()+of.Zone()] = of.Price
+				}
+			}
+
+			nodeClass.Spec.BlockDevices = []*v1alpha1.VolumeAttributes{{SizeInGBs: 1000, VpusPerGB: 120}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			its, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			// 1000GB of storage and 120 performance units per GB, charged per month
+			volumePrice := 1000 * (0.0255 + 120*0.0017) / 744
+			for _, it := range its {
+				for _, of := range it.Offerings {
+					Expect(of.Price).To(BeNumerically("~", prices[it.Name+of.CapacityType()+of.Zone()]+volumePrice, 1e-4))
+				}
+			}
+		})
+	})
+	Context("Processor", func() {
+		It("should derive the architecture and processor labels from the processor description", func() {
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("BM.Standard.A1.160"),
+				IsFlexible: common.Bool(false), Ocpus: common.Float32(160), MemoryInGBs: common.Float32(1024),
+				NetworkingBandwidthInGbps: common.Float32(50), MaxVnicAttachments: common.Int(256),
+				ProcessorDescription: common.String("3.0 GHz Ampere® Altra™")}})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelArchStable: "arm64"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(SatisfyAll(
+				HaveKeyWithValue(v1.LabelInstanceTypeStable, "BM.Standard.A1.160"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceCPU, "160"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceOcpus, "160"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceFamily, "standard-a1"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceProcessorVendor, "ampere"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceGeneration, "altra")))
+		})
+		It("should fall back to the shape name for the Ampere shapes without the processor description", func() {
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("BM.Standard.A4.48"),
+				IsFlexible: common.Bool(false), Ocpus: common.Float32(48), MemoryInGBs: common.Float32(768),
+				NetworkingBandwidthInGbps: common.Float32(50), MaxVnicAttachments: common.Int(256)}})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelArchStable: "arm64"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(SatisfyAll(
+				HaveKeyWithValue(v1alpha1.LabelInstanceCPU, "96"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceOcpus, "48"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceProcessorVendor, "ampere"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceGeneration, "ampereone")))
+		})
+		It("should derive the processor vendor from the platform config type", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1alpha1.LabelInstanceProcessorVendor: "amd"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "shape-1"))
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelArchStable, "amd64"))
+			Expect(node.Labels).ToNot(HaveKey(v1alpha1.LabelInstanceGeneration))
+		})
+		It("should leave the processor labels unset for the unknown shapes", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "shape-2"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelArchStable, "amd64"))
+			Expect(node.Labels).ToNot(HaveKey(v1alpha1.LabelInstanceProcessorVendor))
+			Expect(node.Labels).ToNot(HaveKey(v1alpha1.LabelInstanceGeneration))
+		})
+	})
+	Context("GPU", func() {
+		It("should expose the amd gpus of the amd gpu shapes", func() {
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("BM.GPU.MI300X.8"),
+				IsFlexible: common.Bool(false), Ocpus: common.Float32(112), MemoryInGBs: common.Float32(2048),
+				NetworkingBandwidthInGbps: common.Float32(100), MaxVnicAttachments: common.Int(256),
+				Gpus: common.Int(8), GpuDescription: common.String("AMD Instinct™ MI300X")}})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1alpha1.ResourceAMDGPU: resource.MustParse("8")},
+				Limits:   v1.ResourceList{v1alpha1.ResourceAMDGPU: resource.MustParse("8")},
+			}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(SatisfyAll(
+				HaveKeyWithValue(v1.LabelInstanceTypeStable, "BM.GPU.MI300X.8"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceGPU, "8"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceGPUManufacturer, "amd"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceGPUModel, "mi300x"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceGPUMemory, "196608")))
+		})
+		It("should select the gpu shapes by the gpu memory", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []v1.NodeSelectorRequirement{
+				{Key: v1alpha1.LabelInstanceGPUMemory, Operator: v1.NodeSelectorOpGt, Values: []string{"32768"}},
+			}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "shape-gpu"))
+		})
+	})
+	Context("Platform Config", func() {
+		It("should filter out the shapes which don't support the platform config", func() {
+			nodeClass.Spec.PlatformConfig = &v1alpha1.PlatformConfig{
+				IsSecureBootEnabled:            lo.ToPtr(true),
+				IsTrustedPlatformModuleEnabled: lo.ToPtr(true),
+				IsMeasuredBootEnabled:          lo.ToPtr(true),
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			its, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			Expect(lo.Map(its, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("shape-1"))
+		})
+		It("should launch the instance with the platform config of the shape platform type", func() {
+			nodeClass.Spec.PlatformConfig = &v1alpha1.PlatformConfig{IsMemoryEncryptionEnabled: lo.ToPtr(true)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "shape-1"))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			ltInput := ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop()
+			Expect(ltInput.PlatformConfig).To(Equal(core.AmdVmLaunchInstancePlatformConfig{IsMemoryEncryptionEnabled: lo.ToPtr(true)}))
+		})
+		It("should not set the platform config when it is not specified", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop().PlatformConfig).To(BeNil())
+		})
 	})
 	Context("Flex instance type", func() {
 		BeforeEach(func() {
@@ -783,6 +1157,173 @@ var _ = Describe("InstanceTypeProvider", func() {
 				HaveKeyWithValue(v1alpha1.LabelInstanceCPU, "2"),
 				HaveKeyWithValue(v1alpha1.LabelInstanceMemory, "8192")))
 		})
+		It("should split flex instance type by the flex shape config of the nodeclass", func() {
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("flex_instance"),
+				IsFlexible: common.Bool(true), OcpuOptions: &core.ShapeOcpuOptions{
+					Min: common.Float32(1),
+					Max: common.Float32(16),
+				}, MemoryOptions: &core.ShapeMemoryOptions{
+					MinInGBs: common.Float32(2),
+					MaxInGBs: common.Float32(256),
+				},
+				NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)}})
+			nodeClass.Spec.FlexShapeConfig = &v1alpha1.FlexShapeConfig{
+				CpuMemRatios: []int64{8, 16},
+				Ocpus:        []int64{1},
+				OcpuRanges:   []v1alpha1.OcpuRange{{Min: 4, Max: 8, Step: lo.ToPtr[int64](4)}},
+				MemoryRanges: []v1alpha1.MemoryRange{{MinInGBs: 16, MaxInGBs: 128}},
+			}
+			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			sizes := lo.FilterMap(lo.Values(instanceInfo), func(info *internalmodel.WrapShape, _ int) (string, bool) {
+				return fmt.Sprintf("%d-%d", info.CalcCpu, info.CalMemInGBs), lo.FromPtr(info.Shape.Shape) == "flex_instance"
+			})
+			// 1 ocpu with 16G,32G, 4 ocpus with 64G,128G, 8 ocpus with 128G, 256G is out of the memory ranges
+			Expect(sizes).To(ConsistOf("2-16", "2-32", "8-64", "8-128", "16-128"))
+
+			// the global options are used by the nodeclass without the flex shape config
+			instanceInfo, err = ociEnv.InstanceTypesProvider.ListInstanceType(ctx, test.OciNodeClass())
+			Expect(err).To(BeNil())
+			sizes = lo.FilterMap(lo.Values(instanceInfo), func(info *internalmodel.WrapShape, _ int) (string, bool) {
+				return fmt.Sprintf("%d-%d", info.CalcCpu, info.CalMemInGBs), lo.FromPtr(info.Shape.Shape) == "flex_instance"
+			})
+			Expect(sizes).To(ConsistOf("2-4", "2-8", "4-8", "4-16"))
+		})
+		It("should size the dynamic flex instance type to the resource requests of the nodeclaim", func() {
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("flex_instance"),
+				IsFlexible: common.Bool(true), OcpuOptions: &core.ShapeOcpuOptions{
+					Min: common.Float32(1),
+					Max: common.Float32(16),
+				}, MemoryOptions: &core.ShapeMemoryOptions{
+					MinInGBs:        common.Float32(1),
+					MaxInGBs:        common.Float32(256),
+					MinPerOcpuInGBs: common.Float32(1),
+					MaxPerOcpuInGBs: common.Float32(16),
+				},
+				NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)}})
+			nodeClass.Spec.FlexShapeConfig = &v1alpha1.FlexShapeConfig{Sizing: lo.ToPtr(v1alpha1.FlexShapeSizingDynamic)}
+			instanceInfo, err := ociEnv.InstanceTypesProvider.ListInstanceType(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			sizes := lo.FilterMap(lo.Values(instanceInfo), func(info *internalmodel.WrapShape, _ int) (string, bool) {
+				return fmt.Sprintf("%d-%d", info.CalcCpu, info.CalMemInGBs), lo.FromPtr(info.Shape.Shape) == "flex_instance"
+			})
+			// a single instance type with the max sizes of the shape
+			Expect(sizes).To(ConsistOf("32-256"))
+
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{v1.LabelInstanceTypeStable: "flex_instance"},
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("3"), v1.ResourceMemory: resource.MustParse("20Gi")},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			// 2 ocpus and 24G are the smallest sizes fitting the requests after the reserved resources and the vm overhead
+			Expect(node.Labels).To(SatisfyAll(
+				HaveKeyWithValue(v1.LabelInstanceTypeStable, "flex_instance"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceCPU, "4"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceMemory, "24576")))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			launchReq := ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop()
+			Expect(lo.FromPtr(launchReq.ShapeConfig.Ocpus)).To(BeNumerically("==", 2))
+			Expect(lo.FromPtr(launchReq.ShapeConfig.MemoryInGBs)).To(BeNumerically("==", 24))
+		})
+		It("should not replace a sized dynamic flex node with its own shape by consolidation", func() {
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("VM.Standard.E4.Flex"),
+				IsFlexible: common.Bool(true), OcpuOptions: &core.ShapeOcpuOptions{
+					Min: common.Float32(1),
+					Max: common.Float32(16),
+				}, MemoryOptions: &core.ShapeMemoryOptions{
+					MinInGBs:        common.Float32(1),
+					MaxInGBs:        common.Float32(256),
+					MinPerOcpuInGBs: common.Float32(1),
+					MaxPerOcpuInGBs: common.Float32(16),
+				},
+				NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)}})
+			nodeClass.Spec.FlexShapeConfig = &v1alpha1.FlexShapeConfig{Sizing: lo.ToPtr(v1alpha1.FlexShapeSizingDynamic)}
+			instanceTypes, err := ociEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			instanceType, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "VM.Standard.E4.Flex" })
+			Expect(ok).To(BeTrue())
+			// a node sized with 8 ocpus at launch is priced by its size
+			nodeReqs := func(cpu string) scheduling.Requirements {
+				return scheduling.NewLabelRequirements(map[string]string{
+					karpv1.CapacityTypeLabelKey:                   karpv1.CapacityTypeOnDemand,
+					v1.LabelTopologyZone:                          "US-ASHBURN-AD-1",
+					v1alpha1.LabelInstanceCPU:                     cpu,
+					v1alpha1.LabelInstanceBaselineOcpuUtilization: string(core.ShapeBaselineOcpuUtilizations1),
+				})
+			}
+			candidatePrice := instanceType.Offerings.Compatible(nodeReqs("16")).Cheapest().Price
+			Expect(candidatePrice).To(BeNumerically(">", instanceType.Offerings.Compatible(nodeReqs("2")).Cheapest().Price))
+			// consolidation only replaces the node with the instance types which are cheaper than the node
+			replacement := &pscheduling.NodeClaim{NodeClaimTemplate: pscheduling.NodeClaimTemplate{InstanceTypeOptions: corecloudprovider.InstanceTypes{instanceType}}}
+			replacement, err = replacement.RemoveInstanceTypeOptionsByPriceAndMinValues(scheduling.NewRequirements(
+				scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, karpv1.CapacityTypeOnDemand)), candidatePrice)
+			Expect(err).To(BeNil())
+			Expect(replacement.InstanceTypeOptions).To(BeEmpty())
+		})
+		It("should skip the dynamic flex instance type which can't fit the nodeclaim", func() {
+			// the memory of the shape is 16G per ocpu, 20G is within the memory range of the instance type but no size has it
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("flex_instance"),
+				IsFlexible: common.Bool(true), OcpuOptions: &core.ShapeOcpuOptions{
+					Min: common.Float32(1),
+					Max: common.Float32(16),
+				}, MemoryOptions: &core.ShapeMemoryOptions{
+					MinInGBs:        common.Float32(16),
+					MaxInGBs:        common.Float32(256),
+					MinPerOcpuInGBs: common.Float32(16),
+					MaxPerOcpuInGBs: common.Float32(16),
+				},
+				NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)}})
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("shape-20g"),
+				IsFlexible: common.Bool(false), Ocpus: common.Float32(2), MemoryInGBs: common.Float32(20),
+				NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)}})
+			nodeClass.Spec.FlexShapeConfig = &v1alpha1.FlexShapeConfig{Sizing: lo.ToPtr(v1alpha1.FlexShapeSizingDynamic)}
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{v1alpha1.LabelInstanceMemory: "20480"},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "shape-20g"))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+		})
+		It("should launch burstable flex instance type with the baseline ocpu utilization", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				FlexCpuMemRatios: common.String("2,4"), FlexCpuConstrainList: common.String("1,2"), AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1"},
+				FlexBurstableBaselines: common.String("BASELINE_1_2,BASELINE_1_8"),
+			}))
+			ociEnv.CmpCli.DescribeInstanceTypesOutput.Add(&internalmodel.WrapShape{Shape: core.Shape{Shape: common.String("flex_instance"),
+				IsFlexible: common.Bool(true), OcpuOptions: &core.ShapeOcpuOptions{
+					Min: common.Float32(1),
+					Max: common.Float32(16),
+				}, MemoryOptions: &core.ShapeMemoryOptions{
+					MinInGBs: common.Float32(2),
+					MaxInGBs: common.Float32(128),
+				},
+				BaselineOcpuUtilizations:  []core.ShapeBaselineOcpuUtilizationsEnum{core.ShapeBaselineOcpuUtilizations8, core.ShapeBaselineOcpuUtilizations2, core.ShapeBaselineOcpuUtilizations1},
+				NetworkingBandwidthInGbps: common.Float32(10), MaxVnicAttachments: common.Int(2)}})
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{
+					v1.LabelInstanceTypeStable:                    "flex_instance",
+					v1alpha1.LabelInstanceBaselineOcpuUtilization: "BASELINE_1_8",
+				},
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("5Gi")},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(SatisfyAll(
+				HaveKeyWithValue(v1.LabelInstanceTypeStable, "flex_instance"),
+				HaveKeyWithValue(v1alpha1.LabelInstanceBaselineOcpuUtilization, "BASELINE_1_8")))
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			launchReq := ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop()
+			Expect(launchReq.ShapeConfig.BaselineOcpuUtilization).To(Equal(core.LaunchInstanceShapeConfigDetailsBaselineOcpuUtilization8))
+		})
 	})
 	Context("Preemptible instance type", func() {
 		BeforeEach(func() {
@@ -22,6 +22,7 @@ import (
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
 	"github.com/zoom/karpenter-oci/pkg/providers/internalmodel"
+	"github.com/zoom/karpenter-oci/pkg/providers/limits"
 	"github.com/zoom/karpenter-oci/pkg/utils"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -39,11 +40,11 @@ const (
 	Gi              = 1024 * 1024 * 1024
 	MemoryAvailable = "memory.available"
 	NodeFSAvailable = "nodefs.available"
+	KubeletRootDir  = "/var/lib/kubelet"
+	// ipsPerVnic is the number of the pod IPs of a VNIC in the native pod networking
+	ipsPerVnic = 31
 )
 
-// https://docs.oracle.com/en-us/iaas/Content/Compute/References/arm.htm
-var ArmShapes = []string{"BM.Standard.A1.160", "VM.Standard.A1.Flex", "VM.Standard.A2.Flex", "BM.Standard.A4.48", "VM.Standard.A4.Flex"}
-
 // TaxBrackets implements a simple bracketed tax structure.
 type TaxBrackets []struct {
 	// UpperBound is the largest value this bracket is applied to.
@@ -144,18 +145,17 @@ func NewInstanceType(ctx context.Context, shape *internalmodel.WrapShape, nodeCl
 }
 
 func computeRequirements(ctx context.Context, shape *internalmodel.WrapShape, offerings cloudprovider.Offerings, zones []string, region string) scheduling.Requirements {
-	arch := "amd64"
-	if lo.Contains(ArmShapes, *shape.Shape.Shape) {
-		arch = "arm64"
-	}
+	processor := utils.ShapeProcessor(shape.Shape)
+	vcpusPerOcpu := int64(utils.VcpusPerOcpu(shape.Shape))
 	requirements := scheduling.NewRequirements(
 		// Well Known Upstream
 		scheduling.NewRequirement(v1.LabelInstanceTypeStable, v1.NodeSelectorOpIn, *shape.Shape.Shape),
-		scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, arch),
+		scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, processor.Arch),
 		scheduling.NewRequirement(v1.LabelOSStable, v1.NodeSelectorOpIn, string(v1.Linux)),
 		//scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, lo.Map(offerings.Available(), func(o cloudprovider.Offering, _ int) string { return o.Zone })...),
 		scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, zones...),
 		scheduling.NewRequirement(v1.LabelTopologyRegion, v1.NodeSelectorOpIn, region),
+		scheduling.NewRequirement(v1alpha1.LabelFaultDomain, v1.NodeSelectorOpIn, v1alpha1.FaultDomains...),
 		// Well Known to Karpenter
 		scheduling.NewRequirement(corev1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, lo.Map(offerings.Available(), func(o *cloudprovider.Offering, _ int) string {
 			return o.Requirements.Get(corev1.CapacityTypeLabelKey).Any()
@@ -163,37 +163,90 @@ func computeRequirements(ctx context.Context, shape *internalmodel.WrapShape, of
 		// Well Known to OCI
 		scheduling.NewRequirement(v1alpha1.LabelInstanceShapeName, v1.NodeSelectorOpIn, *shape.Shape.Shape),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceCPU, v1.NodeSelectorOpIn, fmt.Sprint(shape.CalcCpu)),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceOcpus, v1.NodeSelectorOpIn, fmt.Sprint(shape.CalcCpu/vcpusPerOcpu)),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceFamily, v1.NodeSelectorOpIn, limits.Family(*shape.Shape.Shape)),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceGeneration, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceProcessorVendor, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelIsFlexible, v1.NodeSelectorOpIn, fmt.Sprint(lo.FromPtr(shape.IsFlexible))),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceBaselineOcpuUtilization, v1.NodeSelectorOpIn, baselineOcpuUtilization(shape)),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceGPU, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceGPUDescription, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceGPUManufacturer, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceGPUModel, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceGPUMemory, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceMemory, v1.NodeSelectorOpIn, fmt.Sprint(shape.CalMemInGBs*1024)),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceNetworkBandwidth, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceMaxVNICs, v1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceLocalNVMe, v1.NodeSelectorOpDoesNotExist),
 	)
+	if reserved := offerings.Available().Compatible(scheduling.NewRequirements(scheduling.NewRequirement(corev1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1.CapacityTypeReserved))); len(reserved) != 0 {
+		requirements.Add(scheduling.NewRequirement(cloudprovider.ReservationIDLabel, v1.NodeSelectorOpIn, lo.Map(reserved, func(o *cloudprovider.Offering, _ int) string {
+			return o.ReservationID()
+		})...))
+	}
+	// the dynamic flex shapes are sized at launch, any size up to the max sizes of the shape is allowed
+	if shape.CalDynamic {
+		smallest := smallestFlexShape(shape)
+		requirements[v1alpha1.LabelInstanceCPU] = scheduling.NewRequirement(v1alpha1.LabelInstanceCPU, v1.NodeSelectorOpGt, fmt.Sprint(smallest.CalcCpu-1))
+		requirements.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceCPU, v1.NodeSelectorOpLt, fmt.Sprint(shape.CalcCpu+1)))
+		requirements[v1alpha1.LabelInstanceOcpus] = scheduling.NewRequirement(v1alpha1.LabelInstanceOcpus, v1.NodeSelectorOpGt, fmt.Sprint(smallest.CalcCpu/vcpusPerOcpu-1))
+		requirements.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceOcpus, v1.NodeSelectorOpLt, fmt.Sprint(shape.CalcCpu/vcpusPerOcpu+1)))
+		requirements[v1alpha1.LabelInstanceMemory] = scheduling.NewRequirement(v1alpha1.LabelInstanceMemory, v1.NodeSelectorOpGt, fmt.Sprint(smallest.CalMemInGBs*1024-1))
+		requirements.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceMemory, v1.NodeSelectorOpLt, fmt.Sprint(shape.CalMemInGBs*1024+1)))
+		if localNVMe := localNVMeInGBs(shape); localNVMe != 0 {
+			requirements[v1alpha1.LabelInstanceLocalNVMe] = scheduling.NewRequirement(v1alpha1.LabelInstanceLocalNVMe, v1.NodeSelectorOpGt, fmt.Sprint(localNVMeInGBs(smallest)-1))
+			requirements.Add(scheduling.NewRequirement(v1alpha1.LabelInstanceLocalNVMe, v1.NodeSelectorOpLt, fmt.Sprint(localNVMe+1)))
+		}
+	} else if localNVMe := localNVMeInGBs(shape); localNVMe != 0 {
+		requirements[v1alpha1.LabelInstanceLocalNVMe].Insert(fmt.Sprint(localNVMe))
+	}
 	// insert actual value if exist
 	if shape.NetworkingBandwidthInGbps != nil {
 		requirements[v1alpha1.LabelInstanceNetworkBandwidth].Insert(fmt.Sprint(shape.CalMaxBandwidthInGbps * 1024))
 	}
+	// the processor of the shapes which can't be derived from the shape details or name is unknown
+	if processor.Generation != "" {
+		requirements[v1alpha1.LabelInstanceGeneration].Insert(processor.Generation)
+	}
+	if processor.Vendor != "" {
+		requirements[v1alpha1.LabelInstanceProcessorVendor].Insert(processor.Vendor)
+	}
 	if shape.MaxVnicAttachments != nil {
 		requirements[v1alpha1.LabelInstanceMaxVNICs].Insert(fmt.Sprint(shape.CalMaxVnic))
 	}
-	if shape.Gpus != nil && lo.FromPtr(shape.Gpus) != 0 {
-		requirements[v1alpha1.LabelInstanceGPU].Insert(fmt.Sprint(nvidiaGPUs(shape.Shape).Value()))
+	if gpu, ok := utils.ShapeGPU(shape.Shape); ok {
+		requirements[v1alpha1.LabelInstanceGPU].Insert(fmt.Sprint(lo.FromPtr(shape.Gpus)))
 		qualifiedDesc := utils.SanitizeLabelValue(lo.FromPtr(shape.GpuDescription))
 		requirements[v1alpha1.LabelInstanceGPUDescription].Insert(qualifiedDesc)
+		requirements[v1alpha1.LabelInstanceGPUManufacturer].Insert(gpu.Manufacturer)
+		if gpu.Model != "" {
+			requirements[v1alpha1.LabelInstanceGPUModel].Insert(gpu.Model)
+		}
+		if gpu.MemoryInMiB != 0 {
+			requirements[v1alpha1.LabelInstanceGPUMemory].Insert(fmt.Sprint(gpu.MemoryInMiB))
+		}
 	}
 
 	return requirements
 }
 
+// baselineOcpuUtilization returns the baseline of the burstable instance, the instances use the full OCPUs by default
+func baselineOcpuUtilization(shape *internalmodel.WrapShape) string {
+	if shape.CalBaselineOcpuUtilization == "" {
+		return string(core.ShapeBaselineOcpuUtilizations1)
+	}
+	return shape.CalBaselineOcpuUtilization
+}
+
 func computeCapacity(ctx context.Context, shape *internalmodel.WrapShape, kc *v1alpha1.KubeletConfiguration, nodeclass *v1alpha1.OciNodeClass) v1.ResourceList {
 
 	resourceList := v1.ResourceList{
-		v1.ResourceCPU:                    *cpu(shape.CalcCpu),
-		v1.ResourceMemory:                 *memory(ctx, shape.CalMemInGBs),
-		v1.ResourceEphemeralStorage:       *ephemeralStorage(nodeclass),
-		v1.ResourcePods:                   *pods(shape, kc),
-		v1.ResourceName("nvidia.com/gpu"): *nvidiaGPUs(shape.Shape),
+		v1.ResourceCPU:              *cpu(shape.CalcCpu),
+		v1.ResourceMemory:           *memory(ctx, shape.CalMemInGBs),
+		v1.ResourceEphemeralStorage: *ephemeralStorage(shape, nodeclass),
+		v1.ResourcePods:             *pods(shape, kc),
+		v1alpha1.ResourceNVIDIAGPU:  *gpus(shape.Shape, utils.GPUManufacturerNVIDIA),
+		v1alpha1.ResourceAMDGPU:     *gpus(shape.Shape, utils.GPUManufacturerAMD),
 	}
 	return resourceList
 }
@@ -209,19 +262,65 @@ func memory(ctx context.Context, memoryInGBs int64) *resource.Quantity {
 	return mem
 }
 
-// Setting ephemeral-storage to be either the default value, what is defined in blockDeviceMappings, or the combined size of local store volumes.
-func ephemeralStorage(nodeclass *v1alpha1.OciNodeClass) *resource.Quantity {
+// Setting ephemeral-storage to be either the boot volume size, or the size of the block device mounted closest to the kubelet root dir.
+// The kubelet root dir is moved onto the local NVMe disks with the RAID0 instance store policy, which takes precedence.
+func ephemeralStorage(shape *internalmodel.WrapShape, nodeclass *v1alpha1.OciNodeClass) *resource.Quantity {
+	if localNVMe := localNVMeInGBs(shape); localNVMe != 0 && lo.FromPtr(nodeclass.Spec.InstanceStorePolicy) == v1alpha1.InstanceStorePolicyRAID0 {
+		// the disks are sized in decimal units
+		return resources.Quantity(fmt.Sprintf("%dG", localNVMe))
+	}
+	var mounted *v1alpha1.VolumeAttributes
+	for _, device := range nodeclass.Spec.BlockDevices {
+		mountPath := strings.TrimSuffix(lo.FromPtr(device.MountPath), "/")
+		if mountPath == "" || (mountPath != KubeletRootDir && !strings.HasPrefix(KubeletRootDir, mountPath+"/")) {
+			continue
+		}
+		if mounted == nil || len(mountPath) > len(strings.TrimSuffix(lo.FromPtr(mounted.MountPath), "/")) {
+			mounted = device
+		}
+	}
+	if mounted != nil {
+		return resources.Quantity(fmt.Sprintf("%dGi", mounted.SizeInGBs))
+	}
 	return resources.Quantity(fmt.Sprintf("%dGi", nodeclass.Spec.BootConfig.BootVolumeSizeInGBs))
 }
 
-func nvidiaGPUs(shape core.Shape) *resource.Quantity {
+// localNVMeInGBs returns the size of the local NVMe disks of the shape. The disks of the flex shapes grow with the ocpus,
+// a disk per the same number of ocpus, and the shape details have the disks of the max ocpus.
+func localNVMeInGBs(shape *internalmodel.WrapShape) int64 {
+	total := int64(lo.FromPtr(shape.LocalDisksTotalSizeInGBs))
+	disks := int64(lo.FromPtr(shape.LocalDisks))
+	if total == 0 || !lo.FromPtr(shape.IsFlexible) || shape.OcpuOptions == nil || lo.FromPtr(shape.OcpuOptions.Max) == 0 {
+		return total
+	}
+	maxOcpus := int64(lo.FromPtr(shape.OcpuOptions.Max))
+	ocpus := shape.CalcCpu / int64(utils.VcpusPerOcpu(shape.Shape))
+	if disks == 0 {
+		return total * ocpus / maxOcpus
+	}
+	return (ocpus*disks + maxOcpus - 1) / maxOcpus * (total / disks)
+}
+
+// gpus returns the gpu count of the shape when its gpus are made by the manufacturer, the gpus are exposed as the extended
+// resources of the device plugins of the manufacturers
+func gpus(shape core.Shape, manufacturer string) *resource.Quantity {
 	count := int64(0)
-	if shape.Gpus != nil {
+	if gpu, ok := utils.ShapeGPU(shape); ok && gpu.Manufacturer == manufacturer {
 		count = int64(*shape.Gpus)
 	}
 	return resources.Quantity(fmt.Sprint(count))
 }
 
+// RequiredIPs returns the IPs which an instance takes from its subnet, one per VNIC plus the IPs of the pods when the
+// native pod networking is enabled, the pod VNICs are attached to the subnet of the instance
+func RequiredIPs(nodeClass *v1alpha1.OciNodeClass, maxPods int64) int {
+	if nodeClass.Spec.MetaData["oke-native-pod-networking"] != "true" {
+		return 1
+	}
+	podVnics := (maxPods + ipsPerVnic - 1) / ipsPerVnic
+	return int(1 + podVnics + maxPods)
+}
+
 // TODO fixme, we need to consider the maxVnic only when using native-cni
 func pods(shape *internalmodel.WrapShape, kc *v1alpha1.KubeletConfiguration) *resource.Quantity {
 	var count int64
@@ -236,7 +335,7 @@ func pods(shape *internalmodel.WrapShape, kc *v1alpha1.KubeletConfiguration) *re
 		count = lo.Min([]int64{int64(ptr.Int32Value(kc.PodsPerCore)) * shape.CalcCpu, count})
 	}
 	// Maximum number of Pods per node = MIN( (Number of VNICs - 1) * 31 ), 110)
-	return resources.Quantity(fmt.Sprint(min(count, (shape.CalMaxVnic-1)*31)))
+	return resources.Quantity(fmt.Sprint(min(count, (shape.CalMaxVnic-1)*ipsPerVnic)))
 }
 
 func SystemReservedResources(kc *v1alpha1.KubeletConfiguration) v1.ResourceList {
@@ -23,4 +23,8 @@ type WrapShape struct {
 	AvailableDomains      []string
 	CalMaxVnic            int64
 	CalMaxBandwidthInGbps int64
+	// CalBaselineOcpuUtilization is the baseline of the burstable flex instance, empty for the full OCPUs
+	CalBaselineOcpuUtilization string
+	// CalDynamic is true for the flex shape which is sized at launch, CalcCpu and CalMemInGBs are the max sizes of the shape
+	CalDynamic bool
 }
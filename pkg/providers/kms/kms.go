@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/keymanagement"
+	"github.com/patrickmn/go-cache"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+)
+
+// managementEndpointTemplate is the management endpoint of the vault, the vault prefix is part of the key ocid
+const managementEndpointTemplate = "https://{vault}-management.kms.{region}.{secondLevelDomain}"
+
+type Provider struct {
+	sync.Mutex
+	newClient func(endpoint string) (api.KmsManagementClient, error)
+	clients   map[string]api.KmsManagementClient
+	cache     *cache.Cache
+}
+
+func NewProvider(newClient func(endpoint string) (api.KmsManagementClient, error), cache *cache.Cache) *Provider {
+	return &Provider{newClient: newClient, clients: map[string]api.KmsManagementClient{}, cache: cache}
+}
+
+// GetKey returns the key from the vault the key belongs to
+func (p *Provider) GetKey(ctx context.Context, keyId string) (*keymanagement.Key, error) {
+	if key, ok := p.cache.Get(keyId); ok {
+		return key.(*keymanagement.Key), nil
+	}
+	endpoint, err := ManagementEndpoint(keyId)
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.client(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.GetKey(ctx, keymanagement.GetKeyRequest{KeyId: common.String(keyId)})
+	if err != nil {
+		return nil, err
+	}
+	p.cache.SetDefault(keyId, &resp.Key)
+	return &resp.Key, nil
+}
+
+// client returns the management client of the vault endpoint, only the clients are locked so the keys of the vaults
+// are read concurrently
+func (p *Provider) client(endpoint string) (api.KmsManagementClient, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if client, ok := p.clients[endpoint]; ok {
+		return client, nil
+	}
+	client, err := p.newClient(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("creating kms management client for %s, %w", endpoint, err)
+	}
+	p.clients[endpoint] = client
+	return client, nil
+}
+
+// ManagementEndpoint resolves the vault management endpoint from the key ocid,
+// the ocid is in the format of ocid1.key.<realm>.<region>.<vault prefix>.<unique id>
+func ManagementEndpoint(keyId string) (string, error) {
+	parts := strings.Split(keyId, ".")
+	if len(parts) != 6 || parts[0] != "ocid1" || parts[1] != "key" || parts[3] == "" || parts[4] == "" {
+		return "", fmt.Errorf("invalid kms key id %s", keyId)
+	}
+	endpoint := common.StringToRegion(parts[3]).EndpointForTemplate("kms", managementEndpointTemplate)
+	return strings.Replace(endpoint, "{vault}", parts[4], 1), nil
+}
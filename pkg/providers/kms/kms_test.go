@@ -0,0 +1,34 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"testing"
+)
+
+func TestManagementEndpoint(t *testing.T) {
+	endpoint, err := ManagementEndpoint("ocid1.key.oc1.iad.bbpnr2ukaaeuk.abuwcljsxyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint != "https://bbpnr2ukaaeuk-management.kms.us-ashburn-1.oraclecloud.com" {
+		t.Errorf("unexpected endpoint %s", endpoint)
+	}
+	for _, keyId := range []string{"", "ocid1.key.oc1.iad", "ocid1.vault.oc1.iad.bbpnr2ukaaeuk.abuwcljsxyz"} {
+		if _, err := ManagementEndpoint(keyId); err == nil {
+			t.Errorf("expected error for key id %q", keyId)
+		}
+	}
+}
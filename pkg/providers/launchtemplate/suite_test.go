@@ -16,6 +16,7 @@ package launchtemplate_test
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/awslabs/operatorpkg/status"
@@ -70,7 +71,7 @@ var _ = BeforeSuite(func() {
 
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(ociEnv.InstanceTypesProvider, ociEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, ociEnv.AMIProvider)
+		env.Client, ociEnv.AMIProvider, ociEnv.ConsoleHistoryProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster, fakeClock)
 })
@@ -180,6 +181,73 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				Expect(lo.FromPtr(volumeAttrs[1].SizeInGBs)).To(Equal(int64(50)))
 			})
 		})
+		It("should attach block devices to the device path and mount them in the user data", func() {
+			nodeClass.Spec.BlockDevices = []*v1alpha1.VolumeAttributes{
+				{
+					SizeInGBs:      100,
+					VpusPerGB:      20,
+					AttachmentType: lo.ToPtr(v1alpha1.VolumeAttachmentTypeParavirtualized),
+					Device:         lo.ToPtr("/dev/oracleoci/oraclevdb"),
+					FileSystem:     lo.ToPtr(v1alpha1.FileSystemXfs),
+					MountPath:      lo.ToPtr("/var/lib/containerd"),
+				},
+				{
+					SizeInGBs: 50,
+					VpusPerGB: 10,
+					Device:    lo.ToPtr("/dev/oracleoci/oraclevdc"),
+					MountPath: lo.ToPtr("/data"),
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			ltInput := ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop()
+			Expect(ltInput.LaunchVolumeAttachments).To(HaveLen(2))
+			paravirtualized, ok := ltInput.LaunchVolumeAttachments[0].(core.LaunchAttachParavirtualizedVolumeDetails)
+			Expect(ok).To(BeTrue())
+			Expect(lo.FromPtr(paravirtualized.Device)).To(Equal("/dev/oracleoci/oraclevdb"))
+			iscsi, ok := ltInput.LaunchVolumeAttachments[1].(core.LaunchAttachIScsiVolumeDetails)
+			Expect(ok).To(BeTrue())
+			Expect(lo.FromPtr(iscsi.Device)).To(Equal("/dev/oracleoci/oraclevdc"))
+			Expect(lo.FromPtr(iscsi.IsAgentAutoIscsiLoginEnabled)).To(BeTrue())
+			// the iscsi volume is logged in by the block volume management plugin before it's mounted
+			Expect(ltInput.AgentConfig).ToNot(BeNil())
+			Expect(lo.Map(ltInput.AgentConfig.PluginsConfig, func(item core.InstanceAgentPluginConfigDetails, _ int) string {
+				return lo.FromPtr(item.Name)
+			})).To(ContainElement("Block Volume Management"))
+
+			userData, err := base64.StdEncoding.DecodeString(ltInput.Metadata["user_data"])
+			Expect(err).To(BeNil())
+			Expect(string(userData)).To(ContainSubstring("mkfs -t xfs '/dev/oracleoci/oraclevdb'"))
+			Expect(string(userData)).To(ContainSubstring("mount -t xfs '/dev/oracleoci/oraclevdb' '/var/lib/containerd'"))
+			Expect(string(userData)).To(ContainSubstring("mkfs -t ext4 '/dev/oracleoci/oraclevdc'"))
+			Expect(string(userData)).To(ContainSubstring("mount -t ext4 '/dev/oracleoci/oraclevdc' '/data'"))
+			Expect(string(userData)).To(ContainSubstring("[ -b '/dev/oracleoci/oraclevdc' ] || { echo 'block device /dev/oracleoci/oraclevdc of /data never appeared"))
+		})
+		It("should assemble the local NVMe disks into a RAID0 array with the RAID0 instance store policy", func() {
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1alpha1.InstanceStorePolicyRAID0)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			userData, err := base64.StdEncoding.DecodeString(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop().Metadata["user_data"])
+			Expect(err).To(BeNil())
+			Expect(string(userData)).To(ContainSubstring("mdadm --create --force --run '/dev/md/instance-store' --level=0"))
+			Expect(string(userData)).To(ContainSubstring("for dir in /var/lib/kubelet /var/lib/containers; do"))
+		})
+		It("should not use the local NVMe disks without the instance store policy", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Len()).To(Equal(1))
+			userData, err := base64.StdEncoding.DecodeString(ociEnv.CmpCli.LaunchInstanceBehavior.CalledWithInput.Pop().Metadata["user_data"])
+			Expect(err).To(BeNil())
+			Expect(string(userData)).ToNot(ContainSubstring("mdadm"))
+		})
 	})
 	Context("Ephemeral Storage", func() {
 		It("should pack pods when a daemonset has an ephemeral-storage request", func() {
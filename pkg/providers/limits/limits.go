@@ -0,0 +1,188 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package limits
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/limits"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+)
+
+const (
+	// the limits of the shapes are defined in the compute service
+	computeService  = "compute"
+	limitValuesKey  = "limit-values"
+	coresResource   = "core"
+	memoryResource  = "memory"
+	limitNameFormat = "%s-%s-count"
+)
+
+// Remaining is the remaining capacity of the limits of a shape family in an availability domain, nil when the family
+// has no such limit
+type Remaining struct {
+	// Cores is the number of the remaining OCPUs
+	Cores *float64
+	// MemoryInGBs is the remaining memory in GBs
+	MemoryInGBs *float64
+}
+
+// Fits returns true if the remaining capacity can fit an instance with the ocpus and the memory
+func (r Remaining) Fits(ocpus, memoryInGBs float64) bool {
+	return (r.Cores == nil || *r.Cores >= ocpus) && (r.MemoryInGBs == nil || *r.MemoryInGBs >= memoryInGBs)
+}
+
+type Provider struct {
+	client    api.LimitsClient
+	tenancyId string
+	cache     *cache.Cache
+}
+
+func NewProvider(client api.LimitsClient, tenancyId string, cache *cache.Cache) *Provider {
+	return &Provider{client: client, tenancyId: tenancyId, cache: cache}
+}
+
+// Remaining returns the remaining capacity of the service limits of the shape family in the zone, the availability is
+// queried in the compartment of the instances, so the compartment quotas are taken into account. The remaining
+// capacity is resolved once per family and availability domain, a failure is cached for a shorter time with no limits
+// so a tenancy whose limits can't be read isn't queried on every listing of the instance types.
+func (p *Provider) Remaining(ctx context.Context, shape string, zone string) (Remaining, error) {
+	ad, ok := lo.Find(options.FromContext(ctx).AvailableDomains, func(item string) bool {
+		return strings.Contains(item, zone)
+	})
+	if !ok {
+		return Remaining{}, fmt.Errorf("failed to find a zone for %s, available az: %s", zone, options.FromContext(ctx).AvailableDomains)
+	}
+	key := fmt.Sprintf("remaining:%s:%s", Family(shape), ad)
+	if remaining, ok := p.cache.Get(key); ok {
+		return remaining.(Remaining), nil
+	}
+	remaining, err := p.remainingOfFamily(ctx, Family(shape), ad)
+	if err != nil {
+		p.cache.Set(key, Remaining{}, ocicache.LimitsFailureTTL)
+		return Remaining{}, err
+	}
+	p.cache.SetDefault(key, remaining)
+	return remaining, nil
+}
+
+func (p *Provider) remainingOfFamily(ctx context.Context, family, ad string) (Remaining, error) {
+	scopes, err := p.limitScopes(ctx)
+	if err != nil {
+		return Remaining{}, err
+	}
+	cores, err := p.remaining(ctx, scopes, fmt.Sprintf(limitNameFormat, family, coresResource), ad)
+	if err != nil {
+		return Remaining{}, err
+	}
+	memory, err := p.remaining(ctx, scopes, fmt.Sprintf(limitNameFormat, family, memoryResource), ad)
+	if err != nil {
+		return Remaining{}, err
+	}
+	return Remaining{Cores: cores, MemoryInGBs: memory}, nil
+}
+
+// remaining returns the available count of the limit, nil if the tenancy has no such limit
+func (p *Provider) remaining(ctx context.Context, scopes map[string]bool, name, ad string) (*float64, error) {
+	adScoped, ok := scopes[name]
+	if !ok {
+		return nil, nil
+	}
+	available, err := p.availability(ctx, name, lo.Ternary(adScoped, ad, ""))
+	if err != nil {
+		return nil, err
+	}
+	return &available, nil
+}
+
+// limitScopes returns the names of the compute limits of the tenancy, true if the limit is scoped to an availability domain
+func (p *Provider) limitScopes(ctx context.Context) (map[string]bool, error) {
+	if scopes, ok := p.cache.Get(limitValuesKey); ok {
+		return scopes.(map[string]bool), nil
+	}
+	scopes := map[string]bool{}
+	var page *string
+	for {
+		resp, err := p.client.ListLimitValues(ctx, limits.ListLimitValuesRequest{
+			CompartmentId: common.String(p.tenancyId),
+			ServiceName:   common.String(computeService),
+			Page:          page,
+		})
+		if err != nil {
+			// the other families are resolved with no limits until the failure expires
+			p.cache.Set(limitValuesKey, map[string]bool{}, ocicache.LimitsFailureTTL)
+			return nil, fmt.Errorf("listing the limits of %s, %w", computeService, err)
+		}
+		for _, item := range resp.Items {
+			name := lo.FromPtr(item.Name)
+			scopes[name] = scopes[name] || item.ScopeType == limits.LimitValueSummaryScopeTypeAd
+		}
+		if page = resp.OpcNextPage; page == nil {
+			break
+		}
+	}
+	p.cache.SetDefault(limitValuesKey, scopes)
+	return scopes, nil
+}
+
+// availability returns the available count of the limit in the compartment of the instances
+func (p *Provider) availability(ctx context.Context, name, ad string) (float64, error) {
+	key := fmt.Sprintf("availability:%s:%s", name, ad)
+	if available, ok := p.cache.Get(key); ok {
+		return available.(float64), nil
+	}
+	resp, err := p.client.GetResourceAvailability(ctx, limits.GetResourceAvailabilityRequest{
+		ServiceName:        common.String(computeService),
+		LimitName:          common.String(name),
+		CompartmentId:      common.String(options.FromContext(ctx).CompartmentId),
+		AvailabilityDomain: lo.EmptyableToPtr(ad),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("getting the availability of limit %s, %w", name, err)
+	}
+	available := float64(lo.FromPtr(resp.Available))
+	if resp.FractionalAvailability != nil {
+		available = float64(*resp.FractionalAvailability)
+	}
+	p.cache.SetDefault(key, available)
+	return available, nil
+}
+
+// Family returns the prefix of the limit names of the shape, e.g. standard-e4 for VM.Standard.E4.Flex and
+// BM.Standard.E4.128, standard3 for VM.Standard3.Flex
+func Family(shape string) string {
+	parts := strings.Split(shape, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	if last := parts[len(parts)-1]; len(parts) > 1 && (last == "Flex" || isNumber(last)) {
+		parts = parts[:len(parts)-1]
+	}
+	family := strings.ToLower(strings.Join(parts, "-"))
+	return strings.Replace(family, "denseio", "dense-io", 1)
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package limits_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	ocilimits "github.com/oracle/oci-go-sdk/v65/limits"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
+	"github.com/zoom/karpenter-oci/pkg/fake"
+	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/providers/limits"
+	"github.com/zoom/karpenter-oci/pkg/test"
+)
+
+func TestFamily(t *testing.T) {
+	for shape, family := range map[string]string{
+		"VM.Standard.E4.Flex": "standard-e4",
+		"BM.Standard.E4.128":  "standard-e4",
+		"VM.Standard3.Flex":   "standard3",
+		"VM.Standard2.4":      "standard2",
+		"VM.Standard.A1.Flex": "standard-a1",
+		"VM.DenseIO.E4.Flex":  "dense-io-e4",
+		"VM.Optimized3.Flex":  "optimized3",
+		"shape-1":             "shape-1",
+		"VM.Standard.E5.Flex": "standard-e5",
+		"BM.Standard.A1.160":  "standard-a1",
+		"VM.Standard.E3.Flex": "standard-e3",
+	} {
+		if got := limits.Family(shape); got != family {
+			t.Errorf("expected family %s of %s, got %s", family, shape, got)
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	ctx := options.ToContext(context.Background(), test.Options(test.OptionsFields{
+		AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2"}}))
+	cli := fake.NewLimitsCli()
+	cli.LimitValues.Add(&ocilimits.LimitValueSummary{Name: common.String("standard-e4-core-count"),
+		ScopeType: ocilimits.LimitValueSummaryScopeTypeAd, AvailabilityDomain: common.String("JPqd:US-ASHBURN-AD-1"), Value: common.Int64(100)})
+	cli.LimitValues.Add(&ocilimits.LimitValueSummary{Name: common.String("standard-e4-core-count"),
+		ScopeType: ocilimits.LimitValueSummaryScopeTypeAd, AvailabilityDomain: common.String("JPqd:US-ASHBURN-AD-2"), Value: common.Int64(100)})
+	cli.LimitValues.Add(&ocilimits.LimitValueSummary{Name: common.String("standard-e4-memory-count"),
+		ScopeType: ocilimits.LimitValueSummaryScopeTypeRegion, Value: common.Int64(1000)})
+	cli.ResourceAvailabilities.Set(&map[string]ocilimits.ResourceAvailability{
+		"standard-e4-core-count/JPqd:US-ASHBURN-AD-1": {Available: common.Int64(2), FractionalAvailability: common.Float32(2.5)},
+		"standard-e4-core-count/JPqd:US-ASHBURN-AD-2": {Available: common.Int64(64)},
+		// the memory limit is regional
+		"standard-e4-memory-count/": {Available: common.Int64(512), EffectiveQuotaValue: common.Float32(512)},
+	})
+	provider := limits.NewProvider(cli, "ocid1.tenancy.oc1..aaaaaaaa", cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
+
+	remaining, err := provider.Remaining(ctx, "VM.Standard.E4.Flex", "US-ASHBURN-AD-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo.FromPtr(remaining.Cores) != 2.5 || lo.FromPtr(remaining.MemoryInGBs) != 512 {
+		t.Errorf("unexpected remaining cores %v and memory %v in AD-1", lo.FromPtr(remaining.Cores), lo.FromPtr(remaining.MemoryInGBs))
+	}
+	if remaining.Fits(4, 16) || !remaining.Fits(2, 16) || remaining.Fits(2, 1024) {
+		t.Errorf("unexpected fits of remaining cores %v and memory %v", lo.FromPtr(remaining.Cores), lo.FromPtr(remaining.MemoryInGBs))
+	}
+	remaining, err = provider.Remaining(ctx, "BM.Standard.E4.128", "US-ASHBURN-AD-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo.FromPtr(remaining.Cores) != 64 || lo.FromPtr(remaining.MemoryInGBs) != 512 {
+		t.Errorf("unexpected remaining cores %v and memory %v in AD-2", lo.FromPtr(remaining.Cores), lo.FromPtr(remaining.MemoryInGBs))
+	}
+	// the family without limits is unlimited
+	remaining, err = provider.Remaining(ctx, "VM.Standard3.Flex", "US-ASHBURN-AD-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining.Cores != nil || remaining.MemoryInGBs != nil || !remaining.Fits(1000, 1000) {
+		t.Errorf("expected no limits of standard3")
+	}
+	// the limits and the availabilities are cached
+	if _, err = provider.Remaining(ctx, "VM.Standard.E4.Flex", "US-ASHBURN-AD-1"); err != nil {
+		t.Fatal(err)
+	}
+	if cli.CalledWithListLimitValues.Len() != 1 || cli.CalledWithGetResourceAvailability.Len() != 3 {
+		t.Errorf("expected cached limits, got %d list and %d get calls", cli.CalledWithListLimitValues.Len(), cli.CalledWithGetResourceAvailability.Len())
+	}
+	if _, err = provider.Remaining(ctx, "VM.Standard.E4.Flex", "US-ASHBURN-AD-3"); err == nil {
+		t.Errorf("expected an error for an unknown zone")
+	}
+}
+
+func TestRemainingFailure(t *testing.T) {
+	ctx := options.ToContext(context.Background(), test.Options(test.OptionsFields{
+		AvailableDomains: []string{"JPqd:US-ASHBURN-AD-1", "JPqd:US-ASHBURN-AD-2"}}))
+	cli := fake.NewLimitsCli()
+	cli.ListLimitValuesError.Set(&fake.FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "not authorized"})
+	provider := limits.NewProvider(cli, "ocid1.tenancy.oc1..aaaaaaaa", cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval))
+
+	if _, err := provider.Remaining(ctx, "VM.Standard.E4.Flex", "US-ASHBURN-AD-1"); err == nil {
+		t.Errorf("expected an error when the limits can't be listed")
+	}
+	// the failure is cached with no limits for the family and the other families
+	for _, shape := range []string{"VM.Standard.E4.Flex", "VM.Standard3.Flex"} {
+		for _, zone := range []string{"US-ASHBURN-AD-1", "US-ASHBURN-AD-2"} {
+			remaining, err := provider.Remaining(ctx, shape, zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if remaining.Cores != nil || remaining.MemoryInGBs != nil {
+				t.Errorf("expected no limits of %s in %s after the failure", shape, zone)
+			}
+		}
+	}
+	if cli.CalledWithListLimitValues.Len() != 1 {
+		t.Errorf("expected the failure cached, got %d list calls", cli.CalledWithListLimitValues.Len())
+	}
+}
@@ -15,6 +15,7 @@ limitations under the License.
 package pricing
 
 import (
+	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/zoom/karpenter-oci/pkg/providers/internalmodel"
 	"github.com/zoom/karpenter-oci/pkg/utils"
 	"math"
@@ -24,15 +25,14 @@ import (
 func Calculate(shape *internalmodel.WrapShape, catalog *PriceCatalog) float32 {
 
 	// Determine OCPU-to-vCPU multiplier based on shape
-	shapeName := *shape.Shape.Shape
-	ratioFactor := 2
-	if utils.IsA1FlexShape(shapeName) {
-		ratioFactor = 1
-	}
+	ratioFactor := utils.VcpusPerOcpu(shape.Shape)
+
+	// burstable instances are charged for the baseline of the OCPUs
+	baselineFactor := baselineOcpuFactor(shape.CalBaselineOcpuUtilization)
 
 	if catalog == nil {
 
-		return float32(8.0*(shape.CalcCpu/int64(ratioFactor)) + (shape.CalMemInGBs))
+		return float32(8.0*float32(shape.CalcCpu/int64(ratioFactor))*baselineFactor + float32(shape.CalMemInGBs))
 	}
 	items := catalog.FindPriceItems(*shape.Shape.Shape)
 	priceLen := len(items)
@@ -50,7 +50,7 @@ func Calculate(shape *internalmodel.WrapShape, catalog *PriceCatalog) float32 {
 		case GpuPerHour:
 			return float32(*shape.Gpus) * it.PricePerUnit()
 		case OcpuPerHour:
-			return float32(shape.CalcCpu/int64(ratioFactor)) * it.PricePerUnit()
+			return float32(shape.CalcCpu/int64(ratioFactor)) * baselineFactor * it.PricePerUnit()
 		case GigabytePerHour:
 			return float32(shape.CalMemInGBs) * it.PricePerUnit()
 		case NodePerHour:
@@ -68,7 +68,7 @@ func Calculate(shape *internalmodel.WrapShape, catalog *PriceCatalog) float32 {
 		for _, item := range items {
 
 			if item.IsOcpuType() {
-				price += float32(shape.CalcCpu/int64(ratioFactor)) * item.PricePerUnit()
+				price += float32(shape.CalcCpu/int64(ratioFactor)) * baselineFactor * item.PricePerUnit()
 			} else if item.IsMemoryType() {
 				price += float32(shape.CalMemInGBs) * item.PricePerUnit()
 			} else if item.IsNVMeType() {
@@ -93,6 +93,35 @@ func Calculate(shape *internalmodel.WrapShape, catalog *PriceCatalog) float32 {
 	return 0
 }
 
+// CalculateVolume returns the hourly price of a volume, the storage and the performance units are both charged per GB
+func CalculateVolume(sizeInGBs int64, vpusPerGB int64, catalog *PriceCatalog) float32 {
+	// list prices of the storage and performance units per GB per month
+	var storagePrice, performanceUnitPrice float32 = 0.0255, 0.0017
+	if catalog != nil {
+		for _, item := range catalog.Items {
+			switch item.PartNumber {
+			case BlockVolumeStoragePartNumber:
+				storagePrice = item.GetPrice(USD)
+			case BlockVolumePerformanceUnitsPartNumber:
+				performanceUnitPrice = item.GetPrice(USD)
+			}
+		}
+	}
+	return float32(sizeInGBs) * (storagePrice + float32(vpusPerGB)*performanceUnitPrice) / HoursPerMonth
+}
+
+// baselineOcpuFactor returns the fraction of the OCPU price charged for the baseline
+func baselineOcpuFactor(baseline string) float32 {
+	switch baseline {
+	case string(core.ShapeBaselineOcpuUtilizations8):
+		return 0.125
+	case string(core.ShapeBaselineOcpuUtilizations2):
+		return 0.5
+	default:
+		return 1
+	}
+}
+
 func ContainOcpu(shape string) bool {
 	return strings.Contains(shape, "OCPU")
 }
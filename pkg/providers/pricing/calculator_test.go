@@ -16,6 +16,7 @@ package pricing
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/stretchr/testify/assert"
 	"github.com/zoom/karpenter-oci/pkg/providers/internalmodel"
@@ -199,3 +200,49 @@ func TestPrice(t *testing.T) {
 		}
 	}
 }
+
+func TestCalculateVolume(t *testing.T) {
+	catalog := &PriceCatalog{}
+	if err := json.Unmarshal([]byte(defaultPrice), catalog); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		SizeInGBs int64
+		VpusPerGB int64
+		Price     float32
+	}{
+		{SizeInGBs: 100, VpusPerGB: 0, Price: 100 * 0.0255 / 744},
+		{SizeInGBs: 100, VpusPerGB: 10, Price: 100 * (0.0255 + 10*0.0017) / 744},
+		{SizeInGBs: 1000, VpusPerGB: 120, Price: 1000 * (0.0255 + 120*0.0017) / 744},
+	}
+	for _, tc := range testCases {
+		assert.InDelta(t, tc.Price, CalculateVolume(tc.SizeInGBs, tc.VpusPerGB, catalog), 1e-6)
+		assert.InDelta(t, tc.Price, CalculateVolume(tc.SizeInGBs, tc.VpusPerGB, nil), 1e-6)
+	}
+}
+
+func TestCalculateBurstable(t *testing.T) {
+	catalog := &PriceCatalog{}
+	if err := json.Unmarshal([]byte(defaultPrice), catalog); err != nil {
+		t.Fatal(err)
+	}
+	standardE4 := "VM.Standard.E4.Flex"
+	testCases := []struct {
+		Baseline string
+		Price    float32
+	}{
+		{Baseline: "", Price: 0.025 + 8*0.0015},
+		{Baseline: string(core.ShapeBaselineOcpuUtilizations1), Price: 0.025 + 8*0.0015},
+		{Baseline: string(core.ShapeBaselineOcpuUtilizations2), Price: 0.025*0.5 + 8*0.0015},
+		{Baseline: string(core.ShapeBaselineOcpuUtilizations8), Price: 0.025*0.125 + 8*0.0015},
+	}
+	for _, tc := range testCases {
+		wrapShape := &internalmodel.WrapShape{
+			Shape:                      core.Shape{Shape: &standardE4},
+			CalcCpu:                    2,
+			CalMemInGBs:                8,
+			CalBaselineOcpuUtilization: tc.Baseline,
+		}
+		assert.InDelta(t, tc.Price, Calculate(wrapShape, catalog), 1e-6)
+	}
+}
@@ -38,6 +38,11 @@ const (
 	GigabytePerHour     = "Gigabyte Per Hour"
 	NodePerHour         = "Node Per Hour"
 	NVMeTerabytePerHour = "NVMe Terabyte Per Hour"
+
+	BlockVolumeStoragePartNumber          = "B91961"
+	BlockVolumePerformanceUnitsPartNumber = "B91962"
+	// block volumes are billed per month, the price list assumes 744 hours a month
+	HoursPerMonth = 744
 )
 
 var specialTypeMap = map[string]string{
@@ -295,6 +300,7 @@ func isNumeric(s string) bool {
 
 type Provider interface {
 	Price(shape *internalmodel.WrapShape) float32
+	VolumePrice(sizeInGBs int64, vpusPerGB int64) float32
 	UpdateOnDemandPricing(context.Context) error
 }
 
@@ -323,6 +329,13 @@ func (p *DefaultProvider) Price(shape *internalmodel.WrapShape) float32 {
 	return price
 }
 
+// VolumePrice returns the hourly price of a block volume or boot volume
+func (p *DefaultProvider) VolumePrice(sizeInGBs int64, vpusPerGB int64) float32 {
+	p.muOnDemand.RLock()
+	defer p.muOnDemand.RUnlock()
+	return CalculateVolume(sizeInGBs, vpusPerGB, p.priceCatalog)
+}
+
 func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	p.muOnDemand.Lock()
 	defer p.muOnDemand.Unlock()
@@ -27,6 +27,7 @@ import (
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/utils"
 )
 
 type Provider struct {
@@ -67,19 +68,29 @@ func (p *Provider) List(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) (
 				return nil, err
 			}
 			sgs[lo.FromPtr(resp.Id)] = resp.NetworkSecurityGroup
-		} else if selector.Name != "" {
+		} else if selector.Name != "" || len(selector.Tags) != 0 {
 			req := core.ListNetworkSecurityGroupsRequest{CompartmentId: common.String(options.FromContext(ctx).CompartmentId),
 				VcnId:          common.String(nodeClass.Spec.VcnId),
-				DisplayName:    common.String(selector.Name),
 				LifecycleState: core.NetworkSecurityGroupLifecycleStateAvailable,
 			}
-			// Send the request using the service client
-			resp, err := p.client.ListNetworkSecurityGroups(ctx, req)
-			if err != nil {
-				return nil, err
+			if selector.Name != "" {
+				req.DisplayName = common.String(selector.Name)
 			}
-			for _, sg := range resp.Items {
-				sgs[lo.FromPtr(sg.Id)] = sg
+			// the tags are matched on the client side, so all the pages are listed
+			for {
+				resp, err := p.client.ListNetworkSecurityGroups(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				for _, sg := range resp.Items {
+					if !utils.MatchTags(selector.Tags, sg.FreeformTags, sg.DefinedTags) {
+						continue
+					}
+					sgs[lo.FromPtr(sg.Id)] = sg
+				}
+				if req.Page = resp.OpcNextPage; req.Page == nil {
+					break
+				}
 			}
 		}
 	}
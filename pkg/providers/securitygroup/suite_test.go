@@ -97,6 +97,63 @@ var _ = Describe("SecurityGroupProvider", func() {
 			},
 		}, securityGroups)
 	})
+	It("should discover security groups by tags", func() {
+		ociEnv.VcnCli.ListSecurityGroupOutput.Set(&core.ListNetworkSecurityGroupsResponse{Items: []core.NetworkSecurityGroup{
+			{
+				Id:           common.String("sg-test1"),
+				DisplayName:  common.String("securityGroup-test1"),
+				FreeformTags: map[string]string{"role": "worker"},
+			},
+			{
+				Id:           common.String("sg-test2"),
+				DisplayName:  common.String("securityGroup-test2"),
+				FreeformTags: map[string]string{"role": "control-plane"},
+			},
+			{
+				Id:          common.String("sg-test3"),
+				DisplayName: common.String("securityGroup-test3"),
+				DefinedTags: map[string]map[string]interface{}{"oke": {"role": "worker"}},
+			},
+		}})
+		nodeClass.Spec.SecurityGroupSelector = []v1alpha1.SecurityGroupSelectorTerm{
+			{Tags: map[string]string{"role": "worker"}},
+			{Tags: map[string]string{"oke.role": "*"}}}
+		securityGroups, err := ociEnv.SecurityGroupProvider.List(ctx, nodeClass)
+		Expect(err).To(BeNil())
+		ExpectConsistsOfSecurityGroups([]core.NetworkSecurityGroup{
+			{
+				Id:          common.String("sg-test1"),
+				DisplayName: common.String("securityGroup-test1"),
+			},
+			{
+				Id:          common.String("sg-test3"),
+				DisplayName: common.String("securityGroup-test3"),
+			},
+		}, securityGroups)
+	})
+	It("should discover security groups by tags on the next pages", func() {
+		ociEnv.VcnCli.ListSecurityGroupOutput.Set(&core.ListNetworkSecurityGroupsResponse{Items: []core.NetworkSecurityGroup{
+			{
+				Id:          common.String("sg-test1"),
+				DisplayName: common.String("securityGroup-test1"),
+			},
+			{
+				Id:           common.String("sg-test2"),
+				DisplayName:  common.String("securityGroup-test2"),
+				FreeformTags: map[string]string{"role": "worker"},
+			},
+		}})
+		ociEnv.VcnCli.PageSize.Set(lo.ToPtr(1))
+		nodeClass.Spec.SecurityGroupSelector = []v1alpha1.SecurityGroupSelectorTerm{{Tags: map[string]string{"role": "worker"}}}
+		securityGroups, err := ociEnv.SecurityGroupProvider.List(ctx, nodeClass)
+		Expect(err).To(BeNil())
+		ExpectConsistsOfSecurityGroups([]core.NetworkSecurityGroup{
+			{
+				Id:          common.String("sg-test2"),
+				DisplayName: common.String("securityGroup-test2"),
+			},
+		}, securityGroups)
+	})
 	Context("Provider Cache", func() {
 		It("should resolve security groups from cache that are filtered by name", func() {
 			expectedSecurityGroups := ociEnv.VcnCli.ListSecurityGroupOutput.Clone().Items
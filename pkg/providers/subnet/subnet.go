@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"sync"
 
 	"github.com/mitchellh/hashstructure/v2"
@@ -28,16 +29,20 @@ import (
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
 	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
+	"github.com/zoom/karpenter-oci/pkg/utils"
 )
 
 type Provider struct {
 	sync.Mutex
 	client api.VirtualNetworkClient
 	cache  *cache.Cache
+	// inflightIPs are the IPs reserved for the launching instances, key: subnet id
+	inflightMu  sync.Mutex
+	inflightIPs map[string]int
 }
 
 func NewProvider(client api.VirtualNetworkClient, cache *cache.Cache) *Provider {
-	return &Provider{client: client, cache: cache}
+	return &Provider{client: client, cache: cache, inflightIPs: map[string]int{}}
 }
 
 func (p *Provider) GetSubnetUtilization(ctx context.Context, subnet *core.Subnet) (summary []core.IpInventoryCidrUtilizationSummary, err error) {
@@ -79,6 +84,66 @@ func (p *Provider) GetSubnetAvailableIPv4Count(ctx context.Context, subnet *core
 	return availableCount, nil
 }
 
+// AvailableIPs returns the available IPs of the subnet minus the IPs reserved for the launching instances, the
+// utilization of the subnet is cached
+func (p *Provider) AvailableIPs(ctx context.Context, subnet *core.Subnet) (int, error) {
+	count, ok := p.cachedAvailableIPs(lo.FromPtr(subnet.Id))
+	if !ok {
+		var err error
+		if count, err = p.GetSubnetAvailableIPv4Count(ctx, subnet); err != nil {
+			return 0, err
+		}
+		p.cache.SetDefault(availableIPsKey(lo.FromPtr(subnet.Id)), count)
+	}
+	return max(0, count-p.reservedIPs(lo.FromPtr(subnet.Id))), nil
+}
+
+// CachedAvailableIPs returns the available IPs of the subnet minus the IPs reserved for the launching instances,
+// false if the utilization of the subnet isn't cached
+func (p *Provider) CachedAvailableIPs(subnetId string) (int, bool) {
+	count, ok := p.cachedAvailableIPs(subnetId)
+	if !ok {
+		return 0, false
+	}
+	return max(0, count-p.reservedIPs(subnetId)), true
+}
+
+// ReserveIPs reserves the IPs of the subnet for a launching instance until the returned func is called, the IPs are
+// taken from the cached utilization if the instance is launched, otherwise they are released
+func (p *Provider) ReserveIPs(subnetId string, ips int) func(launched bool) {
+	p.inflightMu.Lock()
+	defer p.inflightMu.Unlock()
+	p.inflightIPs[subnetId] += ips
+	return func(launched bool) {
+		p.inflightMu.Lock()
+		defer p.inflightMu.Unlock()
+		if p.inflightIPs[subnetId] -= ips; p.inflightIPs[subnetId] <= 0 {
+			delete(p.inflightIPs, subnetId)
+		}
+		if launched {
+			// the cached utilization doesn't include the launched instance until it expires
+			_, _ = p.cache.DecrementInt(availableIPsKey(subnetId), ips)
+		}
+	}
+}
+
+func (p *Provider) cachedAvailableIPs(subnetId string) (int, bool) {
+	if count, ok := p.cache.Get(availableIPsKey(subnetId)); ok {
+		return count.(int), true
+	}
+	return 0, false
+}
+
+func (p *Provider) reservedIPs(subnetId string) int {
+	p.inflightMu.Lock()
+	defer p.inflightMu.Unlock()
+	return p.inflightIPs[subnetId]
+}
+
+func availableIPsKey(subnetId string) string {
+	return fmt.Sprintf("available-ips:%s", subnetId)
+}
+
 func (p *Provider) List(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) ([]core.Subnet, error) {
 
 	hash, err := hashstructure.Hash(nodeClass.Spec.SubnetSelector, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
@@ -104,21 +169,31 @@ func (p *Provider) List(ctx context.Context, nodeClass *v1alpha1.OciNodeClass) (
 				return nil, err
 			}
 			subnets[lo.FromPtr(resp.Id)] = resp.Subnet
-		} else if selector.Name != "" {
+		} else if selector.Name != "" || len(selector.Tags) != 0 {
 			// Create a request and dependent object(s).
 			req := core.ListSubnetsRequest{CompartmentId: common.String(options.FromContext(ctx).CompartmentId),
 				VcnId:          common.String(nodeClass.Spec.VcnId),
-				DisplayName:    common.String(selector.Name),
 				LifecycleState: core.SubnetLifecycleStateAvailable,
 			}
-
-			// Send the request using the service client
-			resp, err := p.client.ListSubnets(ctx, req)
-			if err != nil {
-				return nil, err
+			if selector.Name != "" {
+				req.DisplayName = common.String(selector.Name)
 			}
-			for _, subnet := range resp.Items {
-				subnets[lo.FromPtr(subnet.Id)] = subnet
+
+			// the tags are matched on the client side, so all the pages are listed
+			for {
+				resp, err := p.client.ListSubnets(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				for _, subnet := range resp.Items {
+					if !utils.MatchTags(selector.Tags, subnet.FreeformTags, subnet.DefinedTags) {
+						continue
+					}
+					subnets[lo.FromPtr(subnet.Id)] = subnet
+				}
+				if req.Page = resp.OpcNextPage; req.Page == nil {
+					break
+				}
 			}
 		}
 	}
@@ -175,6 +250,17 @@ func (p *Provider) GetSubnets(ctx context.Context, vnics []core.VnicAttachment,
 	return subnets, nil
 }
 
+// Zones returns the availability domains without the tenancy prefix where the subnet can launch instances,
+// a regional subnet spans all the availability domains of the region
+func Zones(ctx context.Context, subnet *core.Subnet) []string {
+	if ad := lo.FromPtr(subnet.AvailabilityDomain); ad != "" {
+		return []string{lo.LastOrEmpty(strings.Split(ad, ":"))}
+	}
+	return lo.Map(options.FromContext(ctx).AvailableDomains, func(ad string, _ int) string {
+		return lo.LastOrEmpty(strings.Split(ad, ":"))
+	})
+}
+
 func calculateTotalIps(cidr string) (int, error) {
 	_, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
@@ -24,6 +24,7 @@ import (
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/zoom/karpenter-oci/pkg/apis"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"github.com/zoom/karpenter-oci/pkg/fake"
 	"github.com/zoom/karpenter-oci/pkg/operator/options"
 	"github.com/zoom/karpenter-oci/pkg/test"
 	"sigs.k8s.io/karpenter/pkg/test/expectations"
@@ -98,6 +99,76 @@ var _ = Describe("SubnetProvider", func() {
 				},
 			}, subnets)
 		})
+		It("should discover subnets by freeform and defined tags", func() {
+			ociEnv.VcnCli.ListSubnetsOutput.Set(&core.ListSubnetsResponse{Items: []core.Subnet{
+				{
+					Id:           lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaaa"),
+					DisplayName:  lo.ToPtr("private-1"),
+					FreeformTags: map[string]string{"role": "worker"},
+					DefinedTags:  map[string]map[string]interface{}{"oke": {"cluster": "test-cluster"}},
+				},
+				{
+					Id:           lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaab"),
+					DisplayName:  lo.ToPtr("private-2"),
+					FreeformTags: map[string]string{"role": "worker"},
+					DefinedTags:  map[string]map[string]interface{}{"oke": {"cluster": "other-cluster"}},
+				},
+				{
+					Id:          lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaac"),
+					DisplayName: lo.ToPtr("private-3"),
+				},
+			}})
+			nodeClass.Spec.SubnetSelector = []v1alpha1.SubnetSelectorTerm{{
+				Tags: map[string]string{"role": "worker", "oke.cluster": "test-cluster"},
+			}}
+			subnets, err := ociEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			ExpectConsistsOfSubnets([]core.Subnet{
+				{
+					Id:          lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaaa"),
+					DisplayName: lo.ToPtr("private-1"),
+				},
+			}, subnets)
+
+			nodeClass.Spec.SubnetSelector = []v1alpha1.SubnetSelectorTerm{{
+				Tags: map[string]string{"oke.cluster": "*"},
+			}}
+			subnets, err = ociEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			ExpectConsistsOfSubnets([]core.Subnet{
+				{
+					Id:          lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaaa"),
+					DisplayName: lo.ToPtr("private-1"),
+				},
+				{
+					Id:          lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaab"),
+					DisplayName: lo.ToPtr("private-2"),
+				},
+			}, subnets)
+		})
+		It("should discover subnets by tags on the next pages", func() {
+			ociEnv.VcnCli.ListSubnetsOutput.Set(&core.ListSubnetsResponse{Items: []core.Subnet{
+				{
+					Id:          lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaaa"),
+					DisplayName: lo.ToPtr("private-1"),
+				},
+				{
+					Id:           lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaab"),
+					DisplayName:  lo.ToPtr("private-2"),
+					FreeformTags: map[string]string{"role": "worker"},
+				},
+			}})
+			ociEnv.VcnCli.PageSize.Set(lo.ToPtr(1))
+			nodeClass.Spec.SubnetSelector = []v1alpha1.SubnetSelectorTerm{{Tags: map[string]string{"role": "worker"}}}
+			subnets, err := ociEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			ExpectConsistsOfSubnets([]core.Subnet{
+				{
+					Id:          lo.ToPtr("ocid1.subnet.oc1.iad.aaaaaaab"),
+					DisplayName: lo.ToPtr("private-2"),
+				},
+			}, subnets)
+		})
 	})
 	Context("Provider Cache", func() {
 		It("should resolve subnets from cache that are filtered by name", func() {
@@ -120,6 +191,46 @@ var _ = Describe("SubnetProvider", func() {
 			}
 		})
 	})
+	Context("Available IPs", func() {
+		It("should cache the available IPs of the subnet", func() {
+			subnet := fake.DefaultSubnets[0]
+			expected, err := ociEnv.SubnetProvider.GetSubnetAvailableIPv4Count(ctx, &subnet)
+			Expect(err).To(BeNil())
+			_, ok := ociEnv.SubnetProvider.CachedAvailableIPs(lo.FromPtr(subnet.Id))
+			Expect(ok).To(BeFalse())
+
+			count, err := ociEnv.SubnetProvider.AvailableIPs(ctx, &subnet)
+			Expect(err).To(BeNil())
+			Expect(count).To(Equal(expected))
+			count, ok = ociEnv.SubnetProvider.CachedAvailableIPs(lo.FromPtr(subnet.Id))
+			Expect(ok).To(BeTrue())
+			Expect(count).To(Equal(expected))
+		})
+		It("should release the reserved IPs when the launch fails", func() {
+			subnet := fake.DefaultSubnets[0]
+			expected, err := ociEnv.SubnetProvider.AvailableIPs(ctx, &subnet)
+			Expect(err).To(BeNil())
+
+			release := ociEnv.SubnetProvider.ReserveIPs(lo.FromPtr(subnet.Id), 10)
+			count, err := ociEnv.SubnetProvider.AvailableIPs(ctx, &subnet)
+			Expect(err).To(BeNil())
+			Expect(count).To(Equal(expected - 10))
+			release(false)
+			count, err = ociEnv.SubnetProvider.AvailableIPs(ctx, &subnet)
+			Expect(err).To(BeNil())
+			Expect(count).To(Equal(expected))
+		})
+		It("should take the reserved IPs from the cached utilization when the instance is launched", func() {
+			subnet := fake.DefaultSubnets[0]
+			expected, err := ociEnv.SubnetProvider.AvailableIPs(ctx, &subnet)
+			Expect(err).To(BeNil())
+
+			ociEnv.SubnetProvider.ReserveIPs(lo.FromPtr(subnet.Id), 10)(true)
+			count, ok := ociEnv.SubnetProvider.CachedAvailableIPs(lo.FromPtr(subnet.Id))
+			Expect(ok).To(BeTrue())
+			Expect(count).To(Equal(expected - 10))
+		})
+	})
 	It("should not cause data races when calling List() simultaneously", func() {
 		wg := sync.WaitGroup{}
 		for i := 0; i < 10000; i++ {
@@ -25,10 +25,16 @@ import (
 	"github.com/samber/lo"
 	ocicache "github.com/zoom/karpenter-oci/pkg/cache"
 	fake "github.com/zoom/karpenter-oci/pkg/fake"
+	"github.com/zoom/karpenter-oci/pkg/operator/oci/api"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityprobe"
+	"github.com/zoom/karpenter-oci/pkg/providers/capacityreservation"
+	"github.com/zoom/karpenter-oci/pkg/providers/consolehistory"
 	"github.com/zoom/karpenter-oci/pkg/providers/imagefamily"
 	"github.com/zoom/karpenter-oci/pkg/providers/instance"
 	"github.com/zoom/karpenter-oci/pkg/providers/instancetype"
+	"github.com/zoom/karpenter-oci/pkg/providers/kms"
 	"github.com/zoom/karpenter-oci/pkg/providers/launchtemplate"
+	"github.com/zoom/karpenter-oci/pkg/providers/limits"
 	"github.com/zoom/karpenter-oci/pkg/providers/securitygroup"
 	"github.com/zoom/karpenter-oci/pkg/providers/subnet"
 	"knative.dev/pkg/ptr"
@@ -44,45 +50,73 @@ func init() {
 
 type Environment struct {
 	// API
-	CmpCli *fake.CmpCli
-	VcnCli *fake.VcnCli
+	CmpCli          *fake.CmpCli
+	VcnCli          *fake.VcnCli
+	KmsCli          *fake.KmsCli
+	LimitsCli       *fake.LimitsCli
+	BlockStorageCli *fake.BlockStorageCli
+	WorkRequestCli  *fake.WorkRequestCli
 
 	// Cache
 	AmiCache                  *cache.Cache
 	InstanceTypeCache         *cache.Cache
 	SubnetCache               *cache.Cache
 	SecurityGroupCache        *cache.Cache
+	CapacityReservationCache  *cache.Cache
+	KmsKeyCache               *cache.Cache
+	LimitsCache               *cache.Cache
+	CapacityProbeCache        *cache.Cache
+	ConsoleHistoryCache       *cache.Cache
 	UnavailableOfferingsCache *ocicache.UnavailableOfferings
 
 	// Providers
-	InstanceTypesProvider  *instancetype.Provider
-	InstanceProvider       *instance.Provider
-	SubnetProvider         *subnet.Provider
-	SecurityGroupProvider  *securitygroup.Provider
-	AMIProvider            *imagefamily.Provider
-	AMIResolver            *imagefamily.Resolver
-	LaunchTemplateProvider *launchtemplate.DefaultProvider
+	InstanceTypesProvider       *instancetype.Provider
+	InstanceProvider            *instance.Provider
+	SubnetProvider              *subnet.Provider
+	SecurityGroupProvider       *securitygroup.Provider
+	AMIProvider                 *imagefamily.Provider
+	AMIResolver                 *imagefamily.Resolver
+	LaunchTemplateProvider      *launchtemplate.DefaultProvider
+	CapacityReservationProvider *capacityreservation.Provider
+	KmsProvider                 *kms.Provider
+	LimitsProvider              *limits.Provider
+	CapacityProbeProvider       *capacityprobe.Provider
+	ConsoleHistoryProvider      *consolehistory.Provider
 }
 
 func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment {
 	// API
 	cmpCli := fake.NewCmpCli()
 	vcnCli := fake.NewVcnCli()
+	kmsCli := fake.NewKmsCli()
+	limitsCli := fake.NewLimitsCli()
+	blockStorageCli := fake.NewBlockStorageCli()
+	workRequestCli := fake.NewWorkRequestCli()
 
 	// cache
 	amiCache := cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval)
 	instanceTypeCache := cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval)
 	subnetCache := cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval)
 	sgCache := cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval)
+	capacityReservationCache := cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval)
+	kmsKeyCache := cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval)
+	limitsCache := cache.New(ocicache.DefaultTTL, ocicache.DefaultCleanupInterval)
+	capacityProbeCache := cache.New(ocicache.CapacityProbeTTL, ocicache.DefaultCleanupInterval)
+	consoleHistoryCache := cache.New(ocicache.ConsoleHistoryTTL, ocicache.DefaultCleanupInterval)
 
 	// Providers
 	subnetProvider := subnet.NewProvider(vcnCli, subnetCache)
 	securityGroupProvider := securitygroup.NewProvider(vcnCli, sgCache)
 	amiProvider := imagefamily.NewProvider(cmpCli, amiCache)
+	capacityReservationProvider := capacityreservation.NewProvider(cmpCli, capacityReservationCache)
+	kmsProvider := kms.NewProvider(func(string) (api.KmsManagementClient, error) { return kmsCli, nil }, kmsKeyCache)
+	limitsProvider := limits.NewProvider(limitsCli, "ocid1.tenancy.oc1..aaaaaaaa", limitsCache)
 	amiResolver := imagefamily.NewResolver(amiProvider)
 	priceProvider := pricing.NewDefaultProvider(ctx, "https://apexapps.oracle.com/pls/apex/cetools/api/v1/products/")
 	unavailableOfferCache := ocicache.NewUnavailableOfferings()
-	instanceTypesProvider := instancetype.NewProvider("us-ashburn-1", cmpCli, instanceTypeCache, unavailableOfferCache, priceProvider)
+	capacityProbeProvider := capacityprobe.NewProvider(cmpCli, "ocid1.tenancy.oc1..aaaaaaaa", unavailableOfferCache, capacityProbeCache)
+	consoleHistoryProvider := consolehistory.NewProvider(cmpCli, env.Client, env.Client, "default", consoleHistoryCache)
+	instanceTypesProvider := instancetype.NewProvider("us-ashburn-1", cmpCli, instanceTypeCache, unavailableOfferCache, priceProvider, subnetProvider, limitsProvider)
 	launchTemplateProvider :=
 		launchtemplate.NewDefaultProvider(
 			amiResolver,
@@ -93,20 +127,34 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	instanceProvider :=
 		instance.NewProvider(
 			cmpCli,
+			blockStorageCli,
+			workRequestCli,
 			subnetProvider,
 			securityGroupProvider,
 			launchTemplateProvider,
+			instanceTypesProvider,
 			unavailableOfferCache,
+			capacityProbeProvider,
+			env.Client,
 		)
 
 	return &Environment{
-		CmpCli: cmpCli,
-		VcnCli: vcnCli,
+		CmpCli:          cmpCli,
+		VcnCli:          vcnCli,
+		KmsCli:          kmsCli,
+		LimitsCli:       limitsCli,
+		BlockStorageCli: blockStorageCli,
+		WorkRequestCli:  workRequestCli,
 
 		AmiCache:                  amiCache,
 		InstanceTypeCache:         instanceTypeCache,
 		SubnetCache:               subnetCache,
 		SecurityGroupCache:        sgCache,
+		CapacityReservationCache:  capacityReservationCache,
+		KmsKeyCache:               kmsKeyCache,
+		LimitsCache:               limitsCache,
+		CapacityProbeCache:        capacityProbeCache,
+		ConsoleHistoryCache:       consoleHistoryCache,
 		UnavailableOfferingsCache: unavailableOfferCache,
 
 		InstanceTypesProvider:  instanceTypesProvider,
@@ -116,18 +164,34 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		LaunchTemplateProvider: launchTemplateProvider,
 		AMIProvider:            amiProvider,
 		AMIResolver:            amiResolver,
+
+		CapacityReservationProvider: capacityReservationProvider,
+		KmsProvider:                 kmsProvider,
+		LimitsProvider:              limitsProvider,
+		CapacityProbeProvider:       capacityProbeProvider,
+		ConsoleHistoryProvider:      consoleHistoryProvider,
 	}
 }
 
 func (env *Environment) Reset() {
 	env.CmpCli.Reset()
 	env.VcnCli.Reset()
+	env.KmsCli.Reset()
+	env.LimitsCli.Reset()
+	env.BlockStorageCli.Reset()
+	env.WorkRequestCli.Reset()
+	env.InstanceProvider.Reset()
 
 	env.UnavailableOfferingsCache.Flush()
 	env.AmiCache.Flush()
 	env.InstanceTypeCache.Flush()
 	env.SubnetCache.Flush()
 	env.SecurityGroupCache.Flush()
+	env.CapacityReservationCache.Flush()
+	env.KmsKeyCache.Flush()
+	env.LimitsCache.Flush()
+	env.CapacityProbeCache.Flush()
+	env.ConsoleHistoryCache.Flush()
 
 	mfs, err := crmetrics.Registry.Gather()
 	if err != nil {
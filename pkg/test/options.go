@@ -30,6 +30,10 @@ type OptionsFields struct {
 	VMMemoryOverheadPercent  *float64
 	FlexCpuMemRatios         *string
 	FlexCpuConstrainList     *string
+	FlexBurstableBaselines   *string
+	MaxLaunchAttempts        *int
+	CapacityProbeShapes      *string
+	CapacityProbePeriod      *int
 	AvailableDomains         []string
 	TagNamespace             *string
 	PreemptibleShapes        *string
@@ -52,6 +56,10 @@ func Options(overrides ...OptionsFields) *options.Options {
 		VMMemoryOverheadPercent:  lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
 		FlexCpuMemRatios:         lo.FromPtrOr(opts.FlexCpuMemRatios, "4"),
 		FlexCpuConstrainList:     lo.FromPtrOr(opts.FlexCpuConstrainList, "2,4,8,16,32,48,64,96,128"),
+		FlexBurstableBaselines:   lo.FromPtrOr(opts.FlexBurstableBaselines, ""),
+		MaxLaunchAttempts:        lo.FromPtrOr(opts.MaxLaunchAttempts, 3),
+		CapacityProbeShapes:      lo.FromPtrOr(opts.CapacityProbeShapes, ""),
+		CapacityProbePeriod:      lo.FromPtrOr(opts.CapacityProbePeriod, 5),
 		TagNamespace:             lo.FromPtrOr(opts.TagNamespace, "tag_namespace"),
 		AvailableDomains:         opts.AvailableDomains,
 		PreemptibleShapes:        lo.FromPtrOr(opts.PreemptibleShapes, "VM.Standard3.Flex,VM.Standard.E2"),
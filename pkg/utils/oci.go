@@ -15,12 +15,116 @@ limitations under the License.
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/core"
 	"github.com/samber/lo"
 	"github.com/zoom/karpenter-oci/pkg/apis/v1alpha1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"strings"
 )
 
+// OciErrorReason is the class of an OCI service error
+type OciErrorReason string
+
+const (
+	// OciErrorUnknown is any error which isn't a classified OCI service error
+	OciErrorUnknown OciErrorReason = ""
+	// OciErrorOutOfCapacity is returned when the availability domain is out of host capacity for the shape
+	OciErrorOutOfCapacity OciErrorReason = "OutOfHostCapacity"
+	// OciErrorLimitExceeded is returned when the service limit of the shape is reached
+	OciErrorLimitExceeded OciErrorReason = "LimitExceeded"
+	// OciErrorQuotaExceeded is returned when the compartment quota of the shape is reached
+	OciErrorQuotaExceeded OciErrorReason = "QuotaExceeded"
+	// OciErrorNotAuthorizedOrNotFound is returned when the resource doesn't exist or the policies don't allow the access
+	OciErrorNotAuthorizedOrNotFound OciErrorReason = "NotAuthorizedOrNotFound"
+	// OciErrorNotAuthenticated is returned when the credentials of the request are rejected
+	OciErrorNotAuthenticated OciErrorReason = "NotAuthenticated"
+	// OciErrorInvalidParameter is returned when the request is rejected, usually by a misconfigured nodeclass
+	OciErrorInvalidParameter OciErrorReason = "InvalidParameter"
+	// OciErrorThrottled is returned when the request rate of the tenancy is too high
+	OciErrorThrottled OciErrorReason = "TooManyRequests"
+	// OciErrorInternal is returned for the other failures of the service
+	OciErrorInternal OciErrorReason = "InternalError"
+)
+
+// ClassifyOciError returns the class of the OCI service error by its status code and code
+// https://docs.oracle.com/en-us/iaas/Content/API/References/apierrors.htm
+func ClassifyOciError(err error) OciErrorReason {
+	var serviceErr common.ServiceError
+	if !errors.As(err, &serviceErr) {
+		return OciErrorUnknown
+	}
+	return classify(serviceErr.GetHTTPStatusCode(), serviceErr.GetCode(), serviceErr.GetMessage())
+}
+
+// ClassifyWorkRequestError returns the class of the error of a failed work request, the work request errors have no
+// status code, so the out of capacity is only known by the code or the message of the shortage
+func ClassifyWorkRequestError(code, message string) OciErrorReason {
+	if code == string(OciErrorOutOfCapacity) || strings.Contains(message, "Out of host capacity") {
+		return OciErrorOutOfCapacity
+	}
+	return classify(0, code, message)
+}
+
+func classify(status int, code, message string) OciErrorReason {
+	switch {
+	// the out of capacity launch is an internal error with the message of the shortage
+	case status == http.StatusInternalServerError && strings.Contains(message, "Out of host capacity"):
+		return OciErrorOutOfCapacity
+	// the quotas are reported as exceeded limits with a message of the quota
+	case code == "QuotaExceeded" || (code == "LimitExceeded" && strings.Contains(strings.ToLower(message), "quota")):
+		return OciErrorQuotaExceeded
+	case code == "LimitExceeded" || (status == http.StatusBadRequest && strings.Contains(message, "service limits were exceeded")):
+		return OciErrorLimitExceeded
+	case code == "TooManyRequests" || status == http.StatusTooManyRequests:
+		return OciErrorThrottled
+	case code == "NotAuthorizedOrNotFound" || code == "NotAuthorized" || status == http.StatusNotFound:
+		return OciErrorNotAuthorizedOrNotFound
+	case code == "NotAuthenticated" || status == http.StatusUnauthorized:
+		return OciErrorNotAuthenticated
+	case code == "InvalidParameter" || code == "MissingParameter":
+		return OciErrorInvalidParameter
+	case code == "InternalError" || status >= http.StatusInternalServerError:
+		return OciErrorInternal
+	}
+	return OciErrorUnknown
+}
+
+// IsInsufficientCapacity returns true if the offering of the launch is out of capacity, limits or quotas
+func (r OciErrorReason) IsInsufficientCapacity() bool {
+	return r == OciErrorOutOfCapacity || r == OciErrorLimitExceeded || r == OciErrorQuotaExceeded
+}
+
+// LaunchError converts the error of a launch to the karpenter error of its class. The capacity errors become
+// insufficient capacity errors, so the next offering is tried. The auth and config errors become nodeclass not ready
+// errors, as no launch with the nodeclass can succeed until it is fixed. The throttled and the internal errors fail the
+// launch with a create error of their reason, the throttled launches are held by the instance provider until the
+// Retry-After of the response.
+func LaunchError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var serviceErr common.ServiceError
+	if !errors.As(err, &serviceErr) {
+		return err
+	}
+	switch reason := ClassifyOciError(err); reason {
+	case OciErrorOutOfCapacity, OciErrorLimitExceeded, OciErrorQuotaExceeded:
+		return cloudprovider.NewInsufficientCapacityError(fmt.Errorf("InsufficientCapacityError: %s", serviceErr.GetMessage()))
+	case OciErrorNotAuthorizedOrNotFound, OciErrorNotAuthenticated, OciErrorInvalidParameter:
+		return cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("%s: %s", reason, serviceErr.GetMessage()))
+	case OciErrorThrottled:
+		return cloudprovider.NewCreateError(err, string(reason), "launch requests are throttled")
+	case OciErrorInternal:
+		return cloudprovider.NewCreateError(err, string(reason), fmt.Sprintf("launch failed with an internal error, %s", serviceErr.GetMessage()))
+	}
+	return err
+}
+
 func ConvertLaunchOptions(m *v1alpha1.LaunchOptions) (*core.LaunchOptions, error) {
 	ociLaunchOptions := &core.LaunchOptions{}
 	if m.BootVolumeType != nil {
@@ -44,3 +148,24 @@ func ConvertLaunchOptions(m *v1alpha1.LaunchOptions) (*core.LaunchOptions, error
 func SafeTagKey(origin string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(origin, ".", "_"), " ", "_")
 }
+
+// MatchTags returns true when the resource carries every tag of the selector.
+// Keys in the form of "namespace.key" are looked up in the defined tags, other keys in the freeform tags,
+// a '*' value matches any value of the key.
+func MatchTags(selector map[string]string, freeformTags map[string]string, definedTags map[string]map[string]interface{}) bool {
+	for key, want := range selector {
+		var got string
+		var found bool
+		if namespace, name, ok := strings.Cut(key, "."); ok {
+			var val interface{}
+			val, found = definedTags[namespace][name]
+			got = fmt.Sprint(val)
+		} else {
+			got, found = freeformTags[key]
+		}
+		if !found || (want != "*" && want != got) {
+			return false
+		}
+	}
+	return true
+}
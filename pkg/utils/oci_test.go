@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/zoom/karpenter-oci/pkg/fake"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+func TestClassifyOciError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want OciErrorReason
+	}{
+		{name: "nil", err: nil, want: OciErrorUnknown},
+		{name: "not a service error", err: errors.New("connection reset"), want: OciErrorUnknown},
+		{name: "out of host capacity", err: &fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: "Out of host capacity."}, want: OciErrorOutOfCapacity},
+		{name: "wrapped out of host capacity", err: fmt.Errorf("launching, %w", &fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Message: "Out of host capacity."}), want: OciErrorOutOfCapacity},
+		{name: "limit exceeded", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "LimitExceeded", Message: "Tenancy has reached the limit"}, want: OciErrorLimitExceeded},
+		{name: "service limits message", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Message: "The following service limits were exceeded: standard-e4-core-count"}, want: OciErrorLimitExceeded},
+		{name: "quota exceeded", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "QuotaExceeded", Message: "Quota exceeded"}, want: OciErrorQuotaExceeded},
+		{name: "limit exceeded by a quota", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "LimitExceeded", Message: "Compartment quota exceeded for standard-e4-core-count"}, want: OciErrorQuotaExceeded},
+		{name: "not authorized or not found", err: &fake.FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "Authorization failed or requested resource not found"}, want: OciErrorNotAuthorizedOrNotFound},
+		{name: "not authenticated", err: &fake.FakeServicefailure{StatusCode: http.StatusUnauthorized, Code: "NotAuthenticated", Message: "The required information to complete authentication was not provided"}, want: OciErrorNotAuthenticated},
+		{name: "invalid parameter", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "InvalidParameter", Message: "Invalid imageId"}, want: OciErrorInvalidParameter},
+		{name: "too many requests", err: &fake.FakeServicefailure{StatusCode: http.StatusTooManyRequests, Code: "TooManyRequests", Message: "Too many requests for the tenancy"}, want: OciErrorThrottled},
+		{name: "internal error", err: &fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: "Internal error occurred"}, want: OciErrorInternal},
+		{name: "conflict", err: &fake.FakeServicefailure{StatusCode: http.StatusConflict, Code: "IncorrectState", Message: "Resource is in an incorrect state"}, want: OciErrorUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ClassifyOciError(tc.err); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClassifyWorkRequestError(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		code    string
+		message string
+		want    OciErrorReason
+	}{
+		{name: "out of host capacity message", code: "InternalError", message: "Out of host capacity.", want: OciErrorOutOfCapacity},
+		{name: "out of host capacity code", code: "OutOfHostCapacity", message: "Shape VM.Standard.E4.Flex is out of capacity", want: OciErrorOutOfCapacity},
+		{name: "limit exceeded", code: "LimitExceeded", message: "Tenancy has reached the limit", want: OciErrorLimitExceeded},
+		{name: "quota exceeded", code: "QuotaExceeded", message: "Quota exceeded", want: OciErrorQuotaExceeded},
+		{name: "invalid parameter", code: "InvalidParameter", message: "Image is not compatible with the shape", want: OciErrorInvalidParameter},
+		{name: "internal error", code: "InternalError", message: "Internal error occurred", want: OciErrorInternal},
+		{name: "unknown", code: "", message: "instance failed to boot", want: OciErrorUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ClassifyWorkRequestError(tc.code, tc.message); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLaunchError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		ice      bool
+		notReady bool
+		create   bool
+	}{
+		{name: "out of host capacity", err: &fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: "Out of host capacity."}, ice: true},
+		{name: "limit exceeded", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "LimitExceeded", Message: "Tenancy has reached the limit"}, ice: true},
+		{name: "quota exceeded", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "QuotaExceeded", Message: "Quota exceeded"}, ice: true},
+		{name: "not authorized or not found", err: &fake.FakeServicefailure{StatusCode: http.StatusNotFound, Code: "NotAuthorizedOrNotFound", Message: "Authorization failed or requested resource not found"}, notReady: true},
+		{name: "not authenticated", err: &fake.FakeServicefailure{StatusCode: http.StatusUnauthorized, Code: "NotAuthenticated", Message: "Not authenticated"}, notReady: true},
+		{name: "invalid parameter", err: &fake.FakeServicefailure{StatusCode: http.StatusBadRequest, Code: "InvalidParameter", Message: "Invalid imageId"}, notReady: true},
+		{name: "too many requests", err: &fake.FakeServicefailure{StatusCode: http.StatusTooManyRequests, Code: "TooManyRequests", Message: "Too many requests for the tenancy"}, create: true},
+		{name: "internal error", err: &fake.FakeServicefailure{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: "Internal error occurred"}, create: true},
+		{name: "not a service error", err: errors.New("connection reset")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := LaunchError(tc.err)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := cloudprovider.IsInsufficientCapacityError(err); got != tc.ice {
+				t.Errorf("expected insufficient capacity error %t, got %t", tc.ice, got)
+			}
+			if got := cloudprovider.IsNodeClassNotReadyError(err); got != tc.notReady {
+				t.Errorf("expected nodeclass not ready error %t, got %t", tc.notReady, got)
+			}
+			var createErr *cloudprovider.CreateError
+			if got := errors.As(err, &createErr); got != tc.create {
+				t.Errorf("expected create error %t, got %t", tc.create, got)
+			} else if got && createErr.ConditionReason != string(ClassifyOciError(tc.err)) {
+				t.Errorf("expected create error reason %s, got %s", ClassifyOciError(tc.err), createErr.ConditionReason)
+			}
+			if !tc.ice && !tc.notReady && !tc.create && !errors.Is(err, tc.err) {
+				t.Errorf("expected the error to be returned as is, got %s", err)
+			}
+		})
+	}
+	if err := LaunchError(nil); err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+}
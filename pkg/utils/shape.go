@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/samber/lo"
+)
+
+const (
+	ProcessorVendorAmpere = "ampere"
+	ProcessorVendorAMD    = "amd"
+	ProcessorVendorIntel  = "intel"
+
+	ProcessorGenerationAltra     = "altra"
+	ProcessorGenerationAmpereOne = "ampereone"
+
+	GPUManufacturerNVIDIA = "nvidia"
+	GPUManufacturerAMD    = "amd"
+)
+
+var (
+	// codenameRegexp matches the codename of the processor description, e.g. "2.55 GHz AMD EPYC 7J13 (Milan)"
+	codenameRegexp = regexp.MustCompile(`\(([^()]+)\)`)
+	// ampereSeriesRegexp matches the series of the standard Ampere shapes, e.g. VM.Standard.A1.Flex
+	ampereSeriesRegexp = regexp.MustCompile(`^A(\d+)$`)
+	nonAlphanumRegexp  = regexp.MustCompile(`[^a-z0-9]`)
+	// gpuMemoryRegexp matches the memory of the gpu description, e.g. "NVIDIA A100 80GB"
+	gpuMemoryRegexp = regexp.MustCompile(`^(\d+)gb$`)
+
+	// gpuBrands are the brands of the gpu descriptions which aren't part of the model, e.g. "NVIDIA® Tesla® V100"
+	gpuBrands = []string{"tesla", "instinct"}
+	// gpuMemoryInGiB is the memory of a GPU of the models, the shape details don't include the GPU memory
+	// https://docs.oracle.com/en-us/iaas/Content/Compute/References/computeshapes.htm#bm-gpu
+	gpuMemoryInGiB = map[string]int64{
+		"p100":   16,
+		"v100":   16,
+		"a10":    24,
+		"a100":   40,
+		"l40s":   48,
+		"h100":   80,
+		"h200":   141,
+		"b200":   180,
+		"gb200":  186,
+		"mi300x": 192,
+	}
+	// shapeGPUMemoryInGiB is the memory of a GPU of the shapes whose models are sold with several memory sizes
+	shapeGPUMemoryInGiB = map[string]int64{
+		"BM.GPU.A100-v2.8": 80,
+	}
+)
+
+// Processor is the processor of a shape
+type Processor struct {
+	// Arch is the architecture of the processor, arm64 or amd64
+	Arch string
+	// Vendor is ampere, amd or intel, empty when it's unknown
+	Vendor string
+	// Generation is the lowercased codename of the processor, e.g. milan or altra, empty when it's unknown
+	Generation string
+}
+
+// ShapeProcessor derives the processor of the shape from its processor description, then the type of its platform
+// config. The shapes which have neither fall back to the shape name, the standard A series shapes are Ampere, A1 is
+// Altra and the later ones are AmpereOne, the other shapes are amd64 with an unknown vendor and generation.
+func ShapeProcessor(shape core.Shape) Processor {
+	processor := Processor{Arch: "amd64"}
+	description := strings.ToLower(lo.FromPtr(shape.ProcessorDescription))
+	var platform []string
+	if shape.PlatformConfigOptions != nil && shape.PlatformConfigOptions.Type != "" {
+		platform = strings.Split(strings.ToLower(string(shape.PlatformConfigOptions.Type)), "_")
+	}
+	switch {
+	case strings.Contains(description, ProcessorVendorAmpere):
+		processor.Vendor = ProcessorVendorAmpere
+	case strings.Contains(description, ProcessorVendorAMD):
+		processor.Vendor = ProcessorVendorAMD
+	case strings.Contains(description, ProcessorVendorIntel):
+		processor.Vendor = ProcessorVendorIntel
+	case len(platform) != 0 && (platform[0] == ProcessorVendorAMD || platform[0] == ProcessorVendorIntel):
+		processor.Vendor = platform[0]
+	}
+	switch {
+	case processor.Vendor == ProcessorVendorAmpere:
+		processor.Generation = lo.Ternary(strings.Contains(nonAlphanumRegexp.ReplaceAllString(description, ""), ProcessorGenerationAmpereOne),
+			ProcessorGenerationAmpereOne, ProcessorGenerationAltra)
+	case codenameRegexp.MatchString(description):
+		matches := codenameRegexp.FindAllStringSubmatch(description, -1)
+		processor.Generation = nonAlphanumRegexp.ReplaceAllString(matches[len(matches)-1][1], "")
+	// e.g. AMD_MILAN_BM, the VM and GENERIC types have no codename
+	case len(platform) > 2 && platform[0] == processor.Vendor:
+		processor.Generation = platform[1]
+	}
+	if processor.Vendor == "" {
+		if series, ok := ampereSeries(lo.FromPtr(shape.Shape)); ok {
+			processor.Vendor = ProcessorVendorAmpere
+			processor.Generation = lo.Ternary(series == "1", ProcessorGenerationAltra, ProcessorGenerationAmpereOne)
+		}
+	}
+	if processor.Vendor == ProcessorVendorAmpere {
+		processor.Arch = "arm64"
+	}
+	return processor
+}
+
+// VcpusPerOcpu returns the vcpus of an ocpu of the shape, an ocpu is a physical core of the Ampere Altra processors
+// which have no simultaneous multithreading, so 1 OCPU = 1 vCPU for them, otherwise 1 OCPU = 2 vCPU
+// https://docs.oracle.com/en-us/iaas/Content/Compute/References/computeshapes.htm
+func VcpusPerOcpu(shape core.Shape) int {
+	if ShapeProcessor(shape).Generation == ProcessorGenerationAltra {
+		return 1
+	}
+	return 2
+}
+
+// GPU is the GPU of a shape
+type GPU struct {
+	// Manufacturer is nvidia or amd
+	Manufacturer string
+	// Model is the lowercased model of the GPU without the manufacturer, e.g. a10 or mi300x, empty when it's unknown
+	Model string
+	// MemoryInMiB is the memory of a GPU, 0 when it's unknown
+	MemoryInMiB int64
+}
+
+// ShapeGPU derives the GPU of the shape from its gpu description, e.g. "NVIDIA® A10" or "AMD Instinct™ MI300X", false
+// is returned for the shapes without GPUs. The GPUs of the descriptions without the manufacturer are NVIDIA GPUs, which
+// the GPU shapes were before the AMD shapes. The GPU memory is taken from the description, then the known models.
+func ShapeGPU(shape core.Shape) (GPU, bool) {
+	if lo.FromPtr(shape.Gpus) == 0 {
+		return GPU{}, false
+	}
+	gpu := GPU{Manufacturer: GPUManufacturerNVIDIA}
+	var model []string
+	description := strings.NewReplacer("®", " ", "™", " ").Replace(strings.ToLower(lo.FromPtr(shape.GpuDescription)))
+	for _, token := range strings.Fields(description) {
+		switch {
+		case token == GPUManufacturerNVIDIA || token == GPUManufacturerAMD:
+			gpu.Manufacturer = token
+		case lo.Contains(gpuBrands, token):
+		case gpuMemoryRegexp.MatchString(token):
+			memoryInGiB, _ := strconv.ParseInt(gpuMemoryRegexp.FindStringSubmatch(token)[1], 10, 64)
+			gpu.MemoryInMiB = memoryInGiB * 1024
+		default:
+			model = append(model, token)
+		}
+	}
+	gpu.Model = SanitizeLabelValue(strings.Join(model, "-"))
+	if gpu.MemoryInMiB == 0 {
+		memoryInGiB, ok := shapeGPUMemoryInGiB[lo.FromPtr(shape.Shape)]
+		if !ok {
+			memoryInGiB = gpuMemoryInGiB[gpu.Model]
+		}
+		gpu.MemoryInMiB = memoryInGiB * 1024
+	}
+	return gpu, true
+}
+
+// ampereSeries returns the series number of the standard Ampere shapes, e.g. 1 of BM.Standard.A1.160
+func ampereSeries(shapeName string) (string, bool) {
+	parts := strings.Split(shapeName, ".")
+	if len(parts) < 3 || parts[1] != "Standard" {
+		return "", false
+	}
+	matches := ampereSeriesRegexp.FindStringSubmatch(parts[2])
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
@@ -0,0 +1,99 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+)
+
+func TestShapeProcessor(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		shape        core.Shape
+		want         Processor
+		vcpusPerOcpu int
+	}{
+		{name: "ampere altra", shape: core.Shape{Shape: common.String("VM.Standard.A1.Flex"), ProcessorDescription: common.String("3.0 GHz Ampere® Altra™")},
+			want: Processor{Arch: "arm64", Vendor: ProcessorVendorAmpere, Generation: ProcessorGenerationAltra}, vcpusPerOcpu: 1},
+		{name: "ampereone", shape: core.Shape{Shape: common.String("VM.Standard.A2.Flex"), ProcessorDescription: common.String("3.0 GHz AmpereOne®")},
+			want: Processor{Arch: "arm64", Vendor: ProcessorVendorAmpere, Generation: ProcessorGenerationAmpereOne}, vcpusPerOcpu: 2},
+		{name: "new ampere shape", shape: core.Shape{Shape: common.String("VM.Standard.A9.Flex"), ProcessorDescription: common.String("Ampere One M")},
+			want: Processor{Arch: "arm64", Vendor: ProcessorVendorAmpere, Generation: ProcessorGenerationAmpereOne}, vcpusPerOcpu: 2},
+		{name: "amd", shape: core.Shape{Shape: common.String("VM.Standard.E4.Flex"), ProcessorDescription: common.String("2.55 GHz AMD EPYC™ 7J13 (Milan)")},
+			want: Processor{Arch: "amd64", Vendor: ProcessorVendorAMD, Generation: "milan"}, vcpusPerOcpu: 2},
+		{name: "intel", shape: core.Shape{Shape: common.String("VM.Standard3.Flex"), ProcessorDescription: common.String("2.6 GHz Intel® Xeon® Platinum 8358 (Ice Lake)")},
+			want: Processor{Arch: "amd64", Vendor: ProcessorVendorIntel, Generation: "icelake"}, vcpusPerOcpu: 2},
+		{name: "platform config type", shape: core.Shape{Shape: common.String("BM.Standard.E4.128"),
+			PlatformConfigOptions: &core.ShapePlatformConfigOptions{Type: core.ShapePlatformConfigOptionsTypeAmdMilanBm}},
+			want: Processor{Arch: "amd64", Vendor: ProcessorVendorAMD, Generation: "milan"}, vcpusPerOcpu: 2},
+		{name: "platform config type without codename", shape: core.Shape{Shape: common.String("VM.Standard3.Flex"),
+			PlatformConfigOptions: &core.ShapePlatformConfigOptions{Type: core.ShapePlatformConfigOptionsTypeIntelVm}},
+			want: Processor{Arch: "amd64", Vendor: ProcessorVendorIntel}, vcpusPerOcpu: 2},
+		{name: "generic platform config type", shape: core.Shape{Shape: common.String("BM.Standard.A1.160"),
+			PlatformConfigOptions: &core.ShapePlatformConfigOptions{Type: core.ShapePlatformConfigOptionsTypeGenericBm}},
+			want: Processor{Arch: "arm64", Vendor: ProcessorVendorAmpere, Generation: ProcessorGenerationAltra}, vcpusPerOcpu: 1},
+		{name: "ampere altra shape name", shape: core.Shape{Shape: common.String("VM.Standard.A1.Flex")},
+			want: Processor{Arch: "arm64", Vendor: ProcessorVendorAmpere, Generation: ProcessorGenerationAltra}, vcpusPerOcpu: 1},
+		{name: "ampereone shape name", shape: core.Shape{Shape: common.String("BM.Standard.A4.48")},
+			want: Processor{Arch: "arm64", Vendor: ProcessorVendorAmpere, Generation: ProcessorGenerationAmpereOne}, vcpusPerOcpu: 2},
+		{name: "nvidia gpu isn't ampere", shape: core.Shape{Shape: common.String("BM.GPU.A10.4")},
+			want: Processor{Arch: "amd64"}, vcpusPerOcpu: 2},
+		{name: "unknown", shape: core.Shape{Shape: common.String("VM.Standard.E5.Flex")},
+			want: Processor{Arch: "amd64"}, vcpusPerOcpu: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ShapeProcessor(tc.shape); got != tc.want {
+				t.Errorf("expected processor %+v, got %+v", tc.want, got)
+			}
+			if got := VcpusPerOcpu(tc.shape); got != tc.vcpusPerOcpu {
+				t.Errorf("expected %d vcpus per ocpu, got %d", tc.vcpusPerOcpu, got)
+			}
+		})
+	}
+}
+
+func TestShapeGPU(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		shape core.Shape
+		want  GPU
+		ok    bool
+	}{
+		{name: "no gpus", shape: core.Shape{Shape: common.String("VM.Standard.E4.Flex")}},
+		{name: "nvidia", shape: core.Shape{Shape: common.String("BM.GPU.A10.4"), Gpus: common.Int(4), GpuDescription: common.String("NVIDIA® A10")},
+			want: GPU{Manufacturer: GPUManufacturerNVIDIA, Model: "a10", MemoryInMiB: 24 * 1024}, ok: true},
+		{name: "nvidia brand", shape: core.Shape{Shape: common.String("BM.GPU3.8"), Gpus: common.Int(8), GpuDescription: common.String("NVIDIA® Tesla® V100")},
+			want: GPU{Manufacturer: GPUManufacturerNVIDIA, Model: "v100", MemoryInMiB: 16 * 1024}, ok: true},
+		{name: "amd", shape: core.Shape{Shape: common.String("BM.GPU.MI300X.8"), Gpus: common.Int(8), GpuDescription: common.String("AMD Instinct™ MI300X")},
+			want: GPU{Manufacturer: GPUManufacturerAMD, Model: "mi300x", MemoryInMiB: 192 * 1024}, ok: true},
+		{name: "memory of the description", shape: core.Shape{Shape: common.String("BM.GPU.A100-v2.8"), Gpus: common.Int(8), GpuDescription: common.String("NVIDIA® A100 80GB")},
+			want: GPU{Manufacturer: GPUManufacturerNVIDIA, Model: "a100", MemoryInMiB: 80 * 1024}, ok: true},
+		{name: "memory of the shape", shape: core.Shape{Shape: common.String("BM.GPU.A100-v2.8"), Gpus: common.Int(8), GpuDescription: common.String("NVIDIA® A100")},
+			want: GPU{Manufacturer: GPUManufacturerNVIDIA, Model: "a100", MemoryInMiB: 80 * 1024}, ok: true},
+		{name: "unknown model", shape: core.Shape{Shape: common.String("BM.GPU.X1.8"), Gpus: common.Int(8), GpuDescription: common.String("NVIDIA® X1")},
+			want: GPU{Manufacturer: GPUManufacturerNVIDIA, Model: "x1"}, ok: true},
+		{name: "no description", shape: core.Shape{Shape: common.String("BM.GPU.X1.8"), Gpus: common.Int(8)},
+			want: GPU{Manufacturer: GPUManufacturerNVIDIA}, ok: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, ok := ShapeGPU(tc.shape); got != tc.want || ok != tc.ok {
+				t.Errorf("expected gpu %+v, %t, got %+v, %t", tc.want, tc.ok, got, ok)
+			}
+		})
+	}
+}
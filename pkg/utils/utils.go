@@ -92,8 +92,3 @@ func WithDefaultFloat64(key string, def float64) float64 {
 	}
 	return f
 }
-
-// IsA1FlexShape returns true for VM.Standard.A1.Flex shapes (1 OCPU=1 vCPU)
-func IsA1FlexShape(shapeName string) bool {
-	return strings.Contains(strings.ToLower(shapeName), "a1.flex")
-}